@@ -2,54 +2,600 @@ package main
 
 import (
 	"DFS_GO/internal/client"
+	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"os"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const usage = `Usage: dfs [flags] <command> [args]
+
+Commands:
+  put <local> [remote]   upload a local file or directory (default remote
+                         name: its base name); small files of a directory
+                         are packed together, see small_file_kb
+  get <remote> [local]   download a file (default local name: the remote name)
+  cat <remote>           write a file to stdout
+  ls [-r] [path]         list a directory
+  stat <path>            show details of a file or directory
+  du [path]              show bytes stored under a path
+  quota [path]           show the quotas of a directory and those above it
+                         or, without a path, yours and every directory's
+  mkdir <dir>            create a directory
+  rm <path>              remove a file or an empty directory
+  mv <src> <dst>         rename a file or directory
+  setrep <path> <n>      change the replication factor of a file
+  chmod <mode> <path>    change the permission bits, in octal, e.g. 750
+  chown <owner>[:<group>] <path>
+                         change the owner (administrators only) or, with
+                         :<group>, only the group
+  keygen <keyfile>       create a master key for encrypting files
+
+"upload" and "download" are accepted as aliases of put and get.
+
+Settings are taken from the config file, then DFS_METADATA, DFS_WORKERS,
+DFS_CHUNK_SIZE, DFS_REPLICATION, DFS_COMPRESSION, DFS_KEY_FILE and
+DFS_TOKEN, then flags. The token authenticates to the metadata server and
+has no flag, so that it stays out of the process list.
+
+Flags:
+`
+
+// fileEntry is the --json shape of a namespace entry
+type fileEntry struct {
+	Name        string `json:"name"`
+	Dir         bool   `json:"dir"`
+	Size        int64  `json:"size"`
+	Chunks      int32  `json:"chunks"`
+	StoredSize  int64  `json:"stored_size"`
+	Replication int32  `json:"replication,omitempty"`
+	Packed      bool   `json:"packed,omitempty"`
+	KeyId       string `json:"key_id,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+}
+
+type cli struct {
+	ctx  context.Context // bounds the whole command
+	meta pb.MetadataServiceClient
+	opts client.Options
+	json bool
+}
+
 func main() {
-	if len(os.Args) < 3 {
-		log.Fatal("Usage: client <upload|download> <filename> [output_path]")
+	fs := flag.NewFlagSet("dfs", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file (env DFS_CONFIG, default config/client.yaml)")
+	metaAddr := fs.String("metadata", "", "metadata server address")
+	workers := fs.Int("workers", 0, "number of parallel chunk uploads")
+	chunkSize := fs.Int("chunk-size", 0, "chunk size in MB for uploads")
+	replication := fs.Int("replication", 0, "replication factor for uploaded files")
+	compression := fs.String("compress", "", "compress uploaded chunks with zstd, snappy or gzip")
+	keyFile := fs.String("key-file", "", "master key file to encrypt and decrypt files with")
+	timeout := fs.Duration("timeout", 0, "deadline for the whole command, e.g. 10m; 0 waits as long as retries go on")
+	recursive := fs.Bool("r", false, "list recursively")
+	jsonOut := fs.Bool("json", false, "print machine-readable JSON")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
 	}
 
-	command := os.Args[1]
-	filename := os.Args[2]
+	args := parseArgs(fs, os.Args[1:])
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	command, args := args[0], args[1:]
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := applyEnv(&cfg); err != nil {
+		log.Fatalf("Invalid environment: %v", err)
+	}
+
+	// Flags win over config and environment
+	if *metaAddr != "" {
+		cfg.MetadataAddress = *metaAddr
+	}
+	if *workers > 0 {
+		cfg.Concurrency.UploadWorkers = *workers
+	}
+	if *chunkSize > 0 {
+		cfg.ChunkSizeMB = *chunkSize
+	}
+	if *replication > 0 {
+		cfg.Replication = *replication
+	}
+	if *compression != "" {
+		cfg.Compression = *compression
+	}
+	if *keyFile != "" {
+		cfg.KeyFile = *keyFile
+	}
+	if cfg.MetadataAddress == "" {
+		cfg.MetadataAddress = "localhost:5000"
+	}
+	if cfg.ChunkSizeMB > 16 {
+		log.Fatalf("Chunk size %d MB exceeds the 16 MB gRPC message limit", cfg.ChunkSizeMB)
+	}
+	opts, err := client.OptionsFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid client config: %v", err)
+	}
+	if *timeout <= 0 {
+		*timeout = time.Duration(cfg.Timeouts.OperationSeconds) * time.Second
+	}
+
+	tlsCfg, err := transport.ClientTLS(cfg.TLS)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
 
 	// Connect to metadata server
-	metaConn, err := grpc.Dial("localhost:5000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	metaConn, err := transport.Dial(cfg.MetadataAddress, tlsCfg, transport.WithToken(cfg.Token))
 	if err != nil {
 		log.Fatalf("Failed to connect to metadata server: %v", err)
 	}
 	defer metaConn.Close()
 
-	metaClient := pb.NewMetadataServiceClient(metaConn)
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+	}
+	defer cancel()
+
+	c := &cli{
+		ctx:  ctx,
+		meta: pb.NewMetadataServiceClient(metaConn),
+		opts: opts,
+		json: *jsonOut,
+	}
+	c.opts.TLS = tlsCfg
+	if cfg.KeyFile != "" && command != "keygen" {
+		keys, err := client.LoadKeyFile(cfg.KeyFile)
+		c.check(err)
+		c.opts.Keys = keys
+	}
 
 	switch command {
-	case "upload":
-		log.Printf("Uploading file: %s", filename)
-		if err := client.Upload(filename, metaClient); err != nil {
-			log.Fatalf("Upload failed: %v", err)
-		}
-		log.Println("Upload complete!")
-	case "download":
-		outputPath := filename // default to same name
-		if len(os.Args) >= 4 {
-			outputPath = os.Args[3]
-		}
-		log.Printf("Downloading file: %s to %s", filename, outputPath)
-		data, err := client.Download(filename, metaClient)
+	case "put", "upload":
+		c.need(args, 1, 2)
+		remote := filepath.Base(args[0])
+		if len(args) == 2 {
+			remote = args[1]
+		}
+		c.put(args[0], remote)
+	case "get", "download":
+		c.need(args, 1, 2)
+		output := args[0]
+		if len(args) == 2 {
+			output = args[1]
+		}
+		c.get(args[0], output)
+	case "cat":
+		c.need(args, 1, 1)
+		c.cat(args[0])
+	case "ls":
+		c.need(args, 0, 1)
+		c.ls(argOr(args, ""), *recursive)
+	case "stat":
+		c.need(args, 1, 1)
+		c.stat(args[0])
+	case "du":
+		c.need(args, 0, 1)
+		c.du(argOr(args, ""))
+	case "quota":
+		c.need(args, 0, 1)
+		c.quota(argOr(args, ""))
+	case "mkdir":
+		c.need(args, 1, 1)
+		c.check(client.Mkdir(c.ctx, args[0], c.meta, c.opts))
+		c.ok()
+	case "rm":
+		c.need(args, 1, 1)
+		c.check(client.Delete(c.ctx, args[0], c.meta, c.opts))
+		c.ok()
+	case "mv":
+		c.need(args, 2, 2)
+		c.check(client.Rename(c.ctx, args[0], args[1], c.meta, c.opts))
+		c.ok()
+	case "setrep":
+		c.need(args, 2, 2)
+		n, err := strconv.Atoi(args[1])
+		c.check(err)
+		c.check(client.SetReplication(c.ctx, args[0], n, c.meta, c.opts))
+		c.ok()
+	case "chmod":
+		c.need(args, 2, 2)
+		mode, err := strconv.ParseUint(args[0], 8, 32)
+		c.check(err)
+		c.check(client.Chmod(c.ctx, args[1], uint32(mode), c.meta, c.opts))
+		c.ok()
+	case "chown":
+		c.need(args, 2, 2)
+		owner, group, _ := strings.Cut(args[0], ":")
+		c.check(client.Chown(c.ctx, args[1], owner, group, c.meta, c.opts))
+		c.ok()
+	case "keygen":
+		c.need(args, 1, 1)
+		c.check(common.GenerateKeyFile(args[0]))
+		c.ok()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func (c *cli) put(local, remote string) {
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		c.putDir(local, remote)
+		return
+	}
+
+	if !c.json {
+		log.Printf("Uploading file: %s to %s", local, remote)
+	}
+	c.check(client.UploadWithOptions(c.ctx, local, remote, c.meta, c.opts))
+
+	info, err := client.Stat(c.ctx, remote, c.meta, c.opts)
+	c.check(err)
+
+	if c.json {
+		c.emit(toEntry(info))
+		return
+	}
+	log.Printf("Upload complete! %d bytes in %d chunks", info.Size, info.NumChunks)
+}
+
+// putDir uploads the files under a local directory, keeping their paths
+// relative to it.
+func (c *cli) putDir(local, remote string) {
+	var files []client.FileUpload
+	err := filepath.WalkDir(local, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(local, path)
 		if err != nil {
-			log.Fatalf("Download failed: %v", err)
+			return err
+		}
+		files = append(files, client.FileUpload{
+			Local:  path,
+			Remote: remote + "/" + filepath.ToSlash(rel),
+		})
+		return nil
+	})
+	c.check(err)
+
+	if !c.json {
+		log.Printf("Uploading %d files from %s to %s", len(files), local, remote)
+	}
+	c.check(client.UploadFiles(c.ctx, files, c.meta, c.opts))
+
+	if c.json {
+		c.emit(struct {
+			Name  string `json:"name"`
+			Files int    `json:"files"`
+		}{remote, len(files)})
+		return
+	}
+	log.Printf("Upload complete! %d files", len(files))
+}
+
+func (c *cli) get(remote, output string) {
+	if !c.json {
+		log.Printf("Downloading file: %s to %s", remote, output)
+	}
+	data, err := client.DownloadWithOptions(c.ctx, remote, c.meta, c.opts)
+	c.check(err)
+	c.check(os.WriteFile(output, data, 0644))
+
+	if c.json {
+		c.emit(struct {
+			Name   string `json:"name"`
+			Output string `json:"output"`
+			Size   int    `json:"size"`
+		}{remote, output, len(data)})
+		return
+	}
+	log.Printf("Download complete! Saved to %s", output)
+}
+
+func (c *cli) cat(remote string) {
+	data, err := client.DownloadWithOptions(c.ctx, remote, c.meta, c.opts)
+	c.check(err)
+	os.Stdout.Write(data)
+}
+
+func (c *cli) ls(path string, recursive bool) {
+	files, err := client.List(c.ctx, path, recursive, c.meta, c.opts)
+	c.check(err)
+
+	if c.json {
+		entries := make([]fileEntry, 0, len(files))
+		for _, f := range files {
+			entries = append(entries, toEntry(f))
 		}
-		err = os.WriteFile(outputPath, data, 0644)
+		c.emit(entries)
+		return
+	}
+	for _, f := range files {
+		name := f.Filename
+		if f.IsDir {
+			name += "/"
+		}
+		fmt.Printf("%s %-8s %-8s %12d %s\n", modeString(f), ownerOr(f.Owner), ownerOr(f.Group), f.Size, name)
+	}
+}
+
+func (c *cli) stat(path string) {
+	info, err := client.Stat(c.ctx, path, c.meta, c.opts)
+	c.check(err)
+
+	if c.json {
+		c.emit(toEntry(info))
+		return
+	}
+	kind := "file"
+	if info.IsDir {
+		kind = "directory"
+	}
+	fmt.Printf("Name:        %s\n", info.Filename)
+	fmt.Printf("Type:        %s\n", kind)
+	fmt.Printf("Size:        %d\n", info.Size)
+	if !info.IsDir && info.StoredSize != info.Size {
+		fmt.Printf("Stored:      %d (%.1f%%)\n", info.StoredSize, 100*float64(info.StoredSize)/float64(info.Size))
+	}
+	fmt.Printf("Chunks:      %d\n", info.NumChunks)
+	fmt.Printf("Access:      %s %s %s\n", modeString(info), ownerOr(info.Owner), ownerOr(info.Group))
+	if !info.IsDir {
+		fmt.Printf("Replication: %d\n", info.Replication)
+	}
+	if info.Packed {
+		fmt.Printf("Packed:      yes\n")
+	}
+	if info.KeyId != "" {
+		fmt.Printf("Encrypted:   %s\n", info.KeyId)
+	}
+}
+
+func (c *cli) du(path string) {
+	files, err := client.List(c.ctx, path, true, c.meta, c.opts)
+	c.check(err)
+
+	var size int64
+	count := 0
+	for _, f := range files {
+		if !f.IsDir {
+			size += f.Size
+			count++
+		}
+	}
+
+	if c.json {
+		c.emit(struct {
+			Path  string `json:"path"`
+			Size  int64  `json:"size"`
+			Files int    `json:"files"`
+		}{path, size, count})
+		return
+	}
+	if path == "" {
+		path = "/"
+	}
+	fmt.Printf("%d\t%s\n", size, path)
+}
+
+func (c *cli) quota(path string) {
+	quotas, err := client.Quotas(c.ctx, path, c.meta, c.opts)
+	c.check(err)
+
+	if c.json {
+		type quotaEntry struct {
+			User     string `json:"user,omitempty"`
+			Dir      string `json:"dir,omitempty"`
+			MaxBytes int64  `json:"max_bytes"`
+			MaxFiles int64  `json:"max_files"`
+			Bytes    int64  `json:"bytes"`
+			Files    int64  `json:"files"`
+		}
+		entries := make([]quotaEntry, 0, len(quotas))
+		for _, q := range quotas {
+			entries = append(entries, quotaEntry{q.User, q.Dir, q.MaxBytes, q.MaxFiles, q.Bytes, q.Files})
+		}
+		c.emit(entries)
+		return
+	}
+	if len(quotas) == 0 {
+		fmt.Println("No quotas")
+		return
+	}
+	fmt.Printf("%-24s %12s %12s %8s %8s\n", "QUOTA", "BYTES", "MAX BYTES", "FILES", "MAX")
+	for _, q := range quotas {
+		name := q.Dir
+		if q.User != "" {
+			name = "user " + q.User
+		}
+		fmt.Printf("%-24s %12d %12s %8d %8s\n", name, q.Bytes, limit(q.MaxBytes), q.Files, limit(q.MaxFiles))
+	}
+}
+
+// limit renders a quota limit, where zero means none.
+func limit(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// need exits with usage help unless min <= len(args) <= max
+func (c *cli) need(args []string, min, max int) {
+	if len(args) < min || len(args) > max {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// check exits on error, as JSON when --json is set
+func (c *cli) check(err error) {
+	if err == nil {
+		return
+	}
+	if c.json {
+		c.emit(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		os.Exit(1)
+	}
+	log.Fatalf("Error: %v", err)
+}
+
+func (c *cli) ok() {
+	if c.json {
+		c.emit(struct {
+			Ok bool `json:"ok"`
+		}{true})
+	}
+}
+
+func (c *cli) emit(v any) {
+	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+		log.Fatalf("Failed to encode output: %v", err)
+	}
+}
+
+func toEntry(f *pb.FileInfo) fileEntry {
+	return fileEntry{
+		Name:        f.Filename,
+		Dir:         f.IsDir,
+		Size:        f.Size,
+		StoredSize:  f.StoredSize,
+		Chunks:      f.NumChunks,
+		Replication: f.Replication,
+		Packed:      f.Packed,
+		KeyId:       f.KeyId,
+		Owner:       f.Owner,
+		Group:       f.Group,
+		Mode:        octal(f),
+	}
+}
+
+// octal is the mode of a path as in chmod, or "" when nobody owns it.
+func octal(f *pb.FileInfo) string {
+	if f.Owner == "" && f.Mode == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%03o", f.Mode)
+}
+
+// modeString renders a path's type and permission bits as ls -l does.
+// Paths nobody owns are open to everyone.
+func modeString(f *pb.FileInfo) string {
+	mode := f.Mode
+	if f.Owner == "" && mode == 0 {
+		mode = 0777
+	}
+	b := []byte("-rwxrwxrwx")
+	if f.IsDir {
+		b[0] = 'd'
+	}
+	for i := 0; i < 9; i++ {
+		if mode&(1<<(8-i)) == 0 {
+			b[i+1] = '-'
+		}
+	}
+	return string(b)
+}
+
+// ownerOr returns name, or "-" when it is empty.
+func ownerOr(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
+
+// parseArgs parses flags anywhere on the command line and returns the
+// remaining positional arguments in order.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func argOr(args []string, def string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return def
+}
+
+// loadConfig reads the client config. A missing default config is not an
+// error; a missing config that was asked for explicitly is.
+func loadConfig(path string) (common.ClientConfig, error) {
+	if path == "" {
+		path = os.Getenv("DFS_CONFIG")
+	}
+	if path == "" {
+		cfg, err := common.LoadClientConfig("config/client.yaml")
+		if os.IsNotExist(err) {
+			return common.ClientConfig{}, nil
+		}
+		return cfg, err
+	}
+	return common.LoadClientConfig(path)
+}
+
+func applyEnv(cfg *common.ClientConfig) error {
+	if v := os.Getenv("DFS_METADATA"); v != "" {
+		cfg.MetadataAddress = v
+	}
+	if v := os.Getenv("DFS_COMPRESSION"); v != "" {
+		cfg.Compression = v
+	}
+	if v := os.Getenv("DFS_KEY_FILE"); v != "" {
+		cfg.KeyFile = v
+	}
+	if v := os.Getenv("DFS_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"DFS_WORKERS", &cfg.Concurrency.UploadWorkers},
+		{"DFS_CHUNK_SIZE", &cfg.ChunkSizeMB},
+		{"DFS_REPLICATION", &cfg.Replication},
+	}
+	for _, e := range ints {
+		v := os.Getenv(e.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
 		if err != nil {
-			log.Fatalf("Failed to write file: %v", err)
+			return fmt.Errorf("%s: %w", e.name, err)
 		}
-		log.Printf("Download complete! Saved to %s", outputPath)
-	default:
-		log.Fatalf("Unknown command: %s. Use 'upload' or 'download'", command)
+		*e.dst = n
 	}
+	return nil
 }
@@ -4,33 +4,99 @@ import (
 	"DFS_GO/internal/common"
 	"DFS_GO/internal/datanode"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
 	// Parse command line flags
 	configPath := flag.String("config", "config/datanode.yaml", "path to config file")
+	migrate := flag.Bool("migrate", false, "move chunks stored flat in the data directories into the hashed layout and re-encrypt chunks with the first encryption key, then exit")
+	genKey := flag.String("genkey", "", "write a new encryption key to this file, then exit")
 	flag.Parse()
 
+	if *genKey != "" {
+		if err := common.GenerateKeyFile(*genKey); err != nil {
+			log.Fatalf("Failed to create key: %v", err)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := common.LoadDataNodeConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// One directory per disk; a single data_dir still works
+	dataDirs := cfg.DataDirs
+	if len(dataDirs) == 0 {
+		dataDirs = []string{cfg.DataDir}
+	}
+
+	var keys [][]byte
+	for _, path := range cfg.EncryptionKeys {
+		key, err := common.ReadKeyFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load encryption key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if *migrate {
+		for _, dir := range dataDirs {
+			moved, err := datanode.MigrateLayout(dir)
+			if err != nil {
+				log.Fatalf("Failed to migrate %s after moving %d chunks: %v", dir, moved, err)
+			}
+			log.Printf("Migrated %d chunks in %s", moved, dir)
+		}
+		if len(keys) > 0 {
+			store, err := datanode.OpenStore(cfg.Store, dataDirs, cfg.VolumeChoice)
+			if err != nil {
+				log.Fatalf("Failed to open chunk store: %v", err)
+			}
+			crypt, err := datanode.NewCryptStore(store, keys)
+			if err != nil {
+				log.Fatalf("Failed to set up encryption: %v", err)
+			}
+			if err := crypt.Rotate(); err != nil {
+				log.Fatalf("Key rotation incomplete, run -migrate again: %v", err)
+			}
+		}
+		return
+	}
+
+	// The identity stored with the data wins over the config
+	nodeID, err := datanode.LoadNodeID(dataDirs, cfg.NodeID)
+	if err != nil {
+		log.Fatalf("Failed to load node ID: %v", err)
+	}
+	if cfg.NodeID != "" && cfg.NodeID != nodeID {
+		log.Printf("Ignoring configured node_id %s: the data directories belong to node %s", cfg.NodeID, nodeID)
+	}
+
+	// The same certificate serves clients and authenticates this node to
+	// the metadata server and other DataNodes
+	tlsCfg, err := transport.ServerTLS(cfg.TLS)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
+
 	// Use config values
 	lis, err := net.Listen("tcp", cfg.Address)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	log.Printf("DataNode %s listening on %s", cfg.NodeID, cfg.Address)
+	log.Printf("DataNode %s listening on %s", nodeID, cfg.Address)
 
 	// Calculate max message size from config (convert MB to bytes)
 	maxMsgSize := cfg.GRPC.MaxMsgMB * 1024 * 1024
@@ -41,15 +107,23 @@ func main() {
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(maxMsgSize),
 		grpc.MaxSendMsgSize(maxMsgSize),
+		tlsCfg.ServerOption(),
 	)
 
-	pb.RegisterDataNodeServiceServer(
-		grpcServer,
-		&datanode.Server{DataDir: cfg.DataDir},
-	)
+	store, err := openStore(cfg, dataDirs, keys)
+	if err != nil {
+		log.Fatalf("Failed to open chunk store: %v", err)
+	}
+	server := &datanode.Server{Store: store, TLS: tlsCfg}
+	if cfg.ChunkKeyFile != "" {
+		if server.ChunkKey, err = common.ReadKeyFile(cfg.ChunkKeyFile); err != nil {
+			log.Fatalf("Failed to load chunk token key: %v", err)
+		}
+	}
+	pb.RegisterDataNodeServiceServer(grpcServer, server)
 
 	// Connect to metadata server
-	conn, err := grpc.Dial(cfg.MetadataAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := transport.Dial(cfg.MetadataAddress, tlsCfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to metadata server: %v", err)
 	}
@@ -64,20 +138,38 @@ func main() {
 		nodeAddress = "localhost" + nodeAddress
 	}
 
-	_, err = client.RegisterNode(context.Background(), &pb.NodeInfo{
-		NodeId:  cfg.NodeID,
+	info := &pb.NodeInfo{
+		NodeId:  nodeID,
 		Address: nodeAddress,
-	})
+		Rack:    cfg.Rack,
+		Zone:    cfg.Zone,
+	}
+	// If the metadata server is down, it asks us to register once it is back
+	_, err = client.RegisterNode(context.Background(), info)
 	if err != nil {
-		log.Fatalf("Failed to register node: %v", err)
+		log.Printf("Failed to register node, will retry on heartbeat: %v", err)
+	} else {
+		log.Printf("DataNode %s registered with metadata server at %s", nodeID, cfg.MetadataAddress)
 	}
 
-	log.Printf("DataNode %s registered with metadata server at %s", cfg.NodeID, cfg.MetadataAddress)
-
 	// Start heartbeat loop
-	datanode.StartHeartbeat(cfg.NodeID, client)
+	server.StartHeartbeat(info, client, time.Duration(cfg.Heartbeat.IntervalSeconds)*time.Second)
 
 	if err := grpcServer.Serve(lis); err != nil {
 		log.Fatalf("Storage Server Error: %v", err)
 	}
 }
+
+// openStore opens the configured chunk store, encrypting it when keys are
+// configured. Without keys chunks are stored and served as they are.
+func openStore(cfg common.DataNodeConfig, dataDirs []string, keys [][]byte) (datanode.ChunkStore, error) {
+	store, err := datanode.OpenStore(cfg.Store, dataDirs, cfg.VolumeChoice)
+	if err != nil || len(keys) == 0 {
+		return store, err
+	}
+	crypt, err := datanode.NewCryptStore(store, keys)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	return crypt, nil
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: dfsadmin [flags] <command> [args]
+
+Commands:
+  balance [--threshold 10%] [--max-mb N] [--dry-run]
+                         move replicas from full to empty DataNodes
+  nodes                  list DataNodes with their liveness, admin state and usage
+  decommission <node>    drain a DataNode so it can be removed
+  recommission <node>    return a DataNode to service
+  maintenance <node> <duration>
+                         suspend re-replication for a node, e.g. 30m;
+                         a duration of 0 ends maintenance
+  setquota <user:NAME|path> <max-bytes> <max-files>
+                         limit the bytes, counting every replica, and files
+                         of a user or under a directory; sizes take K, M, G
+                         or T, and 0 means unlimited
+  quota <user:NAME|path> show a quota and its usage
+
+On clusters with authentication, DFS_TOKEN holds the administrator's token.
+
+Flags:
+`
+
+type admin struct {
+	meta pb.MetadataServiceClient
+	ctx  context.Context // bounds the quick admin RPCs
+	json bool
+}
+
+func main() {
+	fs := flag.NewFlagSet("dfsadmin", flag.ExitOnError)
+	metaAddr := fs.String("metadata", envOr("DFS_METADATA", "localhost:5000"), "metadata server address (env DFS_METADATA)")
+	threshold := fs.String("threshold", "", "allowed distance from mean disk utilisation, e.g. 10%")
+	maxMB := fs.Int64("max-mb", 0, "maximum MB to move in this run")
+	dryRun := fs.Bool("dry-run", false, "only print the planned moves")
+	jsonOut := fs.Bool("json", false, "print machine-readable JSON")
+	var tlsFiles common.TLSConfig
+	fs.StringVar(&tlsFiles.CAFile, "tls-ca", os.Getenv("DFS_TLS_CA"), "CA certificate to verify the metadata server with (env DFS_TLS_CA)")
+	fs.StringVar(&tlsFiles.CertFile, "tls-cert", os.Getenv("DFS_TLS_CERT"), "client certificate (env DFS_TLS_CERT)")
+	fs.StringVar(&tlsFiles.KeyFile, "tls-key", os.Getenv("DFS_TLS_KEY"), "key of the client certificate (env DFS_TLS_KEY)")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	args := parseArgs(fs, os.Args[1:])
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	command, args := args[0], args[1:]
+
+	tlsCfg, err := transport.ClientTLS(tlsFiles)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
+
+	conn, err := transport.Dial(*metaAddr, tlsCfg, transport.WithToken(os.Getenv("DFS_TOKEN")))
+	if err != nil {
+		log.Fatalf("Failed to connect to metadata server: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	a := &admin{meta: pb.NewMetadataServiceClient(conn), ctx: ctx, json: *jsonOut}
+
+	switch command {
+	case "balance":
+		a.need(args, 0)
+		pct, err := parsePercent(*threshold)
+		a.check(err)
+		a.balance(pct, *maxMB<<20, *dryRun)
+	case "nodes":
+		a.need(args, 0)
+		a.nodes()
+	case "decommission":
+		a.need(args, 1)
+		_, err := a.meta.Decommission(a.ctx, &pb.NodeRequest{NodeId: args[0]})
+		a.check(err)
+		a.ok()
+	case "recommission":
+		a.need(args, 1)
+		_, err := a.meta.Recommission(a.ctx, &pb.NodeRequest{NodeId: args[0]})
+		a.check(err)
+		a.ok()
+	case "maintenance":
+		a.need(args, 2)
+		d, err := time.ParseDuration(args[1])
+		if err == nil && d < 0 {
+			err = fmt.Errorf("invalid duration %q", args[1])
+		}
+		a.check(err)
+		_, err = a.meta.EnterMaintenance(a.ctx, &pb.MaintenanceRequest{
+			NodeId:          args[0],
+			DurationSeconds: int64(d / time.Second),
+		})
+		a.check(err)
+		a.ok()
+	case "setquota":
+		a.need(args, 3)
+		user, dir := quotaTarget(args[0])
+		maxBytes, err := parseSize(args[1])
+		a.check(err)
+		maxFiles, err := strconv.ParseInt(args[2], 10, 64)
+		a.check(err)
+		_, err = a.meta.SetQuota(a.ctx, &pb.SetQuotaRequest{User: user, Dir: dir, MaxBytes: maxBytes, MaxFiles: maxFiles})
+		a.check(err)
+		a.ok()
+	case "quota":
+		a.need(args, 1)
+		a.quota(quotaTarget(args[0]))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func (a *admin) balance(thresholdPct float64, maxBytes int64, dryRun bool) {
+	// a balancing run may move many chunks; no deadline
+	report, err := a.meta.Balance(context.Background(), &pb.BalanceRequest{
+		ThresholdPercent: thresholdPct,
+		MaxBytes:         maxBytes,
+		DryRun:           dryRun,
+	})
+	a.check(err)
+
+	if a.json {
+		type move struct {
+			ChunkId string `json:"chunk_id"`
+			From    string `json:"from"`
+			To      string `json:"to"`
+			Size    int64  `json:"size"`
+		}
+		moves := make([]move, 0, len(report.Moves))
+		for _, m := range report.Moves {
+			moves = append(moves, move{m.ChunkId, m.From, m.To, m.Size})
+		}
+		a.emit(struct {
+			Moves  []move `json:"moves"`
+			Bytes  int64  `json:"bytes"`
+			DryRun bool   `json:"dry_run"`
+		}{moves, report.Bytes, dryRun})
+		return
+	}
+	// moves carry on in the background after the report
+	verb := "Moving"
+	if dryRun {
+		verb = "Would move"
+	}
+	for _, m := range report.Moves {
+		fmt.Printf("%s  %s -> %s  (%d bytes)\n", m.ChunkId, m.From, m.To, m.Size)
+	}
+	fmt.Printf("%s %d replicas, %d bytes\n", verb, len(report.Moves), report.Bytes)
+}
+
+func (a *admin) nodes() {
+	res, err := a.meta.ListNodes(a.ctx, &pb.ListNodesRequest{})
+	a.check(err)
+
+	if a.json {
+		type node struct {
+			NodeId           string `json:"node_id"`
+			Address          string `json:"address"`
+			Rack             string `json:"rack,omitempty"`
+			Zone             string `json:"zone,omitempty"`
+			Liveness         string `json:"liveness"`
+			State            string `json:"state"`
+			MaintenanceUntil int64  `json:"maintenance_until,omitempty"`
+			Capacity         int64  `json:"capacity"`
+			Used             int64  `json:"used"`
+			Replicas         int64  `json:"replicas"`
+			LastSeen         int64  `json:"last_seen,omitempty"`
+			FailedVolumes    int32  `json:"failed_volumes,omitempty"`
+		}
+		nodes := make([]node, 0, len(res.Nodes))
+		for _, n := range res.Nodes {
+			nodes = append(nodes, node{
+				n.NodeId, n.Address, n.Rack, n.Zone, n.Liveness, n.AdminState, n.MaintenanceUntil,
+				n.CapacityBytes, n.UsedBytes, n.Replicas, n.LastSeen, n.FailedVolumes,
+			})
+		}
+		a.emit(nodes)
+		return
+	}
+	for _, n := range res.Nodes {
+		state := n.AdminState
+		if n.MaintenanceUntil > 0 {
+			state += " until " + time.Unix(n.MaintenanceUntil, 0).Format(time.Kitchen)
+		}
+		if n.FailedVolumes > 0 {
+			state += fmt.Sprintf(", %d failed volumes", n.FailedVolumes)
+		}
+		fmt.Printf("%-12s %-21s %-16s %-9s %8d replicas %14d/%d bytes  %s\n",
+			n.NodeId, n.Address, n.Zone+"/"+n.Rack, n.Liveness, n.Replicas, n.UsedBytes, n.CapacityBytes, state)
+	}
+}
+
+func (a *admin) quota(user, dir string) {
+	res, err := a.meta.GetQuota(a.ctx, &pb.QuotaRequest{User: user, Dir: dir})
+	a.check(err)
+
+	if a.json {
+		type quota struct {
+			User     string `json:"user,omitempty"`
+			Dir      string `json:"dir,omitempty"`
+			MaxBytes int64  `json:"max_bytes"`
+			MaxFiles int64  `json:"max_files"`
+			Bytes    int64  `json:"bytes"`
+			Files    int64  `json:"files"`
+		}
+		quotas := make([]quota, 0, len(res.Quotas))
+		for _, q := range res.Quotas {
+			quotas = append(quotas, quota{q.User, q.Dir, q.MaxBytes, q.MaxFiles, q.Bytes, q.Files})
+		}
+		a.emit(quotas)
+		return
+	}
+	for _, q := range res.Quotas {
+		name := q.Dir
+		if q.User != "" {
+			name = "user:" + q.User
+		}
+		fmt.Printf("%-24s %14d/%d bytes %10d/%d files\n", name, q.Bytes, q.MaxBytes, q.Files, q.MaxFiles)
+	}
+}
+
+func (a *admin) ok() {
+	if a.json {
+		a.emit(struct {
+			Ok bool `json:"ok"`
+		}{true})
+	}
+}
+
+// need exits with usage help unless exactly n positional args were given
+func (a *admin) need(args []string, n int) {
+	if len(args) != n {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// check exits on error, as JSON when --json is set
+func (a *admin) check(err error) {
+	if err == nil {
+		return
+	}
+	if a.json {
+		a.emit(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		os.Exit(1)
+	}
+	log.Fatalf("Error: %v", err)
+}
+
+func (a *admin) emit(v any) {
+	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+		log.Fatalf("Failed to encode output: %v", err)
+	}
+}
+
+// parsePercent accepts "10%" or "10"; empty means the server default.
+func parsePercent(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || v <= 0 || v >= 100 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return v, nil
+}
+
+// quotaTarget splits "user:NAME" from a directory path.
+func quotaTarget(s string) (user, dir string) {
+	if name, ok := strings.CutPrefix(s, "user:"); ok {
+		return name, ""
+	}
+	return "", s
+}
+
+// parseSize accepts a byte count with an optional K, M, G or T suffix,
+// in powers of 1024.
+func parseSize(s string) (int64, error) {
+	shift := 0
+	if i := strings.IndexAny(strings.ToUpper(s), "KMGT"); i > 0 && i == len(s)-1 {
+		shift = 10 * (1 + strings.IndexByte("KMGT", strings.ToUpper(s)[i]))
+		s = s[:i]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return v << shift, nil
+}
+
+// parseArgs parses flags anywhere on the command line and returns the
+// remaining positional arguments in order.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
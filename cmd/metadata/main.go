@@ -4,6 +4,7 @@ import (
 	"DFS_GO/internal/common"
 	"DFS_GO/internal/metadata"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
 	"flag"
 	"log"
 	"net"
@@ -22,6 +23,12 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Certificates are reloaded when their files change
+	tlsCfg, err := transport.ServerTLS(cfg.TLS)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
+
 	// Use config values
 	lis, err := net.Listen("tcp", cfg.Address)
 	if err != nil {
@@ -30,11 +37,45 @@ func main() {
 
 	log.Printf("Metadata server listening on %s", cfg.Address)
 
-	// Create server with config (WAL path from config)
-	server := metadata.NewServer()
+	// Create server with config (WAL path, TTLs, replication factor)
+	opts := metadata.OptionsFromConfig(cfg)
+	if !metadata.IsPlacementPolicy(opts.Placement) {
+		log.Fatalf("Unknown placement policy %q", opts.Placement)
+	}
+	server := metadata.NewServerWithOptions(opts)
+	server.TLS = tlsCfg
+	if cfg.Auth.Enabled {
+		// only node certificates tell DataNodes from clients posing as one
+		if tlsCfg == nil {
+			log.Fatalf("Authentication needs TLS: configure tls or disable auth")
+		}
+		if server.Auth, err = metadata.LoadAuth(cfg.Auth.TokensFile, cfg.Auth.Admins); err != nil {
+			log.Fatalf("Failed to load tokens: %v", err)
+		}
+		log.Printf("Authenticating clients: %d tokens, %d admins", len(server.Auth.Tokens), len(server.Auth.Admins))
+	}
+	if cfg.Auth.ChunkKeyFile != "" {
+		if server.ChunkKey, err = common.ReadKeyFile(cfg.Auth.ChunkKeyFile); err != nil {
+			log.Fatalf("Failed to load chunk token key: %v", err)
+		}
+	}
+
+	// Rebuild state from the snapshot and WAL before serving any requests
+	if err := server.Recover(); err != nil {
+		log.Fatalf("Recovery failed: %v", err)
+	}
+	log.Printf("Recovered %d files and %d nodes from %s and %s",
+		len(server.State.Files), len(server.State.Nodes), opts.SnapshotPath, opts.WALPath)
+
 	server.StartCleanupLoop()
+	server.StartReplicationLoop()
+	server.StartSnapshotLoop()
+	server.StartBalancerLoop()
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		tlsCfg.ServerOption(),
+		grpc.UnaryInterceptor(server.Authenticate),
+	)
 	pb.RegisterMetadataServiceServer(grpcServer, server)
 
 	if err := grpcServer.Serve(lis); err != nil {
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for uploaded chunks. The codec of each chunk is kept
+// in its metadata, so files written with different settings read back the
+// same way.
+const (
+	CodecGzip   = "gzip"
+	CodecSnappy = "snappy"
+	CodecZstd   = "zstd"
+)
+
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+	zstdDec  *zstd.Decoder
+)
+
+// zstdCodec returns the shared zstd encoder and decoder; their EncodeAll
+// and DecodeAll are safe for concurrent use.
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		zstdEnc, _ = zstd.NewWriter(nil)
+		zstdDec, _ = zstd.NewReader(nil)
+	})
+	return zstdEnc, zstdDec
+}
+
+// checkCodec rejects codecs this client cannot write.
+func checkCodec(codec string) error {
+	switch codec {
+	case "", CodecGzip, CodecSnappy, CodecZstd:
+		return nil
+	}
+	return fmt.Errorf("unknown compression codec %q", codec)
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecSnappy:
+		return s2.EncodeSnappy(nil, data), nil
+	case CodecZstd:
+		enc, _ := zstdCodec()
+		return enc.EncodeAll(data, nil), nil
+	}
+	return nil, checkCodec(codec)
+}
+
+// decompress restores a chunk of size bytes stored with codec.
+func decompress(codec string, data []byte, size int64) ([]byte, error) {
+	var out []byte
+	var err error
+	switch codec {
+	case "":
+		return data, nil
+	case CodecGzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			out, err = io.ReadAll(r)
+		}
+	case CodecSnappy:
+		out, err = s2.Decode(nil, data)
+	case CodecZstd:
+		_, dec := zstdCodec()
+		out, err = dec.DecodeAll(data, make([]byte, 0, size))
+	default:
+		return nil, fmt.Errorf("chunk compressed with unknown codec %q", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", codec, err)
+	}
+	if int64(len(out)) != size {
+		return nil, fmt.Errorf("%s: chunk decompressed to %d bytes, expected %d", codec, len(out), size)
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("all work and no play "), 1000)
+
+	for _, codec := range []string{CodecGzip, CodecSnappy, CodecZstd} {
+		packed, err := compress(codec, data)
+		if err != nil {
+			t.Fatalf("%s: compress failed: %v", codec, err)
+		}
+		if len(packed) >= len(data) {
+			t.Fatalf("%s: %d bytes compressed to %d", codec, len(data), len(packed))
+		}
+		got, err := decompress(codec, packed, int64(len(data)))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("%s: decompress = %d bytes, %v", codec, len(got), err)
+		}
+
+		// a chunk that does not decompress to its recorded size is refused
+		if _, err := decompress(codec, packed, int64(len(data))-1); err == nil {
+			t.Fatalf("%s: size mismatch not detected", codec)
+		}
+	}
+
+	if got, err := decompress("", data, int64(len(data))); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("uncompressed chunk: %d bytes, %v", len(got), err)
+	}
+	if _, err := compress("lz4", data); err == nil {
+		t.Fatal("compress with an unknown codec should fail")
+	}
+	if _, err := decompress("lz4", data, int64(len(data))); err == nil {
+		t.Fatal("decompress with an unknown codec should fail")
+	}
+	if _, err := decompress(CodecGzip, data, int64(len(data))); err == nil {
+		t.Fatal("decompressing data that is not gzip should fail")
+	}
+}
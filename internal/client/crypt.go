@@ -0,0 +1,135 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"DFS_GO/internal/common"
+)
+
+// KeyProvider wraps and unwraps the data keys of encrypted files with a
+// master key kept away from the cluster, such as a local keyfile or a key
+// management service. The metadata server only ever stores wrapped keys.
+type KeyProvider interface {
+	// WrapKey encrypts a data key, returning it with the ID of the master
+	// key used.
+	WrapKey(dataKey []byte) (wrapped []byte, keyId string, err error)
+	// UnwrapKey recovers a data key wrapped by the master key keyId.
+	UnwrapKey(wrapped []byte, keyId string) ([]byte, error)
+}
+
+// KeyFile is a KeyProvider whose master key is read from a local file.
+type KeyFile struct {
+	id  string
+	gcm cipher.AEAD
+}
+
+// LoadKeyFile reads a master key created with common.GenerateKeyFile.
+func LoadKeyFile(path string) (*KeyFile, error) {
+	key, err := common.ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyFile{id: "keyfile:" + common.KeyId(key), gcm: gcm}, nil
+}
+
+func (k *KeyFile) WrapKey(dataKey []byte) ([]byte, string, error) {
+	wrapped, err := seal(k.gcm, dataKey, []byte(k.id))
+	return wrapped, k.id, err
+}
+
+func (k *KeyFile) UnwrapKey(wrapped []byte, keyId string) ([]byte, error) {
+	if keyId != k.id {
+		return nil, fmt.Errorf("file was encrypted with master key %s, not %s", keyId, k.id)
+	}
+	return unseal(k.gcm, wrapped, []byte(keyId))
+}
+
+// fileCipher encrypts the chunks of one file with its data key.
+type fileCipher struct {
+	gcm cipher.AEAD
+}
+
+// newFileKey creates a data key for a new file and wraps it.
+func newFileKey(keys KeyProvider) (*fileCipher, []byte, string, error) {
+	dataKey := make([]byte, common.KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, "", err
+	}
+	wrapped, keyId, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("wrap data key: %w", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return &fileCipher{gcm: gcm}, wrapped, keyId, nil
+}
+
+// openFileKey unwraps the data key of an encrypted file.
+func openFileKey(keys KeyProvider, wrapped []byte, keyId string) (*fileCipher, error) {
+	if keys == nil {
+		return nil, errors.New("file is encrypted and no key is configured")
+	}
+	dataKey, err := keys.UnwrapKey(wrapped, keyId)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCipher{gcm: gcm}, nil
+}
+
+// The chunk index is authenticated with the data, so the chunks of a file
+// cannot be reordered or swapped for one another undetected.
+func chunkAD(index int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(index))
+}
+
+func (c *fileCipher) encrypt(index int, data []byte) ([]byte, error) {
+	return seal(c.gcm, data, chunkAD(index))
+}
+
+func (c *fileCipher) decrypt(index int, data []byte) ([]byte, error) {
+	return unseal(c.gcm, data, chunkAD(index))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, which is put in front of the result.
+func seal(gcm cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func unseal(gcm cipher.AEAD, sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, errors.New("decryption failed: wrong key or corrupted data")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"DFS_GO/internal/common"
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// testKeys returns a KeyProvider backed by a fresh key file.
+func testKeys(t *testing.T) *KeyFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := common.GenerateKeyFile(path); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile failed: %v", err)
+	}
+	return keys
+}
+
+func TestFileCipher(t *testing.T) {
+	keys := testKeys(t)
+	fc, wrapped, keyId, err := newFileKey(keys)
+	if err != nil {
+		t.Fatalf("newFileKey failed: %v", err)
+	}
+
+	chunks := [][]byte{[]byte("first chunk"), []byte("second chunk")}
+	sealed := make([][]byte, len(chunks))
+	for i, c := range chunks {
+		if sealed[i], err = fc.encrypt(i, c); err != nil {
+			t.Fatalf("encrypt %d failed: %v", i, err)
+		}
+		if bytes.Contains(sealed[i], c) {
+			t.Fatalf("chunk %d stored in plaintext", i)
+		}
+	}
+
+	// a reader unwraps the same data key from the file's metadata
+	opened, err := openFileKey(keys, wrapped, keyId)
+	if err != nil {
+		t.Fatalf("openFileKey failed: %v", err)
+	}
+	for i, c := range chunks {
+		if got, err := opened.decrypt(i, sealed[i]); err != nil || !bytes.Equal(got, c) {
+			t.Fatalf("decrypt %d = %q, %v", i, got, err)
+		}
+	}
+
+	// chunks are bound to their index by chunkAD
+	if _, err := opened.decrypt(1, sealed[0]); err == nil {
+		t.Fatal("a chunk moved to another index should not decrypt")
+	}
+	tampered := bytes.Clone(sealed[0])
+	tampered[len(tampered)-1] ^= 1
+	if _, err := opened.decrypt(0, tampered); err == nil {
+		t.Fatal("a corrupted chunk should not decrypt")
+	}
+
+	if _, err := openFileKey(testKeys(t), wrapped, keyId); err == nil {
+		t.Fatal("another master key should not unwrap the data key")
+	}
+	if _, err := openFileKey(nil, wrapped, keyId); err == nil {
+		t.Fatal("an encrypted file should not open without keys")
+	}
+}
@@ -2,13 +2,12 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
-	"time"
 
 	pb "DFS_GO/internal/proto"
-
-	"google.golang.org/grpc"
+	"DFS_GO/internal/transport"
 )
 
 /* Parallel download:
@@ -20,15 +19,30 @@ wait
 assemble
 */
 
-func Download(filename string, meta pb.MetadataServiceClient) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func Download(ctx context.Context, filename string, meta pb.MetadataServiceClient) ([]byte, error) {
+	return DownloadWithOptions(ctx, filename, meta, DefaultOptions())
+}
+
+// DownloadWithOptions reads a file. Each chunk is read from its replicas
+// in turn until one serves it; when none does, the round is retried as
+// opts.Retry allows.
+func DownloadWithOptions(ctx context.Context, filename string, meta pb.MetadataServiceClient, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
 
-	resp, err := meta.GetFile(ctx, &pb.FileRequest{Filename: filename})
+	resp, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.FileMetadata, error) {
+		return meta.GetFile(ctx, &pb.FileRequest{Filename: filename})
+	})
 
 	if err != nil {
 		log.Printf("Failed to get file metadata: %v", err)
-		return nil, err
+		return nil, wrap(err)
+	}
+
+	var fc *fileCipher
+	if len(resp.WrappedKey) > 0 {
+		if fc, err = openFileKey(opts.Keys, resp.WrappedKey, resp.KeyId); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
 	}
 
 	chunks := make([][]byte, len(resp.Chunks))
@@ -42,39 +56,29 @@ func Download(filename string, meta pb.MetadataServiceClient) ([]byte, error) {
 		go func(i int, c *pb.ChunkMetadata) {
 			defer wg.Done()
 
-			var lastErr error
-
-			for _, addr := range c.Nodes {
-				conn, err := grpc.Dial(
-					addr,
-					grpc.WithInsecure(),
-					grpc.WithDefaultCallOptions(
-						grpc.MaxCallSendMsgSize(16*1024*1024),
-						grpc.MaxCallRecvMsgSize(16*1024*1024),
-					),
-				)
-				if err != nil {
-					lastErr = err
-					continue
-				}
-
-				dn := pb.NewDataNodeServiceClient(conn)
+			err := retry(ctx, opts.Retry, true, func() error {
+				lastErr := fmt.Errorf("no replicas for chunk %d", i)
+
+				for _, addr := range c.Nodes {
+					data, err := fetchReplica(ctx, addr, c, opts)
+					if err == nil && fc != nil {
+						data, err = fc.decrypt(i, data)
+					}
+					if err == nil {
+						chunks[i], err = decompress(c.Codec, data, c.Size)
+					}
+					if err == nil {
+						return nil
+					}
 
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				chunk, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c.ChunkId})
-				cancel()
-				conn.Close()
-
-				if err == nil {
-					chunks[i] = chunk.Data
-					return
+					lastErr = err
 				}
-
-				lastErr = err
+				return lastErr
+			})
+			if err != nil {
+				// All replicas failed
+				errs <- wrap(err)
 			}
-
-			// All replicas failed
-			errs <- lastErr
 		}(i, c)
 	}
 
@@ -95,3 +99,28 @@ func Download(filename string, meta pb.MetadataServiceClient) ([]byte, error) {
 	return result, nil
 
 }
+
+// fetchReplica reads a chunk, or a packed file's slice of its container,
+// from one DataNode.
+func fetchReplica(ctx context.Context, addr string, c *pb.ChunkMetadata, opts Options) ([]byte, error) {
+	conn, err := transport.Dial(addr, opts.TLS)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dn := pb.NewDataNodeServiceClient(conn)
+	req := &pb.ChunkRequest{ChunkId: c.ChunkId, Token: c.Token}
+	if c.Packed {
+		// only this file's slice of the shared container
+		req.Offset, req.Length = c.Offset, c.Size
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.TransferTimeout)
+	defer cancel()
+
+	chunk, err := dn.GetChunk(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return chunk.Data, nil
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	pb "DFS_GO/internal/proto"
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeMeta serves GetFile from a fixed answer; other calls are not used.
+type fakeMeta struct {
+	pb.MetadataServiceClient
+	file *pb.FileMetadata
+}
+
+func (m *fakeMeta) GetFile(context.Context, *pb.FileRequest, ...grpc.CallOption) (*pb.FileMetadata, error) {
+	return m.file, nil
+}
+
+// fakeNode keeps chunks in memory, or fails every call when err is set.
+type fakeNode struct {
+	pb.UnimplementedDataNodeServiceServer
+	mu     sync.Mutex
+	chunks map[string][]byte
+	err    error
+	reads  atomic.Int32
+}
+
+func (n *fakeNode) GetChunk(_ context.Context, req *pb.ChunkRequest) (*pb.Chunk, error) {
+	n.reads.Add(1)
+	if n.err != nil {
+		return nil, n.err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	data, ok := n.chunks[req.ChunkId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "chunk %s not found", req.ChunkId)
+	}
+	return &pb.Chunk{ChunkId: req.ChunkId, Data: data}, nil
+}
+
+func (n *fakeNode) StoreChunk(_ context.Context, c *pb.Chunk) (*pb.Ack, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.chunks == nil {
+		n.chunks = make(map[string][]byte)
+	}
+	n.chunks[c.ChunkId] = c.Data
+	return &pb.Ack{Ok: true}, nil
+}
+
+// chunk returns what the node holds of a chunk.
+func (n *fakeNode) chunk(id string) []byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.chunks[id]
+}
+
+// startNode serves n on a local port and returns its address.
+func startNode(t *testing.T, n *fakeNode) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterDataNodeServiceServer(gs, n)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+func TestDownloadFailedReplica(t *testing.T) {
+	good := &fakeNode{chunks: map[string][]byte{"c0": []byte("hello "), "c1": []byte("world")}}
+	bad := &fakeNode{err: status.Error(codes.Internal, "disk failed")}
+	goodAddr, badAddr := startNode(t, good), startNode(t, bad)
+
+	meta := &fakeMeta{file: &pb.FileMetadata{Chunks: []*pb.ChunkMetadata{
+		{ChunkId: "c0", Size: 6, Nodes: []string{badAddr, goodAddr}},
+		{ChunkId: "c1", Size: 5, Nodes: []string{badAddr, goodAddr}},
+	}}}
+
+	// a single attempt: the good replica must be found within the sweep
+	opts := DefaultOptions()
+	opts.Retry.MaxAttempts = 1
+
+	data, err := DownloadWithOptions(context.Background(), "f", meta, opts)
+	if err != nil {
+		t.Fatalf("DownloadWithOptions with one failed replica: %v", err)
+	}
+	if !bytes.Equal(data, []byte("hello world")) {
+		t.Fatalf("got %q, want %q", data, "hello world")
+	}
+	if bad.reads.Load() != 2 {
+		t.Fatalf("failed replica read %d times, want 2", bad.reads.Load())
+	}
+
+	// with every replica failing the download fails rather than crashing
+	meta.file.Chunks[1].Nodes = []string{badAddr}
+	if _, err := DownloadWithOptions(context.Background(), "f", meta, opts); status.Code(err) != codes.Internal {
+		t.Fatalf("download with no working replica: got %v, want Internal", err)
+	}
+}
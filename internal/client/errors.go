@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors returned by this package match these with errors.Is, according
+// to the gRPC status of the call that failed. The status itself, with its
+// details, is still available through status.FromError.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrExists        = errors.New("already exists")
+	ErrPermission    = errors.New("permission denied")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// rpcError is a failed call that also matches the sentinel for its code.
+type rpcError struct {
+	err  error
+	kind error
+}
+
+func (e *rpcError) Error() string { return e.err.Error() }
+
+func (e *rpcError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// wrap tags an error with the sentinel for its gRPC code, if any.
+func wrap(err error) error {
+	var kind error
+	switch status.Code(err) {
+	case codes.NotFound:
+		kind = ErrNotFound
+	case codes.AlreadyExists:
+		kind = ErrExists
+	case codes.PermissionDenied, codes.Unauthenticated:
+		kind = ErrPermission
+	case codes.ResourceExhausted:
+		kind = ErrQuotaExceeded
+	default:
+		return err
+	}
+	return &rpcError{err: err, kind: kind}
+}
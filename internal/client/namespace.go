@@ -0,0 +1,100 @@
+package client
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+)
+
+func List(ctx context.Context, prefix string, recursive bool, meta pb.MetadataServiceClient, opts Options) ([]*pb.FileInfo, error) {
+	opts = opts.withDefaults()
+
+	resp, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.FileList, error) {
+		return meta.ListFiles(ctx, &pb.ListRequest{Prefix: prefix, Recursive: recursive})
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return resp.Files, nil
+}
+
+func Stat(ctx context.Context, filename string, meta pb.MetadataServiceClient, opts Options) (*pb.FileInfo, error) {
+	opts = opts.withDefaults()
+
+	info, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.FileInfo, error) {
+		return meta.StatFile(ctx, &pb.FileRequest{Filename: filename})
+	})
+	return info, wrap(err)
+}
+
+func Delete(ctx context.Context, filename string, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.DeleteFile(ctx, &pb.FileRequest{Filename: filename})
+	})
+	return wrap(err)
+}
+
+func Rename(ctx context.Context, src, dst string, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.RenameFile(ctx, &pb.RenameRequest{Src: src, Dst: dst})
+	})
+	return wrap(err)
+}
+
+func Mkdir(ctx context.Context, dir string, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.Mkdir(ctx, &pb.FileRequest{Filename: dir})
+	})
+	return wrap(err)
+}
+
+func SetReplication(ctx context.Context, filename string, replication int, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.SetReplication(ctx, &pb.SetReplicationRequest{
+			Filename:    filename,
+			Replication: int32(replication),
+		})
+	})
+	return wrap(err)
+}
+
+func Chmod(ctx context.Context, filename string, mode uint32, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.Chmod(ctx, &pb.ChmodRequest{Filename: filename, Mode: mode})
+	})
+	return wrap(err)
+}
+
+// Chown changes the owner and group of a file or directory; an empty
+// owner or group is left unchanged.
+func Chown(ctx context.Context, filename, owner, group string, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	_, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.Chown(ctx, &pb.ChownRequest{Filename: filename, Owner: owner, Group: group})
+	})
+	return wrap(err)
+}
+
+// Quotas returns the quotas of dir and the directories above it, or with
+// an empty dir those of the caller and every directory.
+func Quotas(ctx context.Context, dir string, meta pb.MetadataServiceClient, opts Options) ([]*pb.Quota, error) {
+	opts = opts.withDefaults()
+
+	resp, err := call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.QuotaList, error) {
+		return meta.GetQuota(ctx, &pb.QuotaRequest{Dir: dir})
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return resp.Quotas, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"DFS_GO/internal/common"
+	"DFS_GO/internal/transport"
+	"fmt"
+	"time"
+)
+
+// Options controls how the client talks to the cluster.
+// Zero values fall back to the defaults from DefaultOptions.
+type Options struct {
+	RPCTimeout      time.Duration
+	TransferTimeout time.Duration
+	Workers         int
+	ChunkSize       int
+	Replication     int    // 0 means the metadata server default
+	Compression     string // codec for uploaded chunks; empty stores them raw
+
+	// Keys encrypts uploaded files and decrypts downloaded ones; nil
+	// uploads plaintext.
+	Keys KeyProvider
+
+	// TLS for connections to DataNodes; nil dials them in plaintext.
+	TLS *transport.TLS
+
+	// PackThreshold is the size below which UploadFiles packs files into
+	// shared containers of ContainerSize bytes; 0 turns packing off.
+	PackThreshold int
+	ContainerSize int
+
+	// Retry retries calls to the metadata server and DataNodes that fail
+	// with a transient error. Each attempt has RPCTimeout, or
+	// TransferTimeout for chunk transfers; the context of an operation
+	// bounds it as a whole.
+	Retry RetryPolicy
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RPCTimeout:      5 * time.Second,
+		TransferTimeout: 30 * time.Second,
+		Workers:         4,
+		ChunkSize:       common.ChunkSizeMb * 1024 * 1024,
+		ContainerSize:   4 * 1024 * 1024,
+		Retry:           DefaultRetryPolicy(),
+	}
+}
+
+// OptionsFromConfig maps config/client.yaml onto Options. It fails on
+// retryable codes that ParseCodes rejects.
+func OptionsFromConfig(cfg common.ClientConfig) (Options, error) {
+	retryable, err := ParseCodes(cfg.Retry.RetryableCodes)
+	if err != nil {
+		return Options{}, fmt.Errorf("retry.retryable_codes: %w", err)
+	}
+	if len(retryable) == 0 {
+		retryable = nil
+	}
+	return Options{
+		RPCTimeout:      time.Duration(cfg.Timeouts.RPCSeconds) * time.Second,
+		TransferTimeout: time.Duration(cfg.Timeouts.TransferSeconds) * time.Second,
+		Workers:         cfg.Concurrency.UploadWorkers,
+		ChunkSize:       cfg.ChunkSizeMB * 1024 * 1024,
+		Replication:     cfg.Replication,
+		Compression:     cfg.Compression,
+		PackThreshold:   cfg.SmallFileKB * 1024,
+		Retry: RetryPolicy{
+			MaxAttempts:    cfg.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.Retry.InitialBackoffMS) * time.Millisecond,
+			MaxBackoff:     time.Duration(cfg.Retry.MaxBackoffMS) * time.Millisecond,
+			Multiplier:     cfg.Retry.Multiplier,
+			Jitter:         cfg.Retry.Jitter,
+			RetryableCodes: retryable,
+		},
+	}.withDefaults(), nil
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.RPCTimeout <= 0 {
+		o.RPCTimeout = d.RPCTimeout
+	}
+	if o.TransferTimeout <= 0 {
+		o.TransferTimeout = d.TransferTimeout
+	}
+	if o.Workers <= 0 {
+		o.Workers = d.Workers
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = d.ChunkSize
+	}
+	if o.ContainerSize <= 0 {
+		o.ContainerSize = d.ContainerSize
+	}
+	o.Retry = o.Retry.withDefaults()
+	return o
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"DFS_GO/internal/common"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestOptionsFromConfig(t *testing.T) {
+	var cfg common.ClientConfig
+	cfg.Retry.MaxAttempts = 3
+	cfg.Retry.RetryableCodes = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"}
+
+	opts, err := OptionsFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("OptionsFromConfig failed: %v", err)
+	}
+	if opts.Retry.MaxAttempts != 3 {
+		t.Fatalf("wrong max attempts: got %d", opts.Retry.MaxAttempts)
+	}
+	if want := []codes.Code{codes.Unavailable, codes.ResourceExhausted}; !slices.Equal(opts.Retry.RetryableCodes, want) {
+		t.Fatalf("wrong retryable codes: got %v, want %v", opts.Retry.RetryableCodes, want)
+	}
+	if opts.RPCTimeout != DefaultOptions().RPCTimeout {
+		t.Fatalf("expected the default RPC timeout, got %v", opts.RPCTimeout)
+	}
+
+	cfg.Retry.RetryableCodes = nil
+	if opts, err := OptionsFromConfig(cfg); err != nil || !slices.Equal(opts.Retry.RetryableCodes, DefaultRetryPolicy().RetryableCodes) {
+		t.Fatalf("expected the default retryable codes, got %v, %v", opts.Retry.RetryableCodes, err)
+	}
+
+	cfg.Retry.RetryableCodes = []string{"UNAVAILABLE", "SOMETIMES"}
+	if _, err := OptionsFromConfig(cfg); err == nil {
+		t.Fatal("an unknown retryable code should be rejected")
+	}
+}
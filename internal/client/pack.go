@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+)
+
+// FileUpload names a local file and where to store it.
+type FileUpload struct {
+	Local  string
+	Remote string
+}
+
+// UploadFiles stores several files. With opts.PackThreshold set, files
+// smaller than it are packed together into shared containers, saving a
+// chunk and its replicas per file; the rest are uploaded one by one.
+// Encrypted files are never packed, as each has a key of its own.
+func UploadFiles(ctx context.Context, files []FileUpload, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+
+	var small []FileUpload
+	var sizes []int64
+	for _, f := range files {
+		info, err := os.Stat(f.Local)
+		if err != nil {
+			return err
+		}
+		if opts.Keys == nil && info.Size() > 0 && info.Size() < int64(opts.PackThreshold) {
+			small = append(small, f)
+			sizes = append(sizes, info.Size())
+			continue
+		}
+		if err := UploadWithOptions(ctx, f.Local, f.Remote, meta, opts); err != nil {
+			return err
+		}
+	}
+
+	// fill containers up to ContainerSize, in the order given
+	uploadId := fmt.Sprintf("packed@%d", time.Now().UnixNano())
+	start, size := 0, int64(0)
+	for i := range small {
+		if i > start && size+sizes[i] > int64(opts.ContainerSize) {
+			if err := packContainer(ctx, common.ChunkId(uploadId, start), small[start:i], meta, opts); err != nil {
+				return err
+			}
+			start, size = i, 0
+		}
+		size += sizes[i]
+	}
+	if start < len(small) {
+		return packContainer(ctx, common.ChunkId(uploadId, start), small[start:], meta, opts)
+	}
+	return nil
+}
+
+// packContainer writes files back to back into one container chunk and
+// then creates them as slices of it.
+func packContainer(ctx context.Context, chunkId string, files []FileUpload, meta pb.MetadataServiceClient, opts Options) error {
+	var data []byte
+	packed := make([]*pb.PackedFile, 0, len(files))
+	for _, f := range files {
+		b, err := os.ReadFile(f.Local)
+		if err != nil {
+			return err
+		}
+		packed = append(packed, &pb.PackedFile{
+			Filename: f.Remote,
+			Offset:   int64(len(data)),
+			Length:   int64(len(b)),
+		})
+		data = append(data, b...)
+	}
+
+	allocate := func(ctx context.Context, exclude []string) (*pb.ChunkMetadata, error) {
+		container, err := call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.ChunkMetadata, error) {
+			return meta.AllocateContainer(ctx, &pb.AllocateContainerRequest{
+				ChunkId:      chunkId,
+				Size:         int64(len(data)),
+				Replication:  int32(opts.Replication),
+				ExcludeNodes: exclude,
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("allocate container: %w", err)
+		}
+		return container, nil
+	}
+	if err := storeChunk(ctx, data, opts, allocate); err != nil {
+		return err
+	}
+
+	_, err := call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.Ack, error) {
+		return meta.PackFiles(ctx, &pb.PackFilesRequest{
+			ContainerId: chunkId,
+			Files:       packed,
+		})
+	})
+	return wrap(err)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy says how calls failing with a transient error are retried.
+// Zero fields fall back to DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts    int           // tries per call, including the first; 1 turns retries off
+	InitialBackoff time.Duration // wait before the first retry
+	MaxBackoff     time.Duration
+	Multiplier     float64 // growth of the wait after each retry
+	Jitter         float64 // fraction of each wait picked at random, 0 to 1
+
+	// RetryableCodes are retried for calls that are safe to repeat.
+	// Calls that are not, such as CreateFile, are only retried on
+	// Unavailable, when the server most likely never saw them.
+	RetryableCodes []codes.Code
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableCodes: []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Aborted},
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = d.Multiplier
+	}
+	if p.Jitter <= 0 || p.Jitter > 1 {
+		p.Jitter = d.Jitter
+	}
+	if p.RetryableCodes == nil {
+		p.RetryableCodes = d.RetryableCodes
+	}
+	return p
+}
+
+// retryable reports whether a failed call is worth another try.
+func (p RetryPolicy) retryable(err error, idempotent bool) bool {
+	code := status.Code(err)
+	if !idempotent {
+		return code == codes.Unavailable
+	}
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before retry n, counting from 1.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < n && d < float64(p.MaxBackoff); i++ {
+		d *= p.Multiplier
+	}
+	d = min(d, float64(p.MaxBackoff))
+	return time.Duration(d * (1 - p.Jitter*rand.Float64()))
+}
+
+// retry runs try until it succeeds, fails for good or runs out of
+// attempts or ctx.
+func retry(ctx context.Context, p RetryPolicy, idempotent bool, try func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := try()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err, idempotent) {
+			return err
+		}
+
+		wait := p.backoff(attempt)
+		log.Printf("Retrying in %v: %v", wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// call runs an RPC under the retry policy of opts, giving each attempt
+// timeout within what is left of ctx.
+func call[T any](ctx context.Context, opts Options, timeout time.Duration, idempotent bool, rpc func(context.Context) (T, error)) (T, error) {
+	var res T
+	err := retry(ctx, opts.Retry, idempotent, func() error {
+		actx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var err error
+		res, err = rpc(actx)
+		return err
+	})
+	return res, err
+}
+
+// ParseCodes turns gRPC code names such as UNAVAILABLE into codes.
+func ParseCodes(names []string) ([]codes.Code, error) {
+	res := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		var c codes.Code
+		if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return nil, fmt.Errorf("unknown status code %q", name)
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	pb "DFS_GO/internal/proto"
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.2}
+
+	for _, want := range []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, time.Second}, // capped
+	} {
+		// jitter takes up to a fifth off the wait
+		min := want.max * 4 / 5
+		if d := p.backoff(want.retry); d < min || d > want.max {
+			t.Fatalf("backoff(%d) = %v, want %v to %v", want.retry, d, min, want.max)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	for _, c := range []struct {
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{status.Error(codes.Unavailable, ""), true, true},
+		{status.Error(codes.DeadlineExceeded, ""), true, true},
+		{status.Error(codes.NotFound, ""), true, false},
+		{errors.New("not a status"), true, false},
+		// a call that is not safe to repeat may have been carried out
+		{status.Error(codes.Unavailable, ""), false, true},
+		{status.Error(codes.DeadlineExceeded, ""), false, false},
+		{status.Error(codes.Aborted, ""), false, false},
+	} {
+		if got := p.retryable(c.err, c.idempotent); got != c.want {
+			t.Fatalf("retryable(%v, idempotent %v) = %v, want %v", c.err, c.idempotent, got, c.want)
+		}
+	}
+
+	// retry gives up after MaxAttempts or on a permanent error
+	p.MaxAttempts, p.InitialBackoff = 3, time.Millisecond
+	tries := 0
+	err := retry(context.Background(), p, true, func() error {
+		tries++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if status.Code(err) != codes.Unavailable || tries != 3 {
+		t.Fatalf("retry of a failing call: %d tries, %v", tries, err)
+	}
+	tries = 0
+	retry(context.Background(), p, true, func() error {
+		tries++
+		return status.Error(codes.NotFound, "gone")
+	})
+	if tries != 1 {
+		t.Fatalf("a permanent error was tried %d times", tries)
+	}
+}
+
+func TestParseCodes(t *testing.T) {
+	got, err := ParseCodes([]string{"UNAVAILABLE", "ABORTED"})
+	if err != nil || !slices.Equal(got, []codes.Code{codes.Unavailable, codes.Aborted}) {
+		t.Fatalf("ParseCodes = %v, %v", got, err)
+	}
+	if _, err := ParseCodes([]string{"unavailable"}); err == nil {
+		t.Fatal("code names are upper case")
+	}
+	if got, err := ParseCodes(nil); err != nil || len(got) != 0 {
+		t.Fatalf("ParseCodes(nil) = %v, %v", got, err)
+	}
+}
+
+func TestStoreChunkReallocation(t *testing.T) {
+	good := &fakeNode{}
+	bad := &fakeNode{err: status.Error(codes.Unavailable, "disk full")}
+	goodAddr, badAddr := startNode(t, good), startNode(t, bad)
+
+	opts := DefaultOptions()
+	opts.Retry.MaxAttempts, opts.Retry.InitialBackoff = 2, time.Millisecond
+
+	// the chunk goes to the failing node first, then wherever the metadata
+	// server moves it when told to avoid that node
+	var excluded [][]string
+	allocate := func(_ context.Context, exclude []string) (*pb.ChunkMetadata, error) {
+		excluded = append(excluded, exclude)
+		nodes := []string{badAddr}
+		if slices.Contains(exclude, badAddr) {
+			nodes = []string{goodAddr}
+		}
+		return &pb.ChunkMetadata{ChunkId: "c0", Nodes: nodes}, nil
+	}
+
+	data := []byte("chunk data")
+	if err := storeChunk(context.Background(), data, opts, allocate); err != nil {
+		t.Fatalf("storeChunk with a failing node: %v", err)
+	}
+	if len(excluded) != 2 || excluded[0] != nil || !slices.Equal(excluded[1], []string{badAddr}) {
+		t.Fatalf("allocations excluded %v, want none then %s", excluded, badAddr)
+	}
+	if got := good.chunk("c0"); !bytes.Equal(got, data) {
+		t.Fatalf("reallocated chunk holds %q", got)
+	}
+
+	// reallocation gives up after maxReallocations
+	excluded = nil
+	stuck := func(_ context.Context, exclude []string) (*pb.ChunkMetadata, error) {
+		excluded = append(excluded, exclude)
+		return &pb.ChunkMetadata{ChunkId: "c1", Nodes: []string{badAddr}}, nil
+	}
+	if err := storeChunk(context.Background(), data, opts, stuck); status.Code(err) != codes.Unavailable {
+		t.Fatalf("storeChunk with no working node: got %v, want Unavailable", err)
+	}
+	if len(excluded) != maxReallocations+1 {
+		t.Fatalf("chunk allocated %d times, want %d", len(excluded), maxReallocations+1)
+	}
+
+	// a permanent error is not worth moving the chunk for
+	excluded = nil
+	bad.err = status.Error(codes.PermissionDenied, "bad token")
+	if err := storeChunk(context.Background(), data, opts, stuck); !errors.Is(err, ErrPermission) {
+		t.Fatalf("storeChunk refused by the node: got %v, want ErrPermission", err)
+	}
+	if len(excluded) != 1 {
+		t.Fatalf("chunk reallocated after a permanent error: %v", excluded)
+	}
+}
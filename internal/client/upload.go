@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,31 +11,60 @@ import (
 
 	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
-
-	"google.golang.org/grpc"
+	"DFS_GO/internal/transport"
 )
 
-func Upload(filename string, meta pb.MetadataServiceClient) error {
-	data, err := os.ReadFile(filename)
+// maxReallocations bounds how often a chunk that no DataNode would take
+// is moved to others.
+const maxReallocations = 2
+
+func Upload(ctx context.Context, filename string, meta pb.MetadataServiceClient) error {
+	return UploadWithOptions(ctx, filename, filepath.Base(filename), meta, DefaultOptions())
+}
+
+// UploadWithOptions stores the local file at localPath under remotePath.
+// Calls failing with a transient error are retried as opts.Retry allows,
+// and a chunk none of its DataNodes would take is moved to others.
+func UploadWithOptions(ctx context.Context, localPath, remotePath string, meta pb.MetadataServiceClient, opts Options) error {
+	opts = opts.withDefaults()
+	if err := checkCodec(opts.Compression); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(localPath)
 	if err != nil {
 		return err
 	}
 
-	chunks := Chunk(data, 4*1024*1024)
-	baseName := filepath.Base(filename)
+	chunks := Chunk(data, opts.ChunkSize)
 
-	// Tell metadata server we intend to upload this file
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// Chunk IDs are unique per upload so a re-created file never
+	// collides with replicas of a deleted or renamed one
+	uploadId := fmt.Sprintf("%s@%d", remotePath, time.Now().UnixNano())
+
+	// An encrypted file gets a data key of its own; the metadata server
+	// keeps it wrapped by our master key
+	req := &pb.FileRequest{
+		Filename:    remotePath,
+		Replication: int32(opts.Replication),
+	}
+	var fc *fileCipher
+	if opts.Keys != nil {
+		if fc, req.WrappedKey, req.KeyId, err = newFileKey(opts.Keys); err != nil {
+			return err
+		}
+	}
 
-	_, err = meta.CreateFile(ctx, &pb.FileRequest{Filename: baseName})
+	// Tell metadata server we intend to upload this file
+	_, err = call(ctx, opts, opts.RPCTimeout, false, func(ctx context.Context) (*pb.FileMetadata, error) {
+		return meta.CreateFile(ctx, req)
+	})
 	if err != nil {
-		log.Printf("Warning: CreateFile failed (file may exist): %v", err)
+		return fmt.Errorf("create %s: %w", remotePath, wrap(err))
 	}
 
 	// ----- CONCURRENCY CONTROL -----
-	maxWorkers := 4
-	sem := make(chan struct{}, maxWorkers)
+	sem := make(chan struct{}, opts.Workers)
 
 	var wg sync.WaitGroup
 	errs := make(chan error, len(chunks))
@@ -47,63 +77,46 @@ func Upload(filename string, meta pb.MetadataServiceClient) error {
 			defer wg.Done()
 			defer func() { <-sem }() // release slot
 
-			chunkId := common.ChunkId(baseName, i)
+			chunkId := common.ChunkId(uploadId, i)
 
-			// Ask metadata where to store this chunk
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-
-			metaResp, err := meta.AllocateChunk(ctx, &pb.AllocateChunkRequest{
-				ChunkId:    chunkId,
-				Filename:   baseName,
-				ChunkIndex: int32(i),
-			})
-			if err != nil {
-				errs <- err
-				return
-			}
-
-			// Send chunk to assigned DataNodes
-			successful := 0
-			var lastErr error
-
-			for _, nodeAddr := range metaResp.Nodes {
-				conn, err := grpc.Dial(
-					nodeAddr,
-					grpc.WithInsecure(),
-					grpc.WithDefaultCallOptions(
-						grpc.MaxCallSendMsgSize(16*1024*1024),
-						grpc.MaxCallRecvMsgSize(16*1024*1024),
-					),
-				)
+			// keep the raw bytes when compression does not pay off
+			stored, codec := c, ""
+			if opts.Compression != "" {
+				z, err := compress(opts.Compression, c)
 				if err != nil {
-					lastErr = err
-					continue
+					errs <- err
+					return
 				}
-
-				dn := pb.NewDataNodeServiceClient(conn)
-
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				_, err = dn.StoreChunk(ctx, &pb.Chunk{
-					ChunkId: chunkId,
-					Data:    c,
-				})
-				cancel()
-				conn.Close()
-
-				if err == nil {
-					successful++
-				} else {
-					lastErr = err
+				if len(z) < len(c) {
+					stored, codec = z, opts.Compression
+				}
+			}
+			if fc != nil {
+				var err error
+				if stored, err = fc.encrypt(i, stored); err != nil {
+					errs <- err
+					return
 				}
 			}
 
-			// At least one replica must succeed
-			if successful == 0 {
-				errs <- lastErr
-				return
+			// Ask metadata where to store this chunk
+			allocate := func(ctx context.Context, exclude []string) (*pb.ChunkMetadata, error) {
+				return call(ctx, opts, opts.RPCTimeout, true, func(ctx context.Context) (*pb.ChunkMetadata, error) {
+					return meta.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+						ChunkId:      chunkId,
+						Filename:     remotePath,
+						ChunkIndex:   int32(i),
+						Size:         int64(len(c)),
+						Codec:        codec,
+						StoredSize:   int64(len(stored)),
+						ExcludeNodes: exclude,
+					})
+				})
 			}
 
+			if err := storeChunk(ctx, stored, opts, allocate); err != nil {
+				errs <- err
+			}
 		}(i, chunkData)
 	}
 
@@ -116,3 +129,63 @@ func Upload(filename string, meta pb.MetadataServiceClient) error {
 
 	return nil
 }
+
+// storeChunk stores a chunk on the DataNodes allocate places it on. When
+// none of them takes it, even after retries, allocate is asked for others
+// with those excluded.
+func storeChunk(ctx context.Context, data []byte, opts Options, allocate func(ctx context.Context, exclude []string) (*pb.ChunkMetadata, error)) error {
+	var failed []string
+	for n := 0; ; n++ {
+		chunk, err := allocate(ctx, failed)
+		if err != nil {
+			return wrap(err)
+		}
+
+		err = retry(ctx, opts.Retry, true, func() error {
+			return storeReplicas(ctx, chunk, data, opts)
+		})
+		if err == nil || n >= maxReallocations || !opts.Retry.retryable(err, true) {
+			return wrap(err)
+		}
+		log.Printf("No DataNode of %v took chunk %s, allocating others", chunk.Nodes, chunk.ChunkId)
+		failed = append(failed, chunk.Nodes...)
+	}
+}
+
+// storeReplicas sends a chunk to the DataNodes the metadata server
+// allocated it on. At least one replica must be stored; the metadata
+// server heals the rest.
+func storeReplicas(ctx context.Context, chunk *pb.ChunkMetadata, data []byte, opts Options) error {
+	successful := 0
+	lastErr := fmt.Errorf("no datanodes assigned to chunk %s", chunk.ChunkId)
+
+	for _, nodeAddr := range chunk.Nodes {
+		conn, err := transport.Dial(nodeAddr, opts.TLS)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		dn := pb.NewDataNodeServiceClient(conn)
+
+		ctx, cancel := context.WithTimeout(ctx, opts.TransferTimeout)
+		_, err = dn.StoreChunk(ctx, &pb.Chunk{
+			ChunkId: chunk.ChunkId,
+			Data:    data,
+			Token:   chunk.Token,
+		})
+		cancel()
+		conn.Close()
+
+		if err == nil {
+			successful++
+		} else {
+			lastErr = err
+		}
+	}
+
+	if successful == 0 {
+		return lastErr
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations a chunk token grants
+const (
+	ChunkRead   = "r"
+	ChunkWrite  = "w"
+	ChunkDelete = "d"
+
+	// ChunkReplicate lets a DataNode push a chunk it holds to another;
+	// the token is signed for ReplicateSubject rather than the chunk ID.
+	ChunkReplicate = "c"
+)
+
+// ErrChunkToken is returned for a missing, forged or expired chunk token.
+var ErrChunkToken = errors.New("invalid chunk token")
+
+// SignChunkToken grants one operation on a chunk until expires. A read of
+// a slice of the chunk is bound to its offset and length, so the holder
+// of a packed file cannot read the rest of its container. The metadata
+// server and the DataNodes share key, a key file from GenerateKeyFile.
+func SignChunkToken(key []byte, op, chunkId string, offset, length int64, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + op + "." + chunkMAC(key, op, chunkId, offset, length, exp)
+}
+
+// VerifyChunkToken checks that token grants op on the given chunk, or
+// slice of it.
+func VerifyChunkToken(key []byte, token, op, chunkId string, offset, length int64) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[1] != op {
+		return ErrChunkToken
+	}
+	exp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrChunkToken
+	}
+	want := chunkMAC(key, op, chunkId, offset, length, parts[0])
+	if !hmac.Equal([]byte(parts[2]), []byte(want)) {
+		return ErrChunkToken
+	}
+	if time.Now().Unix() > exp {
+		return fmt.Errorf("%w: expired", ErrChunkToken)
+	}
+	return nil
+}
+
+// ReplicateSubject binds a ChunkReplicate token to the DataNode the chunk
+// may be pushed to, so its holder cannot aim the copy, and the write token
+// that comes with it, at any other chunk or node.
+func ReplicateSubject(chunkId, target string) string {
+	return chunkId + "@" + target
+}
+
+func chunkMAC(key []byte, op, chunkId string, offset, length int64, exp string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\x00%s\x00%d\x00%d\x00%s", op, chunkId, offset, length, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
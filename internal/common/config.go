@@ -6,14 +6,37 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// TLSConfig is the tls section of every config. Leaving it out keeps
+// connections in plaintext.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	CAFile   string `yaml:"ca_file"`
+}
+
 // MetadataConfig matches config/metadata.yaml structure
 type MetadataConfig struct {
 	Address           string `yaml:"address"`
 	ReplicationFactor int    `yaml:"replication_factor"`
-	Heartbeat         struct {
+	Placement         struct {
+		Policy           string `yaml:"policy"`
+		HighWaterPercent int    `yaml:"high_water_percent"`
+	} `yaml:"placement"`
+	Heartbeat struct {
 		TTLSeconds             int `yaml:"ttl_seconds"`
+		DeadSeconds            int `yaml:"dead_seconds"`
 		CleanupIntervalSeconds int `yaml:"cleanup_interval_seconds"`
 	} `yaml:"heartbeat"`
+	Replication struct {
+		IntervalSeconds int `yaml:"interval_seconds"`
+		MaxConcurrent   int `yaml:"max_concurrent"`
+		NodeBandwidthMB int `yaml:"node_bandwidth_mb"`
+	} `yaml:"replication"`
+	Balancer struct {
+		IntervalSeconds  int `yaml:"interval_seconds"`
+		ThresholdPercent int `yaml:"threshold_percent"`
+		MaxMoveMB        int `yaml:"max_move_mb"`
+	} `yaml:"balancer"`
 	WAL struct {
 		Path string `yaml:"path"`
 	} `yaml:"wal"`
@@ -21,31 +44,64 @@ type MetadataConfig struct {
 		Path            string `yaml:"path"`
 		IntervalSeconds int    `yaml:"interval_seconds"`
 	} `yaml:"snapshot"`
+	TLS  TLSConfig `yaml:"tls"`
+	Auth struct {
+		Enabled           bool     `yaml:"enabled"`
+		TokensFile        string   `yaml:"tokens_file"`
+		Admins            []string `yaml:"admins"`
+		ChunkKeyFile      string   `yaml:"chunk_key_file"`
+		ChunkTokenSeconds int      `yaml:"chunk_token_seconds"`
+	} `yaml:"auth"`
 }
 
 // DataNodeConfig matches config/datanode.yaml structure
 type DataNodeConfig struct {
-	NodeID          string `yaml:"node_id"`
-	Address         string `yaml:"address"`
-	DataDir         string `yaml:"data_dir"`
-	MetadataAddress string `yaml:"metadata_address"`
+	NodeID          string   `yaml:"node_id"`
+	Address         string   `yaml:"address"`
+	DataDir         string   `yaml:"data_dir"`
+	DataDirs        []string `yaml:"data_dirs"`
+	VolumeChoice    string   `yaml:"volume_choice"`
+	Store           string   `yaml:"store"`
+	EncryptionKeys  []string `yaml:"encryption_keys"`
+	ChunkKeyFile    string   `yaml:"chunk_key_file"`
+	MetadataAddress string   `yaml:"metadata_address"`
+	Rack            string   `yaml:"rack"`
+	Zone            string   `yaml:"zone"`
 	Heartbeat       struct {
 		IntervalSeconds int `yaml:"interval_seconds"`
 	} `yaml:"heartbeat"`
 	GRPC struct {
 		MaxMsgMB int `yaml:"max_msg_mb"`
 	} `yaml:"grpc"`
+	TLS TLSConfig `yaml:"tls"`
 }
 
 // ClientConfig matches config/client.yaml structure
 type ClientConfig struct {
 	MetadataAddress string `yaml:"metadata_address"`
+	ChunkSizeMB     int    `yaml:"chunk_size_mb"`
+	Replication     int    `yaml:"replication"`
+	SmallFileKB     int    `yaml:"small_file_kb"`
+	Compression     string `yaml:"compression"`
+	KeyFile         string `yaml:"key_file"`
+	Token           string `yaml:"token"`
 	Timeouts        struct {
-		RPCSeconds int `yaml:"rpc_seconds"`
+		RPCSeconds       int `yaml:"rpc_seconds"`
+		TransferSeconds  int `yaml:"transfer_seconds"`
+		OperationSeconds int `yaml:"operation_seconds"`
 	} `yaml:"timeouts"`
+	Retry struct {
+		MaxAttempts      int      `yaml:"max_attempts"`
+		InitialBackoffMS int      `yaml:"initial_backoff_ms"`
+		MaxBackoffMS     int      `yaml:"max_backoff_ms"`
+		Multiplier       float64  `yaml:"multiplier"`
+		Jitter           float64  `yaml:"jitter"`
+		RetryableCodes   []string `yaml:"retryable_codes"`
+	} `yaml:"retry"`
 	Concurrency struct {
 		UploadWorkers int `yaml:"upload_workers"`
 	} `yaml:"concurrency"`
+	TLS TLSConfig `yaml:"tls"`
 }
 
 // LoadMetadataConfig loads metadata server configuration
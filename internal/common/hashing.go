@@ -10,3 +10,18 @@ func ChunkId(filename string, index int) string {
 	h := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", filename, index)))
 	return hex.EncodeToString(h[:])
 }
+
+// ValidChunkId reports whether id has the form ChunkId gives chunk IDs: a
+// SHA-256 in lower-case hex. Nodes name files after chunk IDs, so nothing
+// else is accepted from a client.
+func ValidChunkId(id string) bool {
+	if len(id) != 2*sha256.Size {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
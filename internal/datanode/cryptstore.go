@@ -0,0 +1,227 @@
+package datanode
+
+import (
+	"DFS_GO/internal/common"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sync"
+)
+
+// An encrypted chunk is stored as the magic, the ID of its key, a nonce
+// and the AES-GCM sealed data. The chunk ID is authenticated with the data,
+// so chunk files cannot be swapped for one another.
+const (
+	cryptMagic  = "DFSENC1\x00"
+	keyIdLen    = 8
+	cryptHeader = len(cryptMagic) + keyIdLen // before the nonce
+)
+
+// cryptKey is one of the node's keys.
+type cryptKey struct {
+	id  []byte
+	gcm cipher.AEAD
+}
+
+// CryptStore encrypts the chunks of another store with node-local keys,
+// so a disk taken from the node does not give away its chunks. New chunks
+// are encrypted with the first key; the others only decrypt, until
+// Rotate has re-encrypted their chunks. Chunks stored in plaintext, from
+// before encryption was turned on, are read as they are.
+type CryptStore struct {
+	inner ChunkStore
+	keys  []cryptKey
+
+	// serialises rewrites of a chunk by Rotate with puts and deletes
+	locks [64]sync.Mutex
+}
+
+// NewCryptStore wraps a store. Without keys nothing is encrypted, but
+// chunks encrypted earlier are refused rather than served as ciphertext.
+func NewCryptStore(inner ChunkStore, keys [][]byte) (*CryptStore, error) {
+	c := &CryptStore{inner: inner}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := keyId(key)
+		for _, k := range c.keys {
+			if bytes.Equal(k.id, id) {
+				return nil, fmt.Errorf("key %x configured twice", id)
+			}
+		}
+		c.keys = append(c.keys, cryptKey{id: id, gcm: gcm})
+	}
+	return c, nil
+}
+
+func keyId(key []byte) []byte {
+	id, _ := hex.DecodeString(common.KeyId(key))
+	return id
+}
+
+func (c *CryptStore) lock(chunkId string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(chunkId))
+	return &c.locks[h.Sum32()%uint32(len(c.locks))]
+}
+
+// encrypted reports whether stored data is an encrypted chunk, and the ID
+// of its key.
+func encrypted(data []byte) ([]byte, bool) {
+	if len(data) < cryptHeader || string(data[:len(cryptMagic)]) != cryptMagic {
+		return nil, false
+	}
+	return data[len(cryptMagic):cryptHeader], true
+}
+
+func (c *CryptStore) seal(chunkId string, data []byte) ([]byte, error) {
+	if len(c.keys) == 0 {
+		return data, nil
+	}
+	k := c.keys[0]
+
+	out := make([]byte, cryptHeader+k.gcm.NonceSize(), cryptHeader+k.gcm.NonceSize()+len(data)+k.gcm.Overhead())
+	copy(out, cryptMagic)
+	copy(out[len(cryptMagic):], k.id)
+	nonce := out[cryptHeader:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return k.gcm.Seal(out, nonce, data, []byte(chunkId)), nil
+}
+
+func (c *CryptStore) open(chunkId string, data []byte) ([]byte, error) {
+	id, ok := encrypted(data)
+	if !ok {
+		return data, nil
+	}
+	for _, k := range c.keys {
+		if !bytes.Equal(k.id, id) {
+			continue
+		}
+		body := data[cryptHeader:]
+		if len(body) < k.gcm.NonceSize() {
+			return nil, fmt.Errorf("chunk %s: encrypted data too short", chunkId)
+		}
+		plain, err := k.gcm.Open(nil, body[:k.gcm.NonceSize()], body[k.gcm.NonceSize():], []byte(chunkId))
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s: decryption failed", chunkId)
+		}
+		return plain, nil
+	}
+	return nil, fmt.Errorf("chunk %s is encrypted with key %x, which is not configured", chunkId, id)
+}
+
+func (c *CryptStore) Put(chunkId string, data []byte) error {
+	sealed, err := c.seal(chunkId, data)
+	if err != nil {
+		return err
+	}
+
+	l := c.lock(chunkId)
+	l.Lock()
+	defer l.Unlock()
+	return c.inner.Put(chunkId, sealed)
+}
+
+func (c *CryptStore) Get(chunkId string) ([]byte, error) {
+	data, err := c.inner.Get(chunkId)
+	if err != nil {
+		return nil, err
+	}
+	return c.open(chunkId, data)
+}
+
+func (c *CryptStore) Delete(chunkId string) error {
+	l := c.lock(chunkId)
+	l.Lock()
+	defer l.Unlock()
+	return c.inner.Delete(chunkId)
+}
+
+func (c *CryptStore) List() ([]string, error) {
+	return c.inner.List()
+}
+
+// Stat reports the size of the chunk as stored, encryption included.
+func (c *CryptStore) Stat(chunkId string) (ChunkInfo, error) {
+	return c.inner.Stat(chunkId)
+}
+
+func (c *CryptStore) unwrap() ChunkStore {
+	return c.inner
+}
+
+// Rotate re-encrypts, with the first key, every chunk stored in
+// plaintext or under another key. It runs until done, one chunk at a
+// time, and is safe to run alongside normal traffic.
+func (c *CryptStore) Rotate() error {
+	if len(c.keys) == 0 {
+		return nil
+	}
+	ids, err := c.inner.List()
+	if err != nil {
+		return err
+	}
+
+	rotated, failed := 0, 0
+	for _, id := range ids {
+		done, err := c.rotate(id)
+		if err != nil {
+			log.Printf("Failed to re-encrypt chunk %s: %v", id, err)
+			failed++
+			continue
+		}
+		if done {
+			rotated++
+		}
+	}
+	if rotated > 0 || failed > 0 {
+		log.Printf("Re-encrypted %d chunks with key %x, %d failed", rotated, c.keys[0].id, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d chunks could not be re-encrypted", failed)
+	}
+	return nil
+}
+
+// rotate re-encrypts one chunk if needed, reporting whether it did.
+func (c *CryptStore) rotate(chunkId string) (bool, error) {
+	l := c.lock(chunkId)
+	l.Lock()
+	defer l.Unlock()
+
+	data, err := c.inner.Get(chunkId)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil // deleted meanwhile
+	}
+	if err != nil {
+		return false, err
+	}
+	if id, ok := encrypted(data); ok && bytes.Equal(id, c.keys[0].id) {
+		return false, nil
+	}
+
+	plain, err := c.open(chunkId, data)
+	if err != nil {
+		return false, err
+	}
+	sealed, err := c.seal(chunkId, plain)
+	if err != nil {
+		return false, err
+	}
+	return true, c.inner.Put(chunkId, sealed)
+}
@@ -0,0 +1,54 @@
+package datanode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCryptStore(t *testing.T) {
+	inner := NewMemoryStore()
+	oldKey, newKey := make([]byte, 32), make([]byte, 32)
+	newKey[0] = 1
+
+	inner.Put("plain1", []byte("written before encryption"))
+
+	old, err := NewCryptStore(inner, [][]byte{oldKey})
+	if err != nil {
+		t.Fatalf("NewCryptStore failed: %v", err)
+	}
+	old.Put("aaaa01", []byte("secret data"))
+	if raw, _ := inner.Get("aaaa01"); strings.Contains(string(raw), "secret") {
+		t.Fatal("chunk stored in plaintext")
+	}
+	if data, err := old.Get("plain1"); err != nil || string(data) != "written before encryption" {
+		t.Fatalf("plaintext chunk: %q, %v", data, err)
+	}
+
+	// chunks cannot be swapped for one another
+	raw, _ := inner.Get("aaaa01")
+	inner.Put("bbbb02", raw)
+	if _, err := old.Get("bbbb02"); err == nil {
+		t.Fatal("a chunk copied under another ID should not decrypt")
+	}
+	inner.Delete("bbbb02")
+
+	// rotate to the new key, then drop the old one
+	rotating, _ := NewCryptStore(inner, [][]byte{newKey, oldKey})
+	if err := rotating.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	current, _ := NewCryptStore(inner, [][]byte{newKey})
+	for id, want := range map[string]string{"aaaa01": "secret data", "plain1": "written before encryption"} {
+		if data, err := current.Get(id); err != nil || string(data) != want {
+			t.Fatalf("%s after rotation: %q, %v", id, data, err)
+		}
+	}
+	if raw, _ := inner.Get("plain1"); strings.Contains(string(raw), "encryption") {
+		t.Fatal("plaintext chunk not encrypted by rotation")
+	}
+
+	none, _ := NewCryptStore(inner, nil)
+	if _, err := none.Get("aaaa01"); err == nil {
+		t.Fatal("an encrypted chunk should be refused without its key")
+	}
+}
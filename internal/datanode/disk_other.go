@@ -0,0 +1,14 @@
+//go:build !unix
+
+package datanode
+
+import "errors"
+
+func diskUsage(dir string) (capacity, used int64, err error) {
+	return 0, 0, errors.New("disk usage not supported on this platform")
+}
+
+// syncDir is a no-op where directories cannot be synced.
+func syncDir(dir string) error {
+	return nil
+}
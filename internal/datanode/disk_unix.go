@@ -0,0 +1,31 @@
+//go:build unix
+
+package datanode
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage reports the size of the filesystem holding dir and the bytes
+// in use on it.
+func diskUsage(dir string) (capacity, used int64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, 0, err
+	}
+
+	capacity = int64(uint64(st.Blocks) * uint64(st.Bsize))
+	avail := int64(uint64(st.Bavail) * uint64(st.Bsize))
+	return capacity, capacity - avail, nil
+}
+
+// syncDir flushes a directory, making renames and creations in it durable.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
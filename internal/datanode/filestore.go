@@ -0,0 +1,193 @@
+package datanode
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps each chunk in a file of its own under one data
+// directory, in a hashed layout of two directory levels.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore opens the file store in dir, clearing out writes a crash
+// cut short.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := cleanTemp(dir); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) Put(chunkId string, data []byte) error {
+	if err := WriteChunk(chunkPath(f.dir, chunkId), filepath.Join(f.dir, tmpDir), data); err != nil {
+		return err
+	}
+	return f.dropLegacy(chunkId)
+}
+
+func (f *FileStore) Get(chunkId string) ([]byte, error) {
+	path, err := f.locate(chunkId)
+	if err != nil {
+		return nil, err
+	}
+	return Readchunk(path)
+}
+
+// Delete removes a chunk in either layout.
+func (f *FileStore) Delete(chunkId string) error {
+	if err := DeleteChunk(chunkPath(f.dir, chunkId)); err != nil {
+		return err
+	}
+	return f.dropLegacy(chunkId)
+}
+
+func (f *FileStore) Stat(chunkId string) (ChunkInfo, error) {
+	path, err := f.locate(chunkId)
+	if err != nil {
+		return ChunkInfo{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ChunkInfo{}, err
+	}
+	return ChunkInfo{Size: info.Size()}, nil
+}
+
+// List returns the chunks in either layout.
+func (f *FileStore) List() ([]string, error) {
+	top, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range top {
+		if legacyChunk(e) {
+			ids = append(ids, e.Name())
+			continue
+		}
+		if !e.IsDir() || len(e.Name()) != 2 {
+			continue
+		}
+
+		mid, err := os.ReadDir(filepath.Join(f.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range mid {
+			if !m.IsDir() {
+				continue
+			}
+			chunks, err := os.ReadDir(filepath.Join(f.dir, e.Name(), m.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range chunks {
+				if c.Type().IsRegular() {
+					ids = append(ids, c.Name())
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+// locate returns the path of a chunk in either layout.
+func (f *FileStore) locate(chunkId string) (string, error) {
+	for _, path := range []string{chunkPath(f.dir, chunkId), filepath.Join(f.dir, chunkId)} {
+		info, err := os.Stat(path)
+		if err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", notFound(chunkId)
+}
+
+// dropLegacy removes the copy of a chunk the flat layout may still hold.
+func (f *FileStore) dropLegacy(chunkId string) error {
+	path := filepath.Join(f.dir, chunkId)
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	return DeleteChunk(path)
+}
+
+// tmpDir holds chunks being written inside each data directory; whatever
+// is left in it at startup is from writes a crash cut short.
+const tmpDir = "tmp"
+
+// chunkPath places a chunk two directory levels down, named after the
+// first characters of its ID (ab/cd/abcd...), so no directory grows too
+// large for the filesystem. IDs too short for that go under __/__.
+func chunkPath(dataDir, chunkId string) string {
+	prefix := chunkId
+	if len(prefix) < 4 {
+		prefix = "____"
+	}
+	return filepath.Join(dataDir, prefix[0:2], prefix[2:4], chunkId)
+}
+
+// legacyChunk reports whether a top-level entry of a data directory is a
+// chunk stored in the old flat layout.
+func legacyChunk(e os.DirEntry) bool {
+	return e.Type().IsRegular() && e.Name() != idFile && e.Name() != probeFile
+}
+
+// cleanTemp removes writes left unfinished in a data directory.
+func cleanTemp(dataDir string) error {
+	dir := filepath.Join(dataDir, tmpDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateLayout moves chunks stored flat in dataDir into the hashed
+// layout and returns how many it moved. Run it while the DataNode is
+// stopped; until then the DataNode finds chunks in either layout.
+func MigrateLayout(dataDir string) (int, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, e := range entries {
+		if !legacyChunk(e) {
+			continue
+		}
+
+		dst := chunkPath(dataDir, e.Name())
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(filepath.Join(dataDir, e.Name()), dst); err != nil {
+			return moved, err
+		}
+		if err := syncDir(filepath.Dir(dst)); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+
+	if moved > 0 {
+		return moved, syncDir(dataDir)
+	}
+	return moved, nil
+}
@@ -0,0 +1,49 @@
+package datanode
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataNodeLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	old, fresh := chunkID("old"), chunkID("new")
+
+	// a chunk in the old flat layout and a write cut short by a crash
+	os.WriteFile(filepath.Join(dir, old), []byte("old"), 0644)
+	os.MkdirAll(filepath.Join(dir, "tmp"), 0755)
+	os.WriteFile(filepath.Join(dir, "tmp", fresh+".42"), []byte("partial"), 0644)
+
+	dn := &Server{DataDir: dir}
+	got, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: old})
+	if err != nil || string(got.Data) != "old" {
+		t.Fatalf("flat chunk unreadable: %v, %v", got, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(entries) != 0 {
+		t.Fatalf("leftover temp files not cleaned up: %v", entries)
+	}
+
+	if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: fresh, Data: []byte("new")}); err != nil {
+		t.Fatalf("StoreChunk failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fresh[0:2], fresh[2:4], fresh)); err != nil {
+		t.Fatalf("chunk not in hashed layout: %v", err)
+	}
+
+	moved, err := MigrateLayout(dir)
+	if err != nil || moved != 1 {
+		t.Fatalf("MigrateLayout = %d, %v", moved, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, old[0:2], old[2:4], old)); err != nil {
+		t.Fatalf("flat chunk not migrated: %v", err)
+	}
+
+	held, _ := dn.CheckChunks(ctx, &pb.ChunkList{ChunkIds: []string{old, fresh, chunkID("missing")}})
+	if len(held.ChunkIds) != 2 {
+		t.Fatalf("expected both chunks after migration, got %v", held.ChunkIds)
+	}
+}
@@ -7,24 +7,118 @@ import (
 	"time"
 )
 
-func StartHeartbeat(nodeId string, meta pb.MetadataServiceClient) {
+func (s *Server) StartHeartbeat(info *pb.NodeInfo, meta pb.MetadataServiceClient, interval time.Duration) {
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+
 	go func() {
-		log.Printf("Starting heartbeat loop for node %s", nodeId)
+		log.Printf("Starting heartbeat loop for node %s", info.NodeId)
+		reported := 0 // failed volumes the metadata server knows about
 		for {
+			// a failed disk takes its chunks with it; tell the metadata
+			// server at once rather than waiting for reads to fail
+			s.checkVolumes()
+			if failed := s.failedVolumes(); failed > reported {
+				if err := s.blockReport(info.NodeId, meta); err != nil {
+					log.Printf("Failed to report lost chunks: %v", err)
+				} else {
+					reported = failed
+				}
+			}
+
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 
-			_, err := meta.Heartbeat(ctx, &pb.NodeHeartbeat{
-				NodeId: nodeId,
-			})
+			resp, err := meta.Heartbeat(ctx, s.Stats(info.NodeId))
 
 			if err != nil {
 				log.Printf("Heartbeat failed: %v", err)
 			} else {
-				log.Printf("Heartbeat sent successfully from node %s", nodeId)
+				log.Printf("Heartbeat sent successfully from node %s", info.NodeId)
 			}
 
 			cancel()
-			time.Sleep(3 * time.Second)
+
+			if err == nil {
+				for _, cmd := range resp.Commands {
+					s.runCommand(cmd, info, meta)
+				}
+			}
+			time.Sleep(interval)
 		}
 	}()
 }
+
+// runCommand carries out an instruction from the metadata server.
+func (s *Server) runCommand(cmd *pb.NodeCommand, info *pb.NodeInfo, meta pb.MetadataServiceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch cmd.Type {
+	case pb.NodeCommand_REGISTER:
+		log.Printf("Metadata server does not know node %s, registering again", info.NodeId)
+		if _, err := meta.RegisterNode(ctx, info); err != nil {
+			log.Printf("Failed to register node: %v", err)
+		}
+	case pb.NodeCommand_BLOCK_REPORT:
+		if err := s.blockReport(info.NodeId, meta); err != nil {
+			log.Printf("Failed to send block report: %v", err)
+		}
+	case pb.NodeCommand_DELETE_CHUNKS:
+		store, err := s.store()
+		if err != nil {
+			log.Printf("Failed to delete chunks: %v", err)
+			return
+		}
+		for _, id := range cmd.ChunkIds {
+			if err := checkChunkId(id); err != nil {
+				log.Printf("Not deleting chunk: %v", err)
+				continue
+			}
+			if err := s.remove(store, id); err != nil {
+				log.Printf("Failed to delete chunk %s: %v", id, err)
+			}
+		}
+	case pb.NodeCommand_REPLICATE_CHUNK:
+		if len(cmd.ChunkIds) == 0 {
+			return
+		}
+		// a copy can take a while; do not hold up heartbeats
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			_, err := s.ReplicateChunk(ctx, &pb.ReplicateRequest{
+				ChunkId: cmd.ChunkIds[0],
+				Target:  cmd.Target,
+				Token:   cmd.Token,
+			})
+			if err != nil {
+				log.Printf("Failed to replicate chunk %s to %s: %v", cmd.ChunkIds[0], cmd.Target, err)
+			}
+		}()
+	default:
+		log.Printf("Ignoring unknown command %v", cmd.Type)
+	}
+}
+
+// blockReport sends the metadata server the list of chunks this node holds.
+func (s *Server) blockReport(nodeId string, meta pb.MetadataServiceClient) error {
+	store, err := s.store()
+	if err != nil {
+		return err
+	}
+	ids, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err = meta.BlockReport(ctx, &pb.BlockReportRequest{
+		NodeId:   nodeId,
+		ChunkIds: ids,
+	})
+	return err
+}
@@ -0,0 +1,86 @@
+package datanode
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// idFile holds the node's identity inside each data directory, so the
+// identity travels with the chunks rather than with the config.
+const idFile = "node.id"
+
+// LoadNodeID returns the node ID stored in the data directories, creating
+// it on first start. A new node takes fallback as its ID when set,
+// otherwise a random UUID; either way later changes to the config do not
+// change the ID. Every directory gets a copy, so losing a disk does not
+// lose the identity, and directories that cannot be read are skipped.
+func LoadNodeID(dataDirs []string, fallback string) (string, error) {
+	var id, idDir string
+	var missing []string
+	usable := 0
+	for _, dir := range dataDirs {
+		path := filepath.Join(dir, idFile)
+
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			missing = append(missing, dir)
+			continue
+		}
+		if err != nil {
+			log.Printf("Skipping data directory %s: %v", dir, err)
+			continue
+		}
+
+		found := strings.TrimSpace(string(b))
+		usable++
+		switch {
+		case found == "":
+			return "", fmt.Errorf("%s is empty", path)
+		case id == "":
+			id, idDir = found, dir
+		case found != id:
+			return "", fmt.Errorf("%s belongs to node %s but %s to node %s", dir, found, idDir, id)
+		}
+	}
+
+	if id == "" {
+		id = fallback
+	}
+	if id == "" {
+		var err error
+		if id, err = newUUID(); err != nil {
+			return "", err
+		}
+	}
+
+	for _, dir := range missing {
+		err := os.MkdirAll(dir, 0755)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, idFile), []byte(id+"\n"), 0644)
+		}
+		if err != nil {
+			log.Printf("Skipping data directory %s: %v", dir, err)
+			continue
+		}
+		usable++
+	}
+	if usable == 0 {
+		return "", fmt.Errorf("no usable data directory")
+	}
+	return id, nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,407 @@
+package datanode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// logDir holds the segments of a log store inside its data directory.
+const logDir = "log"
+
+// segmentLimit is the size at which a log store starts a new segment.
+const segmentLimit = 64 << 20
+
+// A log record is a header of crc32 (over the rest of the record), kind,
+// ID length and data length, followed by the ID and the data.
+const recordHeader = 4 + 1 + 2 + 4
+
+// Log record kinds
+const (
+	recordPut    byte = 1
+	recordDelete byte = 2
+)
+
+var errCorruptRecord = errors.New("corrupt log record")
+
+// logEntry locates the latest record of a chunk.
+type logEntry struct {
+	seg   int
+	off   int64 // start of the record
+	idLen int
+	size  int64 // length of the data
+}
+
+func (e logEntry) length() int64 {
+	return recordHeader + int64(e.idLen) + e.size
+}
+
+// segment is one file of a log store.
+type segment struct {
+	f    *os.File
+	size int64
+	dead int64 // bytes held by records that no longer count
+
+	// chunks deleted by records in this segment
+	tombstones []string
+}
+
+// LogStore packs chunks into append-only segment files, which suits many
+// small chunks better than a file each. An index of the latest record of
+// every chunk is kept in memory and rebuilt from the segments on open.
+// Segments mostly holding overwritten or deleted chunks are compacted.
+type LogStore struct {
+	dir string
+
+	mu     sync.RWMutex
+	index  map[string]logEntry
+	segs   map[int]*segment
+	active int // the newest segment, the only one written to
+}
+
+// NewLogStore opens the log store in dir. Records that fail their checksum,
+// such as one torn by a crash, end the replay of their segment; appends
+// then go to a new segment so the damage is never built upon.
+func NewLogStore(dir string) (*LogStore, error) {
+	l := &LogStore{
+		dir:   filepath.Join(dir, logDir),
+		index: make(map[string]logEntry),
+		segs:  make(map[int]*segment),
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var nums []int
+	for _, e := range entries {
+		n, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".seg"))
+		if err == nil && strings.HasSuffix(e.Name(), ".seg") {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+
+	damaged := false
+	for _, n := range nums {
+		ok, err := l.load(n)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		damaged = !ok
+		l.active = n
+	}
+	if len(nums) == 0 || damaged {
+		if err := l.roll(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *LogStore) segPath(n int) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%08d.seg", n))
+}
+
+// load opens a segment and replays its records into the index. It
+// reports whether the whole segment could be read.
+func (l *LogStore) load(n int) (bool, error) {
+	f, err := os.OpenFile(l.segPath(n), os.O_RDWR, 0644)
+	if err != nil {
+		return false, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+
+	seg := &segment{f: f}
+	l.segs[n] = seg
+
+	r := bufio.NewReader(io.NewSectionReader(f, 0, info.Size()))
+	var off int64
+	for {
+		kind, id, data, err := readRecord(r)
+		if err != nil {
+			break
+		}
+
+		e := logEntry{seg: n, off: off, idLen: len(id), size: int64(len(data))}
+		off += e.length()
+		seg.size = off
+
+		if old, ok := l.index[id]; ok {
+			l.segs[old.seg].dead += old.length()
+		}
+		if kind == recordDelete {
+			delete(l.index, id)
+			seg.dead += e.length()
+			seg.tombstones = append(seg.tombstones, id)
+			continue
+		}
+		l.index[id] = e
+	}
+
+	if off < info.Size() {
+		log.Printf("Ignoring %d bytes of damaged records in %s", info.Size()-off, l.segPath(n))
+		seg.size = info.Size()
+		seg.dead += info.Size() - off
+		return false, nil
+	}
+	return true, nil
+}
+
+// readRecord reads and verifies the next record.
+func readRecord(r io.Reader) (kind byte, id string, data []byte, err error) {
+	var hdr [recordHeader]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, "", nil, err
+	}
+
+	idLen := binary.LittleEndian.Uint16(hdr[5:7])
+	dataLen := binary.LittleEndian.Uint32(hdr[7:11])
+	if dataLen > segmentLimit {
+		return 0, "", nil, errCorruptRecord
+	}
+	body := make([]byte, int(idLen)+int(dataLen))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, "", nil, err
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[4:])
+	crc.Write(body)
+	if crc.Sum32() != binary.LittleEndian.Uint32(hdr[0:4]) {
+		return 0, "", nil, errCorruptRecord
+	}
+	return hdr[4], string(body[:idLen]), body[idLen:], nil
+}
+
+func encodeRecord(kind byte, id string, data []byte) []byte {
+	rec := make([]byte, recordHeader+len(id)+len(data))
+	rec[4] = kind
+	binary.LittleEndian.PutUint16(rec[5:7], uint16(len(id)))
+	binary.LittleEndian.PutUint32(rec[7:11], uint32(len(data)))
+	copy(rec[recordHeader:], id)
+	copy(rec[recordHeader+len(id):], data)
+	binary.LittleEndian.PutUint32(rec[0:4], crc32.ChecksumIEEE(rec[4:]))
+	return rec
+}
+
+// roll starts a new segment. Caller must hold the write lock.
+func (l *LogStore) roll() error {
+	n := l.active + 1
+	f, err := os.OpenFile(l.segPath(n), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		f.Close()
+		return err
+	}
+	l.segs[n] = &segment{f: f}
+	l.active = n
+	return nil
+}
+
+// append writes a record to the active segment and syncs it.
+// Caller must hold the write lock.
+func (l *LogStore) append(kind byte, id string, data []byte) (logEntry, error) {
+	if len(id) > 0xffff || len(data) > segmentLimit {
+		return logEntry{}, fmt.Errorf("chunk %s too large for the log store", id)
+	}
+
+	rec := encodeRecord(kind, id, data)
+	seg := l.segs[l.active]
+	if seg.size > 0 && seg.size+int64(len(rec)) > segmentLimit {
+		if err := l.roll(); err != nil {
+			return logEntry{}, err
+		}
+		seg = l.segs[l.active]
+	}
+
+	_, err := seg.f.WriteAt(rec, seg.size)
+	if err == nil {
+		err = seg.f.Sync()
+	}
+	if err != nil {
+		// leave no partial record for the next append to follow
+		seg.f.Truncate(seg.size)
+		return logEntry{}, err
+	}
+
+	e := logEntry{seg: l.active, off: seg.size, idLen: len(id), size: int64(len(data))}
+	seg.size += int64(len(rec))
+	return e, nil
+}
+
+// read returns the data of a record.
+func (l *LogStore) read(e logEntry) ([]byte, error) {
+	seg, ok := l.segs[e.seg]
+	if !ok {
+		return nil, errCorruptRecord
+	}
+	_, _, data, err := readRecord(io.NewSectionReader(seg.f, e.off, e.length()))
+	return data, err
+}
+
+func (l *LogStore) Put(chunkId string, data []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, err := l.append(recordPut, chunkId, data)
+	if err != nil {
+		return err
+	}
+	old, ok := l.index[chunkId]
+	l.index[chunkId] = e
+	if ok {
+		l.discard(old)
+	}
+	return nil
+}
+
+func (l *LogStore) Get(chunkId string) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	e, ok := l.index[chunkId]
+	if !ok {
+		return nil, notFound(chunkId)
+	}
+	return l.read(e)
+}
+
+func (l *LogStore) Delete(chunkId string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old, ok := l.index[chunkId]
+	if !ok {
+		return nil
+	}
+	e, err := l.append(recordDelete, chunkId, nil)
+	if err != nil {
+		return err
+	}
+	seg := l.segs[e.seg]
+	seg.dead += e.length()
+	seg.tombstones = append(seg.tombstones, chunkId)
+
+	delete(l.index, chunkId)
+	l.discard(old)
+	return nil
+}
+
+func (l *LogStore) List() ([]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ids := make([]string, 0, len(l.index))
+	for id := range l.index {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (l *LogStore) Stat(chunkId string) (ChunkInfo, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	e, ok := l.index[chunkId]
+	if !ok {
+		return ChunkInfo{}, notFound(chunkId)
+	}
+	return ChunkInfo{Size: e.size}, nil
+}
+
+// Close closes the segment files.
+func (l *LogStore) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	for _, seg := range l.segs {
+		if cerr := seg.f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// discard accounts for a record that no longer counts, compacting its
+// segment once at least half of it is dead. Caller must hold the write
+// lock.
+func (l *LogStore) discard(e logEntry) {
+	seg := l.segs[e.seg]
+	seg.dead += e.length()
+	if e.seg == l.active || seg.dead*2 < seg.size {
+		return
+	}
+	if err := l.compact(e.seg); err != nil {
+		log.Printf("Failed to compact %s: %v", l.segPath(e.seg), err)
+	}
+}
+
+// compact copies the live records of a sealed segment to the active one
+// and removes it. Caller must hold the write lock.
+func (l *LogStore) compact(n int) error {
+	seg := l.segs[n]
+
+	for id, e := range l.index {
+		if e.seg != n {
+			continue
+		}
+		data, err := l.read(e)
+		if err != nil {
+			return err
+		}
+		ne, err := l.append(recordPut, id, data)
+		if err != nil {
+			return err
+		}
+		l.index[id] = ne
+	}
+
+	// a delete only matters while an older segment may hold the chunk
+	older := false
+	for k := range l.segs {
+		older = older || k < n
+	}
+	if older {
+		for _, id := range seg.tombstones {
+			if _, ok := l.index[id]; ok {
+				continue
+			}
+			e, err := l.append(recordDelete, id, nil)
+			if err != nil {
+				return err
+			}
+			active := l.segs[e.seg]
+			active.dead += e.length()
+			active.tombstones = append(active.tombstones, id)
+		}
+	}
+
+	seg.f.Close()
+	delete(l.segs, n)
+	if err := os.Remove(l.segPath(n)); err != nil {
+		return err
+	}
+	return syncDir(l.dir)
+}
@@ -0,0 +1,62 @@
+package datanode
+
+import "sync"
+
+// MemoryStore keeps chunks in memory. It is meant for tests.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	chunks map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{chunks: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Put(chunkId string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.chunks[chunkId] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemoryStore) Get(chunkId string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.chunks[chunkId]
+	if !ok {
+		return nil, notFound(chunkId)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *MemoryStore) Delete(chunkId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.chunks, chunkId)
+	return nil
+}
+
+func (m *MemoryStore) List() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.chunks))
+	for id := range m.chunks {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) Stat(chunkId string) (ChunkInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.chunks[chunkId]
+	if !ok {
+		return ChunkInfo{}, notFound(chunkId)
+	}
+	return ChunkInfo{Size: int64(len(data))}, nil
+}
@@ -0,0 +1,60 @@
+package datanode
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
+	"context"
+	"time"
+)
+
+// ReplicateChunk pushes a local chunk straight to another DataNode, so
+// replica moves never route data through the metadata server. Only a
+// replicate token from the metadata server, naming the target, allows it:
+// the copy is stored there with this node's own write token.
+func (s *Server) ReplicateChunk(ctx context.Context, req *pb.ReplicateRequest) (*pb.Ack, error) {
+	if err := checkChunkId(req.ChunkId); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(common.ChunkReplicate, common.ReplicateSubject(req.ChunkId, req.Target), req.Token, 0, 0); err != nil {
+		return nil, err
+	}
+
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Get(req.ChunkId)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := transport.Dial(req.Target, s.TLS)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = pb.NewDataNodeServiceClient(conn).StoreChunk(ctx, &pb.Chunk{
+		ChunkId: req.ChunkId,
+		Data:    data,
+		Token:   s.writeToken(req.ChunkId),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// writeToken lets this node store a chunk on another, which shares its
+// chunk key.
+func (s *Server) writeToken(chunkId string) string {
+	if s.ChunkKey == nil {
+		return ""
+	}
+	return common.SignChunkToken(s.ChunkKey, common.ChunkWrite, chunkId, 0, 0, time.Now().Add(time.Minute))
+}
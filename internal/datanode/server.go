@@ -1,32 +1,266 @@
 package datanode
 
 import (
+	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
 	"context"
-	"path/filepath"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
 	pb.UnimplementedDataNodeServiceServer
-	DataDir string
+
+	// Store keeps the chunks. When nil, a store of the Backend kind
+	// (FileBackend by default) is opened over DataDirs, or DataDir when
+	// that is empty.
+	Store        ChunkStore
+	Backend      string
+	DataDir      string
+	DataDirs     []string
+	VolumeChoice string // RoundRobin (default) or FreeSpace
+
+	// TLS for pushing replicas to other DataNodes; nil dials them in
+	// plaintext
+	TLS *transport.TLS
+
+	// ChunkKey verifies the chunk tokens the metadata server hands out;
+	// nil serves any chunk to anyone
+	ChunkKey []byte
+
+	active atomic.Int32 // chunk transfers in progress
+
+	// chunks in the store: counted when it opens and again when a volume
+	// fails, kept up to date by put and remove in between
+	chunks       atomic.Int64
+	countedFails atomic.Int32
+
+	storeOnce sync.Once
+	storeErr  error
+}
+
+// store returns the chunk store, opening the default one on first use.
+// A store that cannot be opened fails every request with Unavailable.
+func (s *Server) store() (ChunkStore, error) {
+	s.storeOnce.Do(func() {
+		if s.Store == nil {
+			dirs := s.DataDirs
+			if len(dirs) == 0 {
+				dirs = []string{s.DataDir}
+			}
+			s.Store, s.storeErr = OpenStore(s.Backend, dirs, s.VolumeChoice)
+		}
+		if s.storeErr == nil {
+			s.countChunks(s.Store)
+		}
+	})
+	if s.storeErr != nil {
+		return nil, status.Errorf(codes.Unavailable, "chunk store: %v", s.storeErr)
+	}
+	return s.Store, nil
+}
+
+// volumes returns the disks under the store, or nil when it has none.
+func (s *Server) volumes() *volumes {
+	store, err := s.store()
+	if err != nil {
+		return nil
+	}
+	return volumesOf(store)
+}
+
+// volumesOf returns the disks under a store, or nil when it has none.
+func volumesOf(store ChunkStore) *volumes {
+	for {
+		switch st := store.(type) {
+		case *volumes:
+			return st
+		case wrapper:
+			store = st.unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// countChunks sets the chunk count from a listing of the store.
+func (s *Server) countChunks(store ChunkStore) {
+	if vs := volumesOf(store); vs != nil {
+		s.countedFails.Store(int32(vs.failedVolumes()))
+	}
+	if ids, err := store.List(); err == nil {
+		s.chunks.Store(int64(len(ids)))
+	}
+}
+
+// put stores a chunk, counting it when it is new.
+func (s *Server) put(store ChunkStore, chunkId string, data []byte) error {
+	_, err := store.Stat(chunkId)
+	existed := err == nil
+	if err := store.Put(chunkId, data); err != nil {
+		return err
+	}
+	if !existed {
+		s.chunks.Add(1)
+	}
+	return nil
+}
+
+// remove deletes a chunk, no longer counting it when it was there.
+func (s *Server) remove(store ChunkStore, chunkId string) error {
+	_, err := store.Stat(chunkId)
+	existed := err == nil
+	if err := store.Delete(chunkId); err != nil {
+		return err
+	}
+	if existed {
+		s.chunks.Add(-1)
+	}
+	return nil
+}
+
+// checkVolumes probes the disks under the store, if it has any.
+func (s *Server) checkVolumes() {
+	if vs := s.volumes(); vs != nil {
+		vs.checkVolumes()
+	}
+}
+
+// failedVolumes counts the disks under the store taken out of service.
+func (s *Server) failedVolumes() int {
+	if vs := s.volumes(); vs != nil {
+		return vs.failedVolumes()
+	}
+	return 0
 }
 
 func (s *Server) StoreChunk(ctx context.Context, c *pb.Chunk) (*pb.Ack, error) {
-	path := filepath.Join(s.DataDir, c.ChunkId)
-	err := WriteChunk(path, c.Data)
+	if err := checkChunkId(c.ChunkId); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(common.ChunkWrite, c.ChunkId, c.Token, 0, 0); err != nil {
+		return nil, err
+	}
 
-	return &pb.Ack{Ok: err == nil}, err
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.put(store, c.ChunkId, c.Data); err != nil {
+		return nil, chunkStatus(c.ChunkId, err)
+	}
+	return &pb.Ack{Ok: true}, nil
 }
 
 func (s *Server) GetChunk(ctx context.Context, req *pb.ChunkRequest) (*pb.Chunk, error) {
-	path := filepath.Join(s.DataDir, req.ChunkId)
-	data, err := Readchunk(path)
+	if err := checkChunkId(req.ChunkId); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(common.ChunkRead, req.ChunkId, req.Token, req.Offset, req.Length); err != nil {
+		return nil, err
+	}
+
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	store, err := s.store()
 	if err != nil {
 		return nil, err
 	}
+	data, err := store.Get(req.ChunkId)
+	if err != nil {
+		return nil, chunkStatus(req.ChunkId, err)
+	}
+
+	// a packed file is a slice of its container chunk
+	if req.Length > 0 {
+		if req.Offset < 0 || req.Offset+req.Length > int64(len(data)) {
+			return nil, status.Errorf(codes.OutOfRange, "range %d+%d outside chunk %s of %d bytes",
+				req.Offset, req.Length, req.ChunkId, len(data))
+		}
+		data = data[req.Offset : req.Offset+req.Length]
+	}
 
 	return &pb.Chunk{
 		ChunkId: req.ChunkId,
 		Data:    data,
 	}, nil
 }
+
+func (s *Server) DeleteChunk(ctx context.Context, req *pb.ChunkRequest) (*pb.Ack, error) {
+	if err := checkChunkId(req.ChunkId); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(common.ChunkDelete, req.ChunkId, req.Token, 0, 0); err != nil {
+		return nil, err
+	}
+
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.remove(store, req.ChunkId); err != nil {
+		return nil, chunkStatus(req.ChunkId, err)
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+// chunkStatus gives a chunk store error its gRPC code: NotFound for a
+// missing chunk, Unavailable when no disk can serve it.
+func chunkStatus(chunkId string, err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return status.Errorf(codes.NotFound, "chunk %s not found", chunkId)
+	case errors.Is(err, errNoVolumes):
+		return status.Errorf(codes.Unavailable, "chunk %s: %v", chunkId, err)
+	}
+	return status.Errorf(codes.Internal, "chunk %s: %v", chunkId, err)
+}
+
+// checkChunkId refuses chunk IDs other than those clients are given,
+// before one is used to name a file or checked against a token.
+func checkChunkId(chunkId string) error {
+	if !common.ValidChunkId(chunkId) {
+		return status.Errorf(codes.InvalidArgument, "invalid chunk ID %q", chunkId)
+	}
+	return nil
+}
+
+// authorize checks the chunk token of a request when the node has a
+// chunk key.
+func (s *Server) authorize(op, chunkId, token string, offset, length int64) error {
+	if s.ChunkKey == nil {
+		return nil
+	}
+	if err := common.VerifyChunkToken(s.ChunkKey, token, op, chunkId, offset, length); err != nil {
+		return status.Errorf(codes.PermissionDenied, "chunk %s: %v", chunkId, err)
+	}
+	return nil
+}
+
+// CheckChunks reports which of the requested chunks this node holds.
+func (s *Server) CheckChunks(ctx context.Context, req *pb.ChunkList) (*pb.ChunkList, error) {
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ChunkList{}
+	for _, id := range req.ChunkIds {
+		if err := checkChunkId(id); err != nil {
+			return nil, err
+		}
+		if _, err := store.Stat(id); err == nil {
+			res.ChunkIds = append(res.ChunkIds, id)
+		}
+	}
+	return res, nil
+}
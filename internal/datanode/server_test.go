@@ -0,0 +1,100 @@
+package datanode
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkID is the ID a client gives the test chunk called name.
+func chunkID(name string) string {
+	return common.ChunkId(name, 0)
+}
+
+func TestChunkTokens(t *testing.T) {
+	key := make([]byte, common.KeySize)
+	dn := &Server{Store: NewMemoryStore(), ChunkKey: key}
+	ctx := context.Background()
+	c1, c2 := chunkID("c1"), chunkID("c2")
+	sign := func(op, subject string, offset, length int64) string {
+		return common.SignChunkToken(key, op, subject, offset, length, time.Now().Add(time.Minute))
+	}
+	write := sign(common.ChunkWrite, c1, 0, 0)
+
+	if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: c1, Data: []byte("data")}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("StoreChunk without a token: got %v, want PermissionDenied", err)
+	}
+	if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: c2, Data: []byte("data"), Token: write}); err == nil {
+		t.Fatal("a token for one chunk should not store another")
+	}
+	if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: c1, Data: []byte("data"), Token: write}); err != nil {
+		t.Fatalf("StoreChunk with its token: %v", err)
+	}
+	if _, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: write}); err == nil {
+		t.Fatal("a write token should not read")
+	}
+
+	read := sign(common.ChunkRead, c1, 0, 0)
+	if got, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: read}); err != nil || string(got.Data) != "data" {
+		t.Fatalf("GetChunk with a read token: %v", err)
+	}
+	if _, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: read, Offset: 1, Length: 2}); err == nil {
+		t.Fatal("a token for the whole chunk should not read a slice")
+	}
+	slice := sign(common.ChunkRead, c1, 1, 2)
+	if got, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: slice, Offset: 1, Length: 2}); err != nil || string(got.Data) != "at" {
+		t.Fatalf("GetChunk of a slice with its token: %v, %v", got, err)
+	}
+
+	// replica pushes need a replicate token naming the target
+	if _, err := dn.ReplicateChunk(ctx, &pb.ReplicateRequest{ChunkId: c1, Target: "localhost:1", Token: read}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("ReplicateChunk with a read token: got %v, want PermissionDenied", err)
+	}
+	repl := sign(common.ChunkReplicate, common.ReplicateSubject(c1, "localhost:6002"), 0, 0)
+	if _, err := dn.ReplicateChunk(ctx, &pb.ReplicateRequest{ChunkId: c1, Target: "localhost:1", Token: repl}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("ReplicateChunk to another target: got %v, want PermissionDenied", err)
+	}
+
+	if _, err := dn.DeleteChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: read}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("DeleteChunk with a read token: got %v, want PermissionDenied", err)
+	}
+	if _, err := dn.DeleteChunk(ctx, &pb.ChunkRequest{ChunkId: c1, Token: sign(common.ChunkDelete, c1, 0, 0)}); err != nil {
+		t.Fatalf("DeleteChunk with its token: %v", err)
+	}
+}
+
+func TestChunkIdFormat(t *testing.T) {
+	dir := t.TempDir()
+	dn := &Server{DataDir: dir}
+	ctx := context.Background()
+
+	// chunk IDs name files, so only the IDs clients are given get through
+	for _, id := range []string{"", "../../../etc/x", "c1", strings.ToUpper(chunkID("c1"))} {
+		if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: id, Data: []byte("data")}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("StoreChunk %q: got %v, want InvalidArgument", id, err)
+		}
+		if _, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: id}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("GetChunk %q: got %v, want InvalidArgument", id, err)
+		}
+		if _, err := dn.DeleteChunk(ctx, &pb.ChunkRequest{ChunkId: id}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("DeleteChunk %q: got %v, want InvalidArgument", id, err)
+		}
+		if _, err := dn.ReplicateChunk(ctx, &pb.ReplicateRequest{ChunkId: id, Target: "localhost:1"}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("ReplicateChunk %q: got %v, want InvalidArgument", id, err)
+		}
+		if _, err := dn.CheckChunks(ctx, &pb.ChunkList{ChunkIds: []string{chunkID("c0"), id}}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("CheckChunks %q: got %v, want InvalidArgument", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "..", "..", "etc", "x")); err == nil {
+		t.Fatal("chunk written outside the data directory")
+	}
+}
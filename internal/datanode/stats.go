@@ -0,0 +1,26 @@
+package datanode
+
+import pb "DFS_GO/internal/proto"
+
+// Stats builds a heartbeat carrying this node's capacity and load.
+func (s *Server) Stats(nodeId string) *pb.NodeHeartbeat {
+	hb := &pb.NodeHeartbeat{
+		NodeId:          nodeId,
+		ActiveTransfers: s.active.Load(),
+		FailedVolumes:   int32(s.failedVolumes()),
+	}
+
+	if vs := s.volumes(); vs != nil {
+		hb.CapacityBytes, hb.UsedBytes = vs.usage()
+	}
+
+	// a failed disk takes its chunks with it
+	if store, err := s.store(); err == nil {
+		if int32(hb.FailedVolumes) != s.countedFails.Load() {
+			s.countChunks(store)
+		}
+		hb.ChunkCount = s.chunks.Load()
+	}
+
+	return hb
+}
@@ -0,0 +1,52 @@
+package datanode
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+)
+
+// listCounter counts the listings of the store it wraps.
+type listCounter struct {
+	ChunkStore
+	lists int
+}
+
+func (l *listCounter) List() ([]string, error) {
+	l.lists++
+	return l.ChunkStore.List()
+}
+
+func TestChunkCount(t *testing.T) {
+	mem := NewMemoryStore()
+	for _, c := range []string{"c0", "c1"} {
+		if err := mem.Put(chunkID(c), []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := &listCounter{ChunkStore: mem}
+	dn := &Server{Store: store}
+	ctx := context.Background()
+
+	if n := dn.Stats("dn1").ChunkCount; n != 2 {
+		t.Fatalf("chunks found at startup: %d, want 2", n)
+	}
+	for _, c := range []string{"c1", "c2", "c3"} {
+		if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: chunkID(c), Data: []byte(c)}); err != nil {
+			t.Fatalf("StoreChunk %s failed: %v", c, err)
+		}
+	}
+	for _, c := range []string{"c0", "missing"} {
+		if _, err := dn.DeleteChunk(ctx, &pb.ChunkRequest{ChunkId: chunkID(c)}); err != nil {
+			t.Fatalf("DeleteChunk %s failed: %v", c, err)
+		}
+	}
+
+	// a rewrite and a delete of nothing leave the count alone
+	if n := dn.Stats("dn1").ChunkCount; n != 3 {
+		t.Fatalf("chunk count: %d, want 3", n)
+	}
+	if store.lists != 1 {
+		t.Fatalf("store listed %d times, want once at startup", store.lists)
+	}
+}
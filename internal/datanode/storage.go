@@ -1,11 +1,54 @@
 package datanode
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
-func WriteChunk(path string, data []byte) error {
-	return os.WriteFile(path, data, 0644)
+// WriteChunk writes a chunk atomically: the data goes to a temp file in
+// tmpDir, is synced, and is then renamed to path, so a crash never leaves
+// a truncated chunk behind. tmpDir must be on the same filesystem as path.
+func WriteChunk(path, tmpDir string, data []byte) error {
+	dir := filepath.Dir(path)
+	for _, d := range []string{dir, tmpDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.CreateTemp(tmpDir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// make the rename itself durable
+	return syncDir(dir)
 }
 
 func Readchunk(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
+
+func DeleteChunk(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
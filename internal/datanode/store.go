@@ -0,0 +1,56 @@
+package datanode
+
+import (
+	"fmt"
+	"os"
+)
+
+// Chunk store backends selectable in datanode.yaml
+const (
+	FileBackend   = "file"
+	LogBackend    = "log"
+	MemoryBackend = "memory"
+)
+
+// ChunkStore keeps the chunks of a DataNode. Lookups of chunks a store
+// does not hold fail with an error matching os.ErrNotExist.
+type ChunkStore interface {
+	Put(chunkId string, data []byte) error
+	Get(chunkId string) ([]byte, error)
+	Delete(chunkId string) error
+	List() ([]string, error)
+	Stat(chunkId string) (ChunkInfo, error)
+}
+
+// wrapper is a store layered over another one, such as a CryptStore.
+type wrapper interface {
+	unwrap() ChunkStore
+}
+
+// ChunkInfo describes a stored chunk.
+type ChunkInfo struct {
+	Size int64
+}
+
+// OpenStore opens a chunk store of the given backend. File and log stores
+// keep one store per data directory and survive the loss of some of them;
+// the memory store ignores the directories.
+func OpenStore(backend string, dataDirs []string, volumeChoice string) (ChunkStore, error) {
+	switch backend {
+	case "", FileBackend:
+		return openVolumes(dataDirs, volumeChoice, func(dir string) (ChunkStore, error) {
+			return NewFileStore(dir)
+		}), nil
+	case LogBackend:
+		return openVolumes(dataDirs, volumeChoice, func(dir string) (ChunkStore, error) {
+			return NewLogStore(dir)
+		}), nil
+	case MemoryBackend:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown chunk store %q", backend)
+}
+
+func notFound(chunkId string) error {
+	return fmt.Errorf("chunk %s: %w", chunkId, os.ErrNotExist)
+}
@@ -0,0 +1,57 @@
+package datanode
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChunkStores(t *testing.T) {
+	for _, backend := range []string{FileBackend, LogBackend, MemoryBackend} {
+		store, err := OpenStore(backend, []string{t.TempDir()}, "")
+		if err != nil {
+			t.Fatalf("%s: OpenStore failed: %v", backend, err)
+		}
+
+		for _, c := range []string{"aaaa01", "bbbb02", "cccc03"} {
+			if err := store.Put(c, []byte("data-"+c)); err != nil {
+				t.Fatalf("%s: Put failed: %v", backend, err)
+			}
+		}
+		store.Put("aaaa01", []byte("newer"))
+		store.Delete("bbbb02")
+
+		if data, err := store.Get("aaaa01"); err != nil || string(data) != "newer" {
+			t.Fatalf("%s: Get = %q, %v", backend, data, err)
+		}
+		if info, err := store.Stat("cccc03"); err != nil || info.Size != int64(len("data-cccc03")) {
+			t.Fatalf("%s: Stat = %v, %v", backend, info, err)
+		}
+		if _, err := store.Get("bbbb02"); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s: deleted chunk: expected not found, got %v", backend, err)
+		}
+		if ids, _ := store.List(); len(ids) != 2 {
+			t.Fatalf("%s: List = %v", backend, ids)
+		}
+	}
+}
+
+func TestStoreOpenFailure(t *testing.T) {
+	dn := &Server{Backend: "tape", DataDir: t.TempDir()}
+	ctx := context.Background()
+
+	if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: chunkID("c0"), Data: []byte("data")}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("StoreChunk without a store: got %v, want Unavailable", err)
+	}
+	if _, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: chunkID("c0")}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("GetChunk without a store: got %v, want Unavailable", err)
+	}
+	if hb := dn.Stats("dn1"); hb.ChunkCount != 0 {
+		t.Fatalf("chunk count without a store: %d", hb.ChunkCount)
+	}
+}
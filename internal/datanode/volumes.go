@@ -0,0 +1,241 @@
+package datanode
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Volume choosing policies for new chunks
+const (
+	RoundRobin = "round-robin"
+	FreeSpace  = "free-space"
+)
+
+// probeFile is written and removed by volume health checks.
+const probeFile = ".probe"
+
+var errNoVolumes = errors.New("no healthy volumes")
+
+// volume is one data directory, normally a disk of its own.
+type volume struct {
+	dir   string
+	store ChunkStore
+
+	// a failed volume stays out of service until the node restarts
+	failed atomic.Bool
+}
+
+// check probes the volume by writing, syncing and removing a small file
+// where chunk writes start.
+func (v *volume) check() error {
+	dir := filepath.Join(v.dir, tmpDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, probeFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte("ok"))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(path); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// fail takes the volume out of service, logging the first time.
+func (v *volume) fail(err error) {
+	if !v.failed.Swap(true) {
+		log.Printf("Volume %s failed, its chunks are lost: %v", v.dir, err)
+	}
+}
+
+// volumes spreads chunks over the stores of several data directories and
+// keeps serving from the rest when one of them fails.
+type volumes struct {
+	vols   []*volume
+	choice string        // RoundRobin (default) or FreeSpace
+	next   atomic.Uint64 // round-robin position
+}
+
+// openVolumes opens a store in each data directory. Directories whose
+// store cannot be opened start out failed.
+func openVolumes(dirs []string, choice string, open func(dir string) (ChunkStore, error)) *volumes {
+	vs := &volumes{choice: choice}
+	for _, dir := range dirs {
+		v := &volume{dir: dir}
+		store, err := open(dir)
+		if err != nil {
+			v.fail(err)
+		}
+		v.store = store
+		vs.vols = append(vs.vols, v)
+	}
+	return vs
+}
+
+// healthy returns the volumes still in service.
+func (vs *volumes) healthy() []*volume {
+	var res []*volume
+	for _, v := range vs.vols {
+		if !v.failed.Load() {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// failedVolumes counts the volumes taken out of service.
+func (vs *volumes) failedVolumes() int {
+	return len(vs.vols) - len(vs.healthy())
+}
+
+// checkVolumes probes every healthy volume, failing those that do not
+// respond.
+func (vs *volumes) checkVolumes() {
+	for _, v := range vs.healthy() {
+		if err := v.check(); err != nil {
+			v.fail(err)
+		}
+	}
+}
+
+// usage sums the disk usage of the healthy volumes; failed disks no longer
+// count towards capacity.
+func (vs *volumes) usage() (capacity, used int64) {
+	for _, v := range vs.healthy() {
+		if c, u, err := diskUsage(v.dir); err == nil {
+			capacity += c
+			used += u
+		}
+	}
+	return capacity, used
+}
+
+// recheck probes a volume after an error from its store, failing it when
+// the probe fails too. It reports whether the volume is still in service.
+func (vs *volumes) recheck(v *volume, err error) bool {
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	if err := v.check(); err != nil {
+		v.fail(err)
+		return false
+	}
+	return true
+}
+
+// choose picks the healthy volume a new chunk is written to.
+func (vs *volumes) choose() (*volume, error) {
+	vols := vs.healthy()
+	if len(vols) == 0 {
+		return nil, errNoVolumes
+	}
+
+	if vs.choice == FreeSpace {
+		best, bestFree := vols[0], int64(-1)
+		for _, v := range vols {
+			capacity, used, err := diskUsage(v.dir)
+			if err == nil && capacity-used > bestFree {
+				best, bestFree = v, capacity-used
+			}
+		}
+		return best, nil
+	}
+
+	n := vs.next.Add(1) - 1
+	return vols[n%uint64(len(vols))], nil
+}
+
+// find returns the healthy volume holding a chunk.
+func (vs *volumes) find(chunkId string) (*volume, ChunkInfo, error) {
+	for _, v := range vs.healthy() {
+		info, err := v.store.Stat(chunkId)
+		if err == nil {
+			return v, info, nil
+		}
+		vs.recheck(v, err)
+	}
+	return nil, ChunkInfo{}, notFound(chunkId)
+}
+
+// Put writes a chunk, replacing any copy already held. Should the write
+// fail because a volume is broken, the next healthy one is tried.
+func (vs *volumes) Put(chunkId string, data []byte) error {
+	for {
+		v, _, err := vs.find(chunkId)
+		if err != nil {
+			if v, err = vs.choose(); err != nil {
+				return err
+			}
+		}
+
+		err = v.store.Put(chunkId, data)
+		if err == nil || vs.recheck(v, err) {
+			return err
+		}
+	}
+}
+
+func (vs *volumes) Get(chunkId string) ([]byte, error) {
+	v, _, err := vs.find(chunkId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := v.store.Get(chunkId)
+	if err != nil {
+		vs.recheck(v, err)
+	}
+	return data, err
+}
+
+// Delete removes a chunk from every healthy volume, carrying on past a
+// volume that fails so no other copy is left behind.
+func (vs *volumes) Delete(chunkId string) error {
+	var errs []error
+	for _, v := range vs.healthy() {
+		if err := v.store.Delete(chunkId); err != nil {
+			vs.recheck(v, err)
+			errs = append(errs, fmt.Errorf("%s: %w", v.dir, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// List returns the chunks on the healthy volumes.
+func (vs *volumes) List() ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, v := range vs.healthy() {
+		list, err := v.store.List()
+		if err != nil {
+			vs.recheck(v, err)
+			continue
+		}
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (vs *volumes) Stat(chunkId string) (ChunkInfo, error) {
+	_, info, err := vs.find(chunkId)
+	return info, err
+}
@@ -0,0 +1,95 @@
+package datanode
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataNodeVolumeFailure(t *testing.T) {
+	ctx := context.Background()
+	d1, d2 := t.TempDir(), filepath.Join(t.TempDir(), "disk2")
+	dirs := []string{d1, d2}
+
+	id, err := LoadNodeID(dirs, "dn1")
+	if err != nil || id != "dn1" {
+		t.Fatalf("LoadNodeID = %q, %v", id, err)
+	}
+
+	dn := &Server{DataDirs: dirs}
+	var all []string
+	for i := 0; i < 6; i++ {
+		all = append(all, chunkID(fmt.Sprint(i)))
+	}
+	for _, c := range all[:4] {
+		if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: c, Data: []byte(c)}); err != nil {
+			t.Fatalf("StoreChunk %s failed: %v", c, err)
+		}
+	}
+	for _, c := range []string{all[0], all[2]} {
+		if _, err := os.Stat(chunkPath(d1, c)); err != nil {
+			t.Fatalf("expected chunks spread over both volumes: %v", err)
+		}
+	}
+
+	// replace the second disk with something that cannot hold chunks
+	os.RemoveAll(d2)
+	if err := os.WriteFile(d2, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range all[4:] {
+		if _, err := dn.StoreChunk(ctx, &pb.Chunk{ChunkId: c, Data: []byte(c)}); err != nil {
+			t.Fatalf("StoreChunk %s failed with a healthy volume left: %v", c, err)
+		}
+	}
+	if hb := dn.Stats("dn1"); hb.FailedVolumes != 1 {
+		t.Fatalf("expected 1 failed volume, got %d", hb.FailedVolumes)
+	}
+
+	held, _ := dn.CheckChunks(ctx, &pb.ChunkList{ChunkIds: all})
+	if len(held.ChunkIds) != 4 {
+		t.Fatalf("expected the chunks on the healthy volume only, got %v", held.ChunkIds)
+	}
+	for _, c := range held.ChunkIds {
+		got, err := dn.GetChunk(ctx, &pb.ChunkRequest{ChunkId: c})
+		if err != nil || string(got.Data) != c {
+			t.Fatalf("GetChunk %s = %v, %v", c, got, err)
+		}
+	}
+
+	// the identity survives on the remaining disk
+	if id, err := LoadNodeID(dirs, "other"); err != nil || id != "dn1" {
+		t.Fatalf("LoadNodeID after disk failure = %q, %v", id, err)
+	}
+}
+
+// brokenStore fails to delete anything.
+type brokenStore struct{ ChunkStore }
+
+func (brokenStore) Delete(string) error { return errors.New("read-only file system") }
+
+func TestVolumesDeleteEverywhere(t *testing.T) {
+	stores := []ChunkStore{NewMemoryStore(), brokenStore{NewMemoryStore()}, NewMemoryStore()}
+	vs := &volumes{}
+	for _, store := range stores {
+		if err := store.Put("c0", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		vs.vols = append(vs.vols, &volume{dir: t.TempDir(), store: store})
+	}
+
+	if err := vs.Delete("c0"); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Fatalf("Delete with a failing volume: got %v", err)
+	}
+	for _, i := range []int{0, 2} {
+		if _, err := stores[i].Get("c0"); err == nil {
+			t.Fatalf("copy on volume %d left behind", i)
+		}
+	}
+}
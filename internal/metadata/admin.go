@@ -0,0 +1,216 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+)
+
+// Decommission drains every replica off a node. The node keeps serving
+// reads while its chunks are re-replicated elsewhere, and is marked
+// decommissioned, and safe to remove, once none of them depend on it.
+func (s *Server) Decommission(ctx context.Context, req *pb.NodeRequest) (*pb.Ack, error) {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	node, ok := s.State.Nodes[req.NodeId]
+	if !ok {
+		return nil, notFound("node", req.NodeId)
+	}
+	if node.draining() {
+		return &pb.Ack{Ok: true}, nil
+	}
+
+	if err := s.setNodeState(req.NodeId, AdminDecommissioning, time.Time{}); err != nil {
+		return nil, err
+	}
+
+	// start draining now rather than at the next heal pass
+	s.reconcileNode(req.NodeId)
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// Recommission returns a decommissioning or decommissioned node to service.
+func (s *Server) Recommission(ctx context.Context, req *pb.NodeRequest) (*pb.Ack, error) {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if _, ok := s.State.Nodes[req.NodeId]; !ok {
+		return nil, notFound("node", req.NodeId)
+	}
+
+	if err := s.setNodeState(req.NodeId, AdminInService, time.Time{}); err != nil {
+		return nil, err
+	}
+
+	// its replicas count again, which leaves chunks copied or trimmed
+	// while it drained with the wrong number of replicas
+	s.reconcileNode(req.NodeId)
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// EnterMaintenance suppresses re-replication of a node's chunks for a
+// bounded window, e.g. while it reboots. A zero duration ends maintenance.
+func (s *Server) EnterMaintenance(ctx context.Context, req *pb.MaintenanceRequest) (*pb.Ack, error) {
+	if req.DurationSeconds < 0 {
+		return nil, invalidArgument("duration_seconds", "invalid maintenance duration %d", req.DurationSeconds)
+	}
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	node, ok := s.State.Nodes[req.NodeId]
+	if !ok {
+		return nil, notFound("node", req.NodeId)
+	}
+	if node.draining() {
+		return nil, failedPrecondition("ADMIN_STATE", req.NodeId, "node %s is %s", req.NodeId, node.AdminState)
+	}
+
+	state, until := AdminMaintenance, time.Now().Add(time.Duration(req.DurationSeconds)*time.Second)
+	if req.DurationSeconds == 0 {
+		state, until = AdminInService, time.Time{}
+	}
+
+	if err := s.setNodeState(req.NodeId, state, until); err != nil {
+		return nil, err
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+func (s *Server) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.NodeList, error) {
+	s.State.Mu.RLock()
+	defer s.State.Mu.RUnlock()
+
+	replicas := make(map[string]int64)
+	for _, chunks := range s.State.Files {
+		for _, c := range chunks {
+			for _, id := range c.Nodes {
+				replicas[id]++
+			}
+		}
+	}
+
+	now := time.Now()
+	res := &pb.NodeList{}
+	for id, n := range s.State.Nodes {
+		r := &pb.NodeReport{
+			NodeId:        id,
+			Address:       n.Address,
+			Rack:          n.Rack,
+			Zone:          n.Zone,
+			AdminState:    n.adminState(now),
+			Liveness:      n.Liveness,
+			CapacityBytes: n.Capacity,
+			UsedBytes:     n.Used,
+			Replicas:      replicas[id],
+			FailedVolumes: int32(n.FailedVolumes),
+		}
+		if r.Liveness == NodeLive {
+			r.Liveness = "live"
+		}
+		if n.inMaintenance(now) {
+			r.MaintenanceUntil = n.MaintenanceUntil.Unix()
+		}
+		if !n.Lastseen.IsZero() {
+			r.LastSeen = n.Lastseen.Unix()
+		}
+		res.Nodes = append(res.Nodes, r)
+	}
+
+	sort.Slice(res.Nodes, func(i, j int) bool { return res.Nodes[i].NodeId < res.Nodes[j].NodeId })
+
+	return res, nil
+}
+
+// setNodeState journals and applies an admin state change.
+// Caller must hold the state write lock.
+func (s *Server) setNodeState(nodeId, state string, until time.Time) error {
+	payload, err := json.Marshal(struct {
+		NodeID string    `json:"node_id"`
+		State  string    `json:"state"`
+		Until  time.Time `json:"until"`
+	}{
+		NodeID: nodeId,
+		State:  state,
+		Until:  until,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "SET_NODE_STATE",
+		Data: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.applyNodeState(nodeId, state, until)
+	return nil
+}
+
+// applyNodeState sets a node's admin state. A decommissioned node's
+// replicas are dropped from chunk metadata since nothing relies on them.
+// Caller must hold the state write lock.
+func (s *Server) applyNodeState(nodeId, state string, until time.Time) {
+	node, ok := s.State.Nodes[nodeId]
+	if !ok {
+		return
+	}
+
+	node.AdminState = state
+	node.MaintenanceUntil = until
+	s.State.Nodes[nodeId] = node
+
+	if state != AdminDecommissioned {
+		return
+	}
+	for _, chunks := range s.State.Files {
+		for idx, c := range chunks {
+			if containsNode(c.Nodes, nodeId) {
+				c.Nodes = removeNode(c.Nodes, nodeId)
+				chunks[idx] = c
+			}
+		}
+	}
+}
+
+// checkDecommissions marks draining nodes as decommissioned once every chunk
+// they hold is fully replicated on other nodes.
+func (s *Server) checkDecommissions() {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	for id, node := range s.State.Nodes {
+		if node.AdminState != AdminDecommissioning || !s.drained(id) {
+			continue
+		}
+
+		if err := s.setNodeState(id, AdminDecommissioned, time.Time{}); err != nil {
+			log.Printf("Failed to complete decommission of %s: %v", id, err)
+			continue
+		}
+		log.Printf("Node %s decommissioned and safe to remove", id)
+	}
+}
+
+// drained reports whether no chunk still depends on a replica on node id.
+// Caller must hold the state lock.
+func (s *Server) drained(id string) bool {
+	for fname, chunks := range s.State.Files {
+		rf := s.replicationFor(fname)
+		for _, c := range chunks {
+			if containsNode(c.Nodes, id) && s.usableReplicas(c.Nodes) < rf {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDecommission(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	for i, addr := range []string{"a", "b", "c"} {
+		s.State.Nodes[fmt.Sprintf("dn%d", i+1)] = NodeStatus{Address: addr}
+	}
+	s.State.Files["f"] = map[int]ChunkMetadata{0: {ChunkId: "c0", Nodes: []string{"dn1", "dn2"}}}
+
+	if _, err := s.Decommission(context.Background(), &pb.NodeRequest{NodeId: "dn1"}); err != nil {
+		t.Fatalf("Decommission failed: %v", err)
+	}
+	if s.queue().Len() != 1 {
+		t.Fatalf("expected chunk on draining node to be queued, got %d", s.queue().Len())
+	}
+	if _, ok := s.placementCandidates()["dn1"]; ok {
+		t.Fatalf("decommissioning node must not receive replicas")
+	}
+
+	s.checkDecommissions()
+	if st := s.State.Nodes["dn1"].AdminState; st != AdminDecommissioning {
+		t.Fatalf("node decommissioned before its chunks were re-replicated: %q", st)
+	}
+
+	// re-replication lands on c
+	s.State.Files["f"][0] = ChunkMetadata{ChunkId: "c0", Nodes: []string{"dn1", "dn2", "dn3"}}
+	if v := s.pickTrimVictim([]string{"dn1", "dn2", "dn3"}, s.nodeUsage()); v != "dn1" {
+		t.Fatalf("expected draining replica to be trimmed first, got %s", v)
+	}
+
+	s.checkDecommissions()
+	if st := s.State.Nodes["dn1"].AdminState; st != AdminDecommissioned {
+		t.Fatalf("expected decommissioned, got %q", st)
+	}
+	if nodes := s.State.Files["f"][0].Nodes; containsNode(nodes, "dn1") {
+		t.Fatalf("decommissioned replica still listed: %v", nodes)
+	}
+
+	s2 := NewServerWithOptions(s.Opts)
+	s2.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s2.ReplayWAL(s.Opts.WALPath)
+
+	if st := s2.State.Nodes["dn1"].AdminState; st != AdminDecommissioned {
+		t.Fatalf("expected decommissioned after replay, got %q", st)
+	}
+
+	// back in service, the node's replicas count again and its files are
+	// reconciled
+	s.State.Files["g"] = map[int]ChunkMetadata{0: {ChunkId: "g0", Nodes: []string{"dn1", "dn2", "dn3"}}}
+	queued := s.queue().Len()
+	if _, err := s.Recommission(context.Background(), &pb.NodeRequest{NodeId: "dn1"}); err != nil {
+		t.Fatalf("Recommission failed: %v", err)
+	}
+	if st := s.State.Nodes["dn1"].AdminState; st != AdminInService {
+		t.Fatalf("expected in service, got %q", st)
+	}
+	if s.queue().Len() != queued+1 {
+		t.Fatalf("expected the recommissioned node's file to be queued, got %d tasks", s.queue().Len()-queued)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	s := newTestServer(t, Options{})
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b"}
+
+	_, err := s.EnterMaintenance(context.Background(), &pb.MaintenanceRequest{NodeId: "dn1", DurationSeconds: 60})
+	if err != nil {
+		t.Fatalf("EnterMaintenance failed: %v", err)
+	}
+
+	if _, ok := s.placementCandidates()["dn1"]; ok {
+		t.Fatalf("node in maintenance must not receive replicas")
+	}
+	if n := s.usableReplicas([]string{"dn1", "dn2"}); n != 2 {
+		t.Fatalf("replicas on a node in maintenance should still count, got %d", n)
+	}
+
+	list, err := s.ListNodes(context.Background(), &pb.ListNodesRequest{})
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if st := list.Nodes[0].AdminState; st != AdminMaintenance || list.Nodes[0].MaintenanceUntil == 0 {
+		t.Fatalf("unexpected report for dn1: %v", list.Nodes[0])
+	}
+	if st := list.Nodes[1].AdminState; st != AdminInService {
+		t.Fatalf("expected dn2 in service, got %q", st)
+	}
+
+	// the window expires on its own
+	expired := s.State.Nodes["dn1"]
+	if expired.inMaintenance(time.Now().Add(2 * time.Minute)) {
+		t.Fatalf("maintenance should expire after its window")
+	}
+
+	if _, err := s.EnterMaintenance(context.Background(), &pb.MaintenanceRequest{NodeId: "dn1"}); err != nil {
+		t.Fatalf("ending maintenance failed: %v", err)
+	}
+	if _, ok := s.placementCandidates()["dn1"]; !ok {
+		t.Fatalf("node should be back in service")
+	}
+}
@@ -0,0 +1,218 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
+	"bufio"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nodeMethods are the RPCs only DataNodes may call.
+var nodeMethods = map[string]bool{
+	pb.MetadataService_RegisterNode_FullMethodName: true,
+	pb.MetadataService_Heartbeat_FullMethodName:    true,
+	pb.MetadataService_BlockReport_FullMethodName:  true,
+}
+
+// adminMethods are the RPCs only administrators may call.
+var adminMethods = map[string]bool{
+	pb.MetadataService_Balance_FullMethodName:          true,
+	pb.MetadataService_Decommission_FullMethodName:     true,
+	pb.MetadataService_Recommission_FullMethodName:     true,
+	pb.MetadataService_EnterMaintenance_FullMethodName: true,
+	pb.MetadataService_SetQuota_FullMethodName:         true,
+}
+
+// Auth says who the clients of the metadata server are.
+type Auth struct {
+	Tokens map[string]string   // bearer token to user name
+	Groups map[string][]string // groups of the users with tokens
+	Admins map[string]bool
+}
+
+// Identity is an authenticated caller.
+type Identity struct {
+	User   string
+	Groups []string
+}
+
+func (id Identity) inGroup(group string) bool {
+	for _, g := range id.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAuth reads a tokens file of "<user> <token> [group,...]" lines,
+// where blank lines and lines starting with # are skipped. Without a
+// tokens file clients can still authenticate with TLS client
+// certificates, whose organizational units are their groups.
+func LoadAuth(tokensFile string, admins []string) (*Auth, error) {
+	a := &Auth{
+		Tokens: make(map[string]string),
+		Groups: make(map[string][]string),
+		Admins: make(map[string]bool),
+	}
+	for _, user := range admins {
+		a.Admins[user] = true
+	}
+	if tokensFile == "" {
+		return a, nil
+	}
+
+	f, err := os.Open(tokensFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"<user> <token> [group,...]\"", tokensFile, n)
+		}
+		if _, dup := a.Tokens[fields[1]]; dup {
+			return nil, fmt.Errorf("%s:%d: token given twice", tokensFile, n)
+		}
+		a.Tokens[fields[1]] = fields[0]
+		if len(fields) == 3 {
+			a.Groups[fields[0]] = strings.Split(fields[2], ",")
+		}
+	}
+	return a, sc.Err()
+}
+
+// identify finds the caller by its bearer token or, failing that, by
+// the common name of its client certificate. The user is empty for an
+// anonymous caller.
+func (a *Auth) identify(ctx context.Context) (Identity, error) {
+	if token := transport.BearerToken(ctx); token != "" {
+		user, ok := a.Tokens[token]
+		if !ok {
+			return Identity{}, status.Error(codes.Unauthenticated, "unknown token")
+		}
+		return Identity{User: user, Groups: a.Groups[user]}, nil
+	}
+	if cert := transport.VerifiedPeer(ctx); cert != nil {
+		return Identity{User: cert.Subject.CommonName, Groups: cert.Subject.OrganizationalUnit}, nil
+	}
+	return Identity{}, nil
+}
+
+// isNodeCert reports whether a verified certificate belongs to a DataNode.
+// DataNodes serve gRPC themselves, so their certificates are good for both
+// server and client authentication, unlike those handed to users.
+func isNodeCert(cert *x509.Certificate) bool {
+	server, client := false, false
+	for _, u := range cert.ExtKeyUsage {
+		switch u {
+		case x509.ExtKeyUsageServerAuth:
+			server = true
+		case x509.ExtKeyUsageClientAuth:
+			client = true
+		case x509.ExtKeyUsageAny:
+			server, client = true, true
+		}
+	}
+	return server && client
+}
+
+// certNames reports whether a node certificate was issued to nodeId, as
+// its common name or one of its DNS names.
+func certNames(cert *x509.Certificate, nodeId string) bool {
+	return nodeId != "" && (cert.Subject.CommonName == nodeId || slices.Contains(cert.DNSNames, nodeId))
+}
+
+type callerKey struct{}
+
+// Caller returns who an RPC was authenticated as, or an empty Identity
+// when the server runs without authentication.
+func Caller(ctx context.Context) Identity {
+	id, _ := ctx.Value(callerKey{}).(Identity)
+	return id
+}
+
+// Authenticate is a unary interceptor. When the server runs TLS, only
+// callers with a DataNode certificate signed by the cluster CA may
+// register nodes, heartbeat and send block reports, and only for the node
+// the certificate names. With Auth set, every other RPC needs a known
+// user, and the administrative ones an admin; node RPCs then need TLS.
+func (s *Server) Authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if nodeMethods[info.FullMethod] {
+		if s.TLS == nil {
+			// without TLS nothing proves a caller is a DataNode
+			if s.Auth != nil {
+				return nil, status.Error(codes.Unauthenticated, "node RPCs need TLS when clients are authenticated")
+			}
+			return handler(ctx, req)
+		}
+		cert := transport.VerifiedPeer(ctx)
+		if cert == nil || !isNodeCert(cert) {
+			log.Printf("Rejected %s from a caller without a node certificate", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "a DataNode certificate is required")
+		}
+		if node, ok := req.(interface{ GetNodeId() string }); !ok || !certNames(cert, node.GetNodeId()) {
+			log.Printf("Rejected %s from node certificate %s for another node", info.FullMethod, cert.Subject.CommonName)
+			return nil, status.Errorf(codes.PermissionDenied, "certificate %s does not name this node", cert.Subject.CommonName)
+		}
+		return handler(ctx, req)
+	}
+	if s.Auth == nil {
+		return handler(ctx, req)
+	}
+
+	id, err := s.Auth.identify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id.User == "" {
+		return nil, status.Error(codes.Unauthenticated, "a token or client certificate is required")
+	}
+	if adminMethods[info.FullMethod] && !s.Auth.Admins[id.User] {
+		log.Printf("Denied %s to %s", info.FullMethod, id.User)
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not an administrator", id.User)
+	}
+	return handler(context.WithValue(ctx, callerKey{}, id), req)
+}
+
+// chunkToken grants op on a chunk, or a slice of it, to the caller of an
+// RPC. It returns "" when the cluster runs without chunk tokens.
+func (s *Server) chunkToken(op, chunkId string, offset, length int64) string {
+	if s.ChunkKey == nil {
+		return ""
+	}
+	return common.SignChunkToken(s.ChunkKey, op, chunkId, offset, length, time.Now().Add(s.opts().ChunkTokenTTL))
+}
+
+// chunkInUse reports whether a chunk ID is taken, so that a client cannot
+// obtain a write token for another file's chunk by allocating its ID.
+// Caller must hold the state lock.
+func (s *Server) chunkInUse(chunkId string) bool {
+	for _, chunks := range s.State.Files {
+		for _, c := range chunks {
+			if c.ChunkId == chunkId {
+				return true
+			}
+		}
+	}
+	return false
+}
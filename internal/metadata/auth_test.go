@@ -0,0 +1,266 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testCA issues certificates for TestNodeTLS.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{key: key, dir: t.TempDir()}
+	if ca.cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(ca.dir, "ca.crt"), "CERTIFICATE", der)
+	return ca
+}
+
+// writePEM writes der to path as a single PEM block.
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// issue writes name.crt and name.key and returns their TLS config.
+func (ca *testCA) issue(t *testing.T, name, host string, usage ...x509.ExtKeyUsage) common.TLSConfig {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := common.TLSConfig{
+		CertFile: filepath.Join(ca.dir, name+".crt"),
+		KeyFile:  filepath.Join(ca.dir, name+".key"),
+		CAFile:   filepath.Join(ca.dir, "ca.crt"),
+	}
+	writePEM(t, cfg.CertFile, "CERTIFICATE", der)
+	writePEM(t, cfg.KeyFile, "EC PRIVATE KEY", keyDer)
+	return cfg
+}
+
+func TestNodeTLS(t *testing.T) {
+	ca := newTestCA(t)
+	both := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	// the server certificate names the wrong host until it is renewed
+	serverCfg := ca.issue(t, "metadata", "elsewhere.example", both...)
+	nodeCfg := ca.issue(t, "dn1", "localhost", both...)
+	otherCfg := ca.issue(t, "dn2", "localhost", both...)
+	sanCfg := ca.issue(t, "host7", "dn3", both...)
+	userCfg := ca.issue(t, "alice", "localhost", x509.ExtKeyUsageClientAuth)
+
+	s := newTestServer(t, Options{})
+	var err error
+	if s.TLS, err = transport.ServerTLS(serverCfg); err != nil {
+		t.Fatalf("ServerTLS failed: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(s.TLS.ServerOption(), grpc.UnaryInterceptor(s.Authenticate))
+	pb.RegisterMetadataServiceServer(gs, s)
+	go gs.Serve(lis)
+	defer gs.Stop()
+	addr := fmt.Sprintf("localhost:%d", lis.Addr().(*net.TCPAddr).Port)
+
+	register := func(cfg common.TLSConfig, nodeId string) error {
+		var tc *transport.TLS
+		if cfg != (common.TLSConfig{}) {
+			if tc, err = transport.ClientTLS(cfg); err != nil {
+				t.Fatalf("ClientTLS failed: %v", err)
+			}
+		}
+		conn, err := transport.Dial(addr, tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, err = pb.NewMetadataServiceClient(conn).RegisterNode(ctx, &pb.NodeInfo{NodeId: nodeId, Address: "localhost:6001"})
+		return err
+	}
+
+	if err := register(nodeCfg, "dn1"); err == nil {
+		t.Fatal("a server certificate for another host should be refused")
+	}
+
+	// renew the server certificate in place
+	time.Sleep(1100 * time.Millisecond)
+	ca.issue(t, "metadata", "localhost", both...)
+
+	if err := register(nodeCfg, "dn1"); err != nil {
+		t.Fatalf("RegisterNode with a node certificate after renewal: %v", err)
+	}
+	// a node certificate speaks for its own node only
+	if err := register(otherCfg, "dn1"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("RegisterNode with another node's certificate: got %v, want PermissionDenied", err)
+	}
+	if err := register(sanCfg, "dn3"); err != nil {
+		t.Fatalf("RegisterNode with the node ID as a DNS name: %v", err)
+	}
+	if err := register(userCfg, "dn1"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("RegisterNode with a user certificate: got %v, want Unauthenticated", err)
+	}
+	if err := register(common.TLSConfig{CAFile: nodeCfg.CAFile}, "dn1"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("RegisterNode without a certificate: got %v, want Unauthenticated", err)
+	}
+	if err := register(common.TLSConfig{}, "dn1"); err == nil {
+		t.Fatal("a plaintext connection should be refused")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := newTestServer(t, Options{})
+	s.Auth = &Auth{
+		Tokens: map[string]string{"alice-token": "alice", "root-token": "root"},
+		Admins: map[string]bool{"root": true},
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(grpc.UnaryInterceptor(s.Authenticate))
+	pb.RegisterMetadataServiceServer(gs, s)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	as := func(token string) pb.MetadataServiceClient {
+		conn, err := transport.Dial(lis.Addr().String(), nil, transport.WithToken(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewMetadataServiceClient(conn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := as("").CreateFile(ctx, &pb.FileRequest{Filename: "/a"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous CreateFile: got %v, want Unauthenticated", err)
+	}
+	if _, err := as("forged").CreateFile(ctx, &pb.FileRequest{Filename: "/a"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("CreateFile with an unknown token: got %v, want Unauthenticated", err)
+	}
+	if _, err := as("alice-token").CreateFile(ctx, &pb.FileRequest{Filename: "/a"}); err != nil {
+		t.Fatalf("CreateFile as alice: %v", err)
+	}
+	if _, err := as("alice-token").Balance(ctx, &pb.BalanceRequest{DryRun: true}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Balance as alice: got %v, want PermissionDenied", err)
+	}
+	if _, err := as("root-token").Balance(ctx, &pb.BalanceRequest{DryRun: true}); err != nil {
+		t.Fatalf("Balance as root: %v", err)
+	}
+
+	// without TLS any client could pass itself off as a DataNode
+	if _, err := as("root-token").RegisterNode(ctx, &pb.NodeInfo{NodeId: "dn1", Address: "localhost:6001"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("RegisterNode without TLS: got %v, want Unauthenticated", err)
+	}
+}
+
+func TestChunkTokens(t *testing.T) {
+	key := make([]byte, common.KeySize)
+	s := newTestServer(t, Options{})
+	s.ChunkKey = key
+	ctx := context.Background()
+	c1 := chunkID("c1")
+
+	mustRegister(t, s, "dn1", "localhost:6001")
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "/a"})
+	alloc, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: c1, Filename: "/a", Size: 4})
+	if err != nil || alloc.Token == "" {
+		t.Fatalf("AllocateChunk: token %q, %v", alloc.GetToken(), err)
+	}
+	if err := common.VerifyChunkToken(key, alloc.Token, common.ChunkWrite, c1, 0, 0); err != nil {
+		t.Fatalf("AllocateChunk token does not grant writing its chunk: %v", err)
+	}
+	if err := common.VerifyChunkToken(key, alloc.Token, common.ChunkRead, c1, 0, 0); err == nil {
+		t.Fatal("AllocateChunk token should not grant reading")
+	}
+
+	file, err := s.GetFile(ctx, &pb.FileRequest{Filename: "/a"})
+	if err != nil {
+		t.Fatalf("GetFile: %v", err)
+	}
+	c := file.Chunks[0]
+	if err := common.VerifyChunkToken(key, c.Token, common.ChunkRead, c1, 0, 0); err != nil {
+		t.Fatalf("GetFile token does not grant reading its chunk: %v", err)
+	}
+	if err := common.VerifyChunkToken(key, c.Token, common.ChunkWrite, c1, 0, 0); err == nil {
+		t.Fatal("GetFile token should not grant writing")
+	}
+
+	// another file cannot take over the chunk to get a write token for it
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "/b"})
+	if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: c1, Filename: "/b", Size: 4}); err == nil {
+		t.Fatal("allocating a chunk ID in use should fail")
+	}
+
+	expired := common.SignChunkToken(key, common.ChunkRead, c1, 0, 0, time.Now().Add(-time.Minute))
+	if err := common.VerifyChunkToken(key, expired, common.ChunkRead, c1, 0, 0); !errors.Is(err, common.ErrChunkToken) {
+		t.Fatalf("expired token: got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// replicaMove relocates one replica of a chunk from one node to another.
+type replicaMove struct {
+	Filename   string
+	ChunkIndex int
+	Meta       ChunkMetadata
+	From       string
+	To         string
+}
+
+func (m replicaMove) key() string {
+	return replTask{Filename: m.Filename, ChunkIndex: m.ChunkIndex}.key()
+}
+
+// Balance plans replica moves and, unless it is a dry run, starts carrying
+// them out in the background. The report lists the planned moves; only one
+// run moves replicas at a time.
+func (s *Server) Balance(ctx context.Context, req *pb.BalanceRequest) (*pb.BalanceReport, error) {
+	threshold := req.ThresholdPercent / 100
+	if threshold <= 0 {
+		threshold = s.opts().BalanceThreshold
+	}
+	maxBytes := req.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = s.opts().BalanceMaxBytes
+	}
+
+	if !req.DryRun && !s.balancing.CompareAndSwap(false, true) {
+		return nil, failedPrecondition("BALANCING", "cluster", "a balancing run is already in progress")
+	}
+
+	s.State.Mu.RLock()
+	plan := s.planBalance(threshold, maxBytes)
+	s.State.Mu.RUnlock()
+
+	if !req.DryRun {
+		go func() {
+			defer s.balancing.Store(false)
+			done := s.executeBalance(plan)
+			if len(done) > 0 {
+				log.Printf("Balancer moved %d of %d replicas", len(done), len(plan))
+			}
+		}()
+	}
+
+	report := &pb.BalanceReport{}
+	for _, m := range plan {
+		report.Moves = append(report.Moves, &pb.ReplicaMove{
+			ChunkId: m.Meta.ChunkId,
+			From:    m.From,
+			To:      m.To,
+			Size:    m.Meta.stored(),
+		})
+		report.Bytes += m.Meta.stored()
+	}
+	return report, nil
+}
+
+// StartBalancerLoop periodically evens out disk usage across DataNodes.
+// It is a no-op when no balancer interval is configured.
+func (s *Server) StartBalancerLoop() {
+	opts := s.opts()
+	if opts.BalanceInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(opts.BalanceInterval)
+
+			// a run still in progress is left to finish
+			report, err := s.Balance(context.Background(), &pb.BalanceRequest{})
+			if err == nil && len(report.Moves) > 0 {
+				log.Printf("Balancer moving %d replicas (%d bytes)", len(report.Moves), report.Bytes)
+			}
+		}
+	}()
+}
+
+// planBalance picks replica moves from nodes whose disk utilisation is more
+// than threshold above the cluster average to nodes below the average, until
+// every node is within threshold or maxBytes would be exceeded.
+// Only live, in-service nodes that report their capacity take part.
+// Caller must hold the state lock.
+func (s *Server) planBalance(threshold float64, maxBytes int64) []replicaMove {
+	nodes := make(map[string]NodeStatus)
+	used := make(map[string]int64)
+	var totalUsed, totalCap int64
+	now := time.Now()
+	for id, n := range s.State.Nodes {
+		if n.Capacity > 0 && n.Liveness == NodeLive && !n.draining() && !n.inMaintenance(now) {
+			nodes[id] = n
+			used[id] = n.Used
+			totalUsed += n.Used
+			totalCap += n.Capacity
+		}
+	}
+	if len(nodes) < 2 {
+		return nil
+	}
+
+	mean := float64(totalUsed) / float64(totalCap)
+	util := func(id string) float64 {
+		return float64(used[id]) / float64(nodes[id].Capacity)
+	}
+
+	// replicas each node holds, skipping chunks already being worked on
+	held := make(map[string][]replicaMove)
+	for fname, chunks := range s.State.Files {
+		for idx, meta := range chunks {
+			m := replicaMove{Filename: fname, ChunkIndex: idx, Meta: meta}
+			if meta.Size <= 0 || s.State.Replicating[m.key()] {
+				continue
+			}
+			for _, id := range meta.Nodes {
+				if _, ok := nodes[id]; ok {
+					m.From = id
+					held[id] = append(held[id], m)
+				}
+			}
+		}
+	}
+
+	var plan []replicaMove
+	var planned int64
+	moved := make(map[string]bool)
+	exhausted := make(map[string]bool)
+
+	for {
+		// most over-utilised node that still has something to give
+		src := ""
+		for id := range nodes {
+			if !exhausted[id] && (src == "" || util(id) > util(src)) {
+				src = id
+			}
+		}
+		if src == "" || util(src) <= mean+threshold {
+			return plan
+		}
+
+		m, ok := s.pickMove(held[src], nodes, used, mean, moved)
+		if !ok {
+			exhausted[src] = true
+			continue
+		}
+		if planned+m.Meta.stored() > maxBytes {
+			return plan
+		}
+
+		plan = append(plan, m)
+		planned += m.Meta.stored()
+		moved[m.key()] = true
+		used[m.From] -= m.Meta.stored()
+		used[m.To] += m.Meta.stored()
+	}
+}
+
+// pickMove finds a replica in candidates that can go to a node below the
+// mean without reducing the chunk's rack and zone spread.
+// Caller must hold the state lock.
+func (s *Server) pickMove(
+	candidates []replicaMove,
+	nodes map[string]NodeStatus,
+	used map[string]int64,
+	mean float64,
+	moved map[string]bool,
+) (replicaMove, bool) {
+	policy := s.opts().Placement
+
+	for _, m := range candidates {
+		if moved[m.key()] {
+			continue
+		}
+
+		holders := make(map[string]bool)
+		var others []NodeStatus
+		for _, id := range m.Meta.Nodes {
+			holders[id] = true
+			if id == m.From {
+				continue
+			}
+			if n, ok := s.State.Nodes[id]; ok {
+				others = append(others, n)
+			}
+		}
+		current := placementScore(policy, others, nodes[m.From])
+
+		best, bestUtil := "", 0.0
+		for id, n := range nodes {
+			if holders[id] || placementScore(policy, others, n) > current {
+				continue
+			}
+			after := float64(used[id]+m.Meta.stored()) / float64(n.Capacity)
+			if after > mean {
+				continue
+			}
+			if best == "" || after < bestUtil {
+				best, bestUtil = id, after
+			}
+		}
+
+		if best != "" {
+			m.To = best
+			return m, true
+		}
+	}
+	return replicaMove{}, false
+}
+
+// executeBalance performs planned moves and returns the ones that succeeded.
+func (s *Server) executeBalance(plan []replicaMove) []replicaMove {
+	s.initReplication()
+
+	var done []replicaMove
+	for _, m := range plan {
+		if s.moveReplica(m) {
+			done = append(done, m)
+		}
+	}
+	return done
+}
+
+// moveReplica copies a replica to its new node, repoints metadata, then
+// deletes the old copy.
+func (s *Server) moveReplica(m replicaMove) bool {
+	// claim the chunk so the replication queue leaves it alone
+	s.State.Mu.Lock()
+	if s.State.Replicating[m.key()] {
+		s.State.Mu.Unlock()
+		return false
+	}
+	s.State.Replicating[m.key()] = true
+	fromAddr, toAddr := s.addressOf(m.From), s.addressOf(m.To)
+	s.State.Mu.Unlock()
+
+	defer func() {
+		s.State.Mu.Lock()
+		delete(s.State.Replicating, m.key())
+		s.State.Mu.Unlock()
+	}()
+
+	s.throttle.wait(m.Meta.stored(), m.From, m.To)
+	if err := s.copyChunk(fromAddr, toAddr, m.Meta.ChunkId); err != nil {
+		log.Printf("Balancer failed to copy %s from %s to %s: %v", m.Meta.ChunkId, m.From, m.To, err)
+		return false
+	}
+
+	s.State.Mu.Lock()
+
+	// re-validate the chunk and both replicas
+	chunk, ok := s.State.Files[m.Filename][m.ChunkIndex]
+	if !ok || chunk.ChunkId != m.Meta.ChunkId ||
+		!containsNode(chunk.Nodes, m.From) || containsNode(chunk.Nodes, m.To) {
+		stray := !ok || !containsNode(chunk.Nodes, m.To)
+		s.State.Mu.Unlock()
+
+		// the copy is not referenced by metadata; reclaim it
+		if stray {
+			s.deleteChunk(toAddr, m.Meta.ChunkId)
+		}
+		return false
+	}
+
+	payload, err := json.Marshal(struct {
+		Filename   string
+		ChunkIndex int
+		From       string
+		To         string
+	}{
+		Filename:   m.Filename,
+		ChunkIndex: m.ChunkIndex,
+		From:       m.From,
+		To:         m.To,
+	})
+	if err == nil {
+		err = s.WAL.Append(WALEntry{
+			Type: "MOVE_REPLICA",
+			Data: payload,
+		})
+	}
+	if err != nil {
+		s.State.Mu.Unlock()
+		return false
+	}
+
+	chunk.Nodes = replaceNode(chunk.Nodes, m.From, m.To)
+	s.State.Files[m.Filename][m.ChunkIndex] = chunk
+	s.State.Mu.Unlock()
+
+	if err := s.deleteChunk(fromAddr, m.Meta.ChunkId); err != nil {
+		log.Printf("Balancer failed to delete %s on %s: %v", m.Meta.ChunkId, m.From, err)
+	}
+	return true
+}
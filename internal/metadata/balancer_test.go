@@ -0,0 +1,78 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlanBalance(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	const gb = 1 << 30
+	s.State.Nodes["dn1"] = NodeStatus{Address: "full", Rack: "r1", Capacity: 100 * gb, Used: 90 * gb}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "half", Rack: "r2", Capacity: 100 * gb, Used: 50 * gb}
+	s.State.Nodes["dn3"] = NodeStatus{Address: "new", Rack: "r3", Capacity: 100 * gb, Used: 10 * gb}
+
+	chunks := make(map[int]ChunkMetadata)
+	for i := 0; i < 50; i++ {
+		chunks[i] = ChunkMetadata{ChunkId: fmt.Sprintf("c%d", i), Nodes: []string{"dn1"}, Size: gb}
+	}
+	s.State.Files["f"] = chunks
+
+	plan := s.planBalance(0.1, 100*gb)
+
+	var moved int64
+	for _, m := range plan {
+		if m.From != "dn1" || m.To == "dn1" {
+			t.Fatalf("unexpected move %s -> %s", m.From, m.To)
+		}
+		moved += m.Meta.Size
+	}
+
+	// mean is 50%: "full" must drop to within 10 points of it
+	if remaining := 90*gb - moved; remaining > 60*gb {
+		t.Fatalf("plan leaves full node at %d GB", remaining/gb)
+	}
+
+	if plan := s.planBalance(0.1, 5*gb); len(plan) != 5 {
+		t.Fatalf("byte budget not respected: %d moves", len(plan))
+	}
+}
+
+func TestBalanceInBackground(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	const gb = 1 << 30
+	// nothing listens on these nodes, so every move fails
+	s.State.Nodes["dn1"] = NodeStatus{Address: "127.0.0.1:1", Rack: "r1", Capacity: 100 * gb, Used: 90 * gb}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "127.0.0.1:2", Rack: "r2", Capacity: 100 * gb, Used: 10 * gb}
+	s.State.Files["f"] = map[int]ChunkMetadata{0: {ChunkId: "c0", Nodes: []string{"dn1"}, Size: gb}}
+
+	report, err := s.Balance(ctx, &pb.BalanceRequest{})
+	if err != nil || len(report.Moves) != 1 {
+		t.Fatalf("Balance = %v, %v; want the planned move", report, err)
+	}
+	if _, err := s.Balance(ctx, &pb.BalanceRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Balance during a run: got %v, want FailedPrecondition", err)
+	}
+	if _, err := s.Balance(ctx, &pb.BalanceRequest{DryRun: true}); err != nil {
+		t.Fatalf("dry run during a run: %v", err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); s.balancing.Load(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("balancing run never finished")
+		}
+	}
+	if nodes := s.State.Files["f"][0].Nodes; !slices.Equal(nodes, []string{"dn1"}) {
+		t.Fatalf("failed move changed the replicas to %v", nodes)
+	}
+}
@@ -1,23 +1,131 @@
 package metadata
 
-import "time"
-
-const nodeTTL = 10 * time.Second
+import (
+	"log"
+	"time"
+)
 
+// StartCleanupLoop tracks node liveness from heartbeats. Nodes are never
+// forgotten, so a node that comes back can be reinstated.
 func (s *Server) StartCleanupLoop() {
+	opts := s.opts()
+	started := time.Now()
 	go func() {
 		for {
-			time.Sleep(5 * time.Second)
+			time.Sleep(opts.CleanupInterval)
 
-			now := time.Now()
 			s.State.Mu.Lock()
+			s.updateLiveness(time.Now(), started)
+			s.State.Mu.Unlock()
+		}
+	}()
+}
+
+// updateLiveness marks nodes silent for NodeTTL suspect and those silent
+// for NodeDeadAfter dead, queueing their chunks for re-replication.
+// Silence is counted from no earlier than since, so nodes replayed from
+// the WAL get a full grace period after a restart.
+// Caller must hold the state write lock.
+func (s *Server) updateLiveness(now, since time.Time) {
+	opts := s.opts()
+	for id, node := range s.State.Nodes {
+		// nodes in maintenance are expected to be away
+		if node.inMaintenance(now) || node.AdminState == AdminDecommissioned {
+			continue
+		}
 
-			for id, node := range s.State.Nodes {
-				if now.Sub(node.Lastseen) > nodeTTL {
-					delete(s.State.Nodes, id)
+		seen := node.Lastseen
+		if seen.Before(since) {
+			seen = since
+		}
+		silent := now.Sub(seen)
+
+		switch {
+		case silent > opts.NodeDeadAfter && node.Liveness != NodeDead:
+			log.Printf("Node %s is dead, re-replicating its chunks", id)
+			node.Liveness = NodeDead
+			s.State.Nodes[id] = node
+			s.reconcileNode(id)
+		case silent > opts.NodeTTL && node.Liveness == NodeLive:
+			log.Printf("Node %s is suspect", id)
+			node.Liveness = NodeSuspect
+			s.State.Nodes[id] = node
+		}
+	}
+}
+
+// reviveNode handles contact from a node that was not live. A suspect node
+// is live again at once; a dead one is reinstated once its replicas are
+// verified. Caller must hold the state write lock.
+func (s *Server) reviveNode(id string, node NodeStatus) NodeStatus {
+	switch node.Liveness {
+	case NodeSuspect:
+		log.Printf("Node %s is live again", id)
+		node.Liveness = NodeLive
+	case NodeDead:
+		log.Printf("Node %s is back, verifying its replicas", id)
+		node.Liveness = NodeRejoining
+		go s.reinstate(id)
+	}
+	return node
+}
+
+// reinstate asks a returning node which of its listed replicas it still
+// holds, forgets the ones it lost, and puts it back in service.
+func (s *Server) reinstate(id string) {
+	s.State.Mu.RLock()
+	addr := s.State.Nodes[id].Address
+	asked := make(map[string]bool)
+	var chunkIds []string
+	for _, chunks := range s.State.Files {
+		for _, c := range chunks {
+			if containsNode(c.Nodes, id) && !asked[c.ChunkId] {
+				asked[c.ChunkId] = true
+				chunkIds = append(chunkIds, c.ChunkId)
+			}
+		}
+	}
+	s.State.Mu.RUnlock()
+
+	held, err := s.checkChunks(addr, chunkIds)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	node, ok := s.State.Nodes[id]
+	if !ok || node.Liveness != NodeRejoining {
+		return
+	}
+	if err != nil {
+		// the next heartbeat tries again
+		log.Printf("Failed to verify replicas of node %s: %v", id, err)
+		node.Liveness = NodeDead
+		s.State.Nodes[id] = node
+		return
+	}
+
+	has := make(map[string]bool, len(held))
+	for _, c := range held {
+		has[c] = true
+	}
+
+	lost := 0
+	for fname, chunks := range s.State.Files {
+		for idx, c := range chunks {
+			if asked[c.ChunkId] && !has[c.ChunkId] && containsNode(c.Nodes, id) {
+				if err := s.removeReplica(fname, idx, id); err != nil {
+					log.Printf("Failed to forget lost replica %s on %s: %v", c.ChunkId, id, err)
+					continue
 				}
+				lost++
 			}
-			s.State.Mu.Unlock()
 		}
-	}()
+	}
+
+	node.Liveness = NodeLive
+	s.State.Nodes[id] = node
+	log.Printf("Node %s reinstated, %d of %d replicas lost", id, lost, len(chunkIds))
+
+	// its replicas count again, so some chunks may now be over-replicated
+	s.reconcileNode(id)
 }
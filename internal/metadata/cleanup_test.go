@@ -0,0 +1,163 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestNodeLiveness(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	start := time.Now()
+	for i, addr := range []string{"a", "b", "c"} {
+		s.State.Nodes[fmt.Sprintf("dn%d", i+1)] = NodeStatus{Address: addr, Lastseen: start}
+	}
+	s.State.Files["f"] = map[int]ChunkMetadata{0: {ChunkId: "c0", Nodes: []string{"dn1", "dn2"}}}
+
+	live := func(id string) {
+		n := s.State.Nodes[id]
+		n.Lastseen = start.Add(90 * time.Second)
+		s.State.Nodes[id] = n
+	}
+	live("dn2")
+	live("dn3")
+
+	s.updateLiveness(start.Add(20*time.Second), start)
+	if st := s.State.Nodes["dn1"].Liveness; st != NodeSuspect {
+		t.Fatalf("expected suspect, got %q", st)
+	}
+	if _, ok := s.placementCandidates()["dn1"]; ok {
+		t.Fatalf("suspect node must not receive replicas")
+	}
+	if n := s.usableReplicas([]string{"dn1", "dn2"}); n != 2 {
+		t.Fatalf("suspect replicas should still count, got %d", n)
+	}
+
+	s.updateLiveness(start.Add(2*time.Minute), start)
+	if st := s.State.Nodes["dn1"].Liveness; st != NodeDead {
+		t.Fatalf("expected dead, got %q", st)
+	}
+	if _, ok := s.State.Nodes["dn1"]; !ok {
+		t.Fatalf("dead node must not be forgotten")
+	}
+	if s.queue().Len() != 1 {
+		t.Fatalf("expected chunk on dead node to be queued, got %d", s.queue().Len())
+	}
+	// dn2 has gone quiet too: neither replica is copied from
+	if src := s.pickSource([]string{"dn1", "dn2"}); src != "" {
+		t.Fatalf("expected no source from dead and suspect nodes, got %s", src)
+	}
+
+	meta, err := s.GetFile(context.Background(), &pb.FileRequest{Filename: "f"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if nodes := meta.Chunks[0].Nodes; len(nodes) != 1 || nodes[0] != "b" {
+		t.Fatalf("expected only live replica b, got %v", nodes)
+	}
+
+	// a silent restart of the metadata server is not a death
+	s2 := newTestServer(t, Options{})
+	s2.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s2.updateLiveness(start.Add(time.Second), start)
+	if st := s2.State.Nodes["dn1"].Liveness; st != NodeLive {
+		t.Fatalf("replayed node should get a grace period, got %q", st)
+	}
+}
+
+// fakeNode is a DataNode holding the given chunks, for the checks the
+// metadata server makes of a node's replicas.
+type fakeNode struct {
+	pb.UnimplementedDataNodeServiceServer
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (n *fakeNode) CheckChunks(_ context.Context, req *pb.ChunkList) (*pb.ChunkList, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	res := &pb.ChunkList{}
+	for _, id := range req.ChunkIds {
+		if n.held[id] {
+			res.ChunkIds = append(res.ChunkIds, id)
+		}
+	}
+	return res, nil
+}
+
+func (n *fakeNode) DeleteChunk(_ context.Context, req *pb.ChunkRequest) (*pb.Ack, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.held, req.ChunkId)
+	return &pb.Ack{Ok: true}, nil
+}
+
+// add gives the node a chunk, as another node pushing it there would.
+func (n *fakeNode) add(id string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.held[id] = true
+}
+
+func (n *fakeNode) holds(id string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.held[id]
+}
+
+// startFakeNode serves n until the test ends and returns its address.
+func startFakeNode(t *testing.T, n *fakeNode) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterDataNodeServiceServer(grpcServer, n)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	return lis.Addr().String()
+}
+
+func TestReinstateNode(t *testing.T) {
+	addr := startFakeNode(t, &fakeNode{held: map[string]bool{"c0": true}})
+	s := newTestServer(t, Options{})
+	s.State.Nodes["dn1"] = NodeStatus{Address: addr, Liveness: NodeDead}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b"}
+	s.State.Files["f"] = map[int]ChunkMetadata{
+		0: {ChunkId: "c0", Nodes: []string{"dn1", "dn2"}},
+		1: {ChunkId: "c1", Nodes: []string{"dn1", "dn2"}},
+	}
+
+	s.State.Mu.Lock()
+	s.State.Nodes["dn1"] = s.reviveNode("dn1", s.State.Nodes["dn1"])
+	s.State.Mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.State.Mu.RLock()
+		st := s.State.Nodes["dn1"].Liveness
+		s.State.Mu.RUnlock()
+		if st == NodeLive {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node not reinstated, liveness %q", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.State.Mu.RLock()
+	defer s.State.Mu.RUnlock()
+	if !containsNode(s.State.Files["f"][0].Nodes, "dn1") {
+		t.Fatalf("verified replica was dropped")
+	}
+	if containsNode(s.State.Files["f"][1].Nodes, "dn1") {
+		t.Fatalf("lost replica still listed")
+	}
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"log"
+	"time"
+)
+
+// blockReportGrace shields freshly allocated chunks from block reports:
+// their replicas may not have been written yet.
+const blockReportGrace = time.Minute
+
+// queueCommand holds a command until the node's next heartbeat.
+// Caller must hold the state write lock.
+func (s *Server) queueCommand(nodeId string, cmd *pb.NodeCommand) {
+	if s.commands == nil {
+		s.commands = make(map[string][]*pb.NodeCommand)
+	}
+	s.commands[nodeId] = append(s.commands[nodeId], cmd)
+}
+
+// takeCommands returns and clears the commands waiting for a node.
+// Deletes of chunks the node has since been given again are dropped.
+// Caller must hold the state write lock.
+func (s *Server) takeCommands(nodeId string) []*pb.NodeCommand {
+	cmds := s.commands[nodeId]
+	delete(s.commands, nodeId)
+
+	var keep map[string]bool
+	res := cmds[:0]
+	for _, cmd := range cmds {
+		if cmd.Type == pb.NodeCommand_DELETE_CHUNKS {
+			if keep == nil {
+				keep = s.keptChunks(nodeId)
+			}
+			var ids []string
+			for _, id := range cmd.ChunkIds {
+				if !keep[id] {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			cmd.ChunkIds = ids
+		}
+		res = append(res, cmd)
+	}
+	return res
+}
+
+// keptChunks returns the chunks a node must keep: those listed on it and
+// those that may be on their way to it. Caller must hold the state lock.
+func (s *Server) keptChunks(nodeId string) map[string]bool {
+	keep := make(map[string]bool)
+	for fname, chunks := range s.State.Files {
+		for idx, c := range chunks {
+			t := replTask{Filename: fname, ChunkIndex: idx}
+			if containsNode(c.Nodes, nodeId) || s.State.Replicating[t.key()] {
+				keep[c.ChunkId] = true
+			}
+		}
+	}
+	return keep
+}
+
+// BlockReport reconciles chunk metadata with the chunks a node actually
+// holds: replicas it lost are forgotten and re-replicated, and chunks that
+// no file references on that node are deleted.
+func (s *Server) BlockReport(ctx context.Context, req *pb.BlockReportRequest) (*pb.Ack, error) {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if _, ok := s.State.Nodes[req.NodeId]; !ok {
+		return nil, notFound("node", req.NodeId)
+	}
+
+	held := make(map[string]bool, len(req.ChunkIds))
+	for _, id := range req.ChunkIds {
+		held[id] = true
+	}
+
+	keep := s.keptChunks(req.NodeId)
+	affected := make(map[string]bool)
+	lost, pending := 0, 0
+	for fname, chunks := range s.State.Files {
+		for idx, c := range chunks {
+			if !containsNode(c.Nodes, req.NodeId) || held[c.ChunkId] {
+				continue
+			}
+			if time.Since(c.allocated) < blockReportGrace {
+				pending++
+				continue
+			}
+			if err := s.removeReplica(fname, idx, req.NodeId); err != nil {
+				return nil, err
+			}
+			affected[fname] = true
+			lost++
+		}
+	}
+
+	for fname := range affected {
+		s.reconcileFile(fname)
+	}
+
+	var stray []string
+	for _, id := range req.ChunkIds {
+		if !keep[id] {
+			stray = append(stray, id)
+		}
+	}
+
+	// an empty namespace more likely means a lost or misplaced WAL than a
+	// cluster whose files were all deleted; keep the data for an operator
+	if len(stray) > 0 && len(s.State.Files) == 0 {
+		log.Printf("Keeping %d unreferenced chunks on %s: the namespace is empty", len(stray), req.NodeId)
+		stray = nil
+	}
+	if len(stray) > 0 {
+		s.queueCommand(req.NodeId, &pb.NodeCommand{
+			Type:     pb.NodeCommand_DELETE_CHUNKS,
+			ChunkIds: stray,
+		})
+	}
+
+	// a missing fresh chunk may be lost rather than still on its way;
+	// look again once the grace period is over
+	if pending > 0 {
+		time.AfterFunc(blockReportGrace, func() {
+			s.State.Mu.Lock()
+			defer s.State.Mu.Unlock()
+			s.queueCommand(req.NodeId, &pb.NodeCommand{Type: pb.NodeCommand_BLOCK_REPORT})
+		})
+	}
+
+	log.Printf("Block report from %s: %d chunks, %d lost, %d stray", req.NodeId, len(req.ChunkIds), lost, len(stray))
+	return &pb.Ack{Ok: true}, nil
+}
@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+	"time"
+)
+
+// commandsFor sends a heartbeat from a node and returns the commands it
+// receives.
+func commandsFor(t *testing.T, s *Server, id string) []*pb.NodeCommand {
+	t.Helper()
+	resp, err := s.Heartbeat(context.Background(), &pb.NodeHeartbeat{NodeId: id})
+	if err != nil {
+		t.Fatalf("Heartbeat from %s failed: %v", id, err)
+	}
+	return resp.Commands
+}
+
+func TestHeartbeatCommands(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	// a node replayed from the WAL resyncs on first contact
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	if cmds := commandsFor(t, s, "dn1"); len(cmds) != 1 || cmds[0].Type != pb.NodeCommand_BLOCK_REPORT {
+		t.Fatalf("expected a block report request, got %v", cmds)
+	}
+	if cmds := commandsFor(t, s, "dn1"); len(cmds) != 0 {
+		t.Fatalf("commands should be delivered once, got %v", cmds)
+	}
+}
+
+func TestBlockReport(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a", Lastseen: time.Now()}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b", Lastseen: time.Now()}
+	s.State.Files["f"] = map[int]ChunkMetadata{
+		0: {ChunkId: "kept", Nodes: []string{"dn1"}},
+		1: {ChunkId: "lost", Nodes: []string{"dn1"}},
+		2: {ChunkId: "fresh", Nodes: []string{"dn1"}, allocated: time.Now()},
+		3: {ChunkId: "elsewhere", Nodes: []string{"dn2"}},
+	}
+
+	_, err := s.BlockReport(ctx, &pb.BlockReportRequest{
+		NodeId:   "dn1",
+		ChunkIds: []string{"kept", "orphan", "elsewhere"},
+	})
+	if err != nil {
+		t.Fatalf("BlockReport failed: %v", err)
+	}
+
+	if !containsNode(s.State.Files["f"][0].Nodes, "dn1") {
+		t.Fatal("held replica was dropped")
+	}
+	if containsNode(s.State.Files["f"][1].Nodes, "dn1") {
+		t.Fatal("lost replica still listed")
+	}
+	if !containsNode(s.State.Files["f"][2].Nodes, "dn1") {
+		t.Fatal("freshly allocated replica dropped before it was written")
+	}
+	if s.queue().Len() != 1 {
+		t.Fatalf("expected the lost chunk to be queued, got %d", s.queue().Len())
+	}
+
+	cmds := commandsFor(t, s, "dn1")
+	if len(cmds) != 1 || cmds[0].Type != pb.NodeCommand_DELETE_CHUNKS {
+		t.Fatalf("expected a delete command, got %v", cmds)
+	}
+	if ids := cmds[0].ChunkIds; len(ids) != 2 || ids[0] != "orphan" || ids[1] != "elsewhere" {
+		t.Fatalf("expected stray chunks to be deleted, got %v", ids)
+	}
+
+	// a queued delete must not remove a replica the node was given since
+	s.State.Mu.Lock()
+	s.queueCommand("dn2", &pb.NodeCommand{Type: pb.NodeCommand_DELETE_CHUNKS, ChunkIds: []string{"elsewhere"}})
+	s.State.Mu.Unlock()
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b", Lastseen: time.Now()}
+	if cmds := commandsFor(t, s, "dn2"); len(cmds) != 0 {
+		t.Fatalf("delete of a listed replica should be dropped, got %v", cmds)
+	}
+}
@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// RPC errors carry a canonical status code and, in their details, what
+// went wrong in a form clients can inspect without parsing the message.
+
+// withDetails attaches details to a status, or leaves them out should
+// they fail to encode.
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	if d, err := st.WithDetails(details...); err == nil {
+		return d.Err()
+	}
+	return st.Err()
+}
+
+// notFound reports a missing file, directory, node or container.
+func notFound(kind, name string) error {
+	return withDetails(status.Newf(codes.NotFound, "%s: no such %s", name, kind),
+		&errdetails.ResourceInfo{ResourceType: kind, ResourceName: name})
+}
+
+// alreadyExists reports a name that is taken.
+func alreadyExists(kind, name string) error {
+	return withDetails(status.Newf(codes.AlreadyExists, "%s: %s exists", name, kind),
+		&errdetails.ResourceInfo{ResourceType: kind, ResourceName: name})
+}
+
+// invalidArgument reports a bad field of a request.
+func invalidArgument(field, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return withDetails(status.New(codes.InvalidArgument, msg),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: msg},
+		}})
+}
+
+// failedPrecondition reports a request the state of subject rules out;
+// typ names the condition, such as NOT_EMPTY.
+func failedPrecondition(typ, subject, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return withDetails(status.New(codes.FailedPrecondition, msg),
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{
+			{Type: typ, Subject: subject, Description: msg},
+		}})
+}
+
+// unavailable reports a request the cluster cannot serve for now, such as
+// an allocation with no DataNode to place it on.
+func unavailable(format string, args ...any) error {
+	return status.Errorf(codes.Unavailable, format, args...)
+}
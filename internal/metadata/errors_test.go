@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorCodes(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	_, err := s.GetFile(ctx, &pb.FileRequest{Filename: "/missing"})
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound {
+		t.Fatalf("GetFile of a missing file: got %v", err)
+	}
+	if len(st.Details()) != 1 || st.Details()[0].(*errdetails.ResourceInfo).ResourceName != "missing" {
+		t.Fatalf("GetFile details: %v", st.Details())
+	}
+
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "/a"})
+	if _, err := s.CreateFile(ctx, &pb.FileRequest{Filename: "/a"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("CreateFile of an existing file: got %v", err)
+	}
+	if _, err := s.CreateFile(ctx, &pb.FileRequest{Filename: "/b", Replication: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("CreateFile with a negative replication factor: got %v", err)
+	}
+	if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: chunkID("c1"), Filename: "/a"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("AllocateChunk without DataNodes: got %v", err)
+	}
+	// chunk IDs name files on the DataNodes
+	for _, id := range []string{"", "../../../etc/x", "C1", chunkID("c1")[:63] + "/"} {
+		if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: id, Filename: "/a"}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("AllocateChunk of chunk ID %q: got %v", id, err)
+		}
+		if _, err := s.AllocateContainer(ctx, &pb.AllocateContainerRequest{ChunkId: id, Size: 10}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("AllocateContainer of chunk ID %q: got %v", id, err)
+		}
+	}
+	if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: chunkID("c1"), Filename: "/a", ChunkIndex: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("AllocateChunk of a negative chunk index: got %v", err)
+	}
+
+	mustMkdir(t, ctx, s, "/d")
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "/d/x"})
+	_, err = s.DeleteFile(ctx, &pb.FileRequest{Filename: "/d"})
+	st = status.Convert(err)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("DeleteFile of a directory that is not empty: got %v", err)
+	}
+	if v := st.Details()[0].(*errdetails.PreconditionFailure).Violations[0]; v.Type != "NOT_EMPTY" || v.Subject != "d" {
+		t.Fatalf("DeleteFile details: %v", v)
+	}
+}
+
+func TestGetFileIncomplete(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+	mustRegister(t, s, "dn1", "localhost:6001")
+
+	allocate := func(name string, idx int32) {
+		t.Helper()
+		_, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: chunkID(name), Filename: "/f", ChunkIndex: idx, Size: 10})
+		if err != nil {
+			t.Fatalf("AllocateChunk %d failed: %v", idx, err)
+		}
+	}
+	allocate("c2", 2)
+	allocate("c0", 0)
+	_, err := s.GetFile(ctx, &pb.FileRequest{Filename: "/f"})
+	st := status.Convert(err)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("GetFile of a file with a missing chunk: got %v", err)
+	}
+	if v := st.Details()[0].(*errdetails.PreconditionFailure).Violations[0]; v.Type != "INCOMPLETE" {
+		t.Fatalf("GetFile details: %v", v)
+	}
+
+	allocate("c1", 1)
+	meta, err := s.GetFile(ctx, &pb.FileRequest{Filename: "/f"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	for i, c := range meta.Chunks {
+		if c.ChunkId != chunkID(fmt.Sprintf("c%d", i)) {
+			t.Fatalf("chunk %d is %s", i, c.ChunkId)
+		}
+	}
+}
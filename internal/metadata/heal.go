@@ -2,73 +2,115 @@ package metadata
 
 import (
 	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
 	"encoding/json"
+	"log"
 	"time"
 )
 
 func (s *Server) StartReplicationLoop() {
+	opts := s.opts()
+	s.initReplication()
+
+	for i := 0; i < opts.MaxReplications; i++ {
+		go s.replicationWorker()
+	}
+
 	go func() {
 		for {
-			time.Sleep(10 * time.Second)
-
-			s.State.Mu.RLock()
-			for fname, chunks := range s.State.Files {
-				for idx, meta := range chunks {
-					if len(meta.Nodes) < common.ReplicationFactor {
-						go s.replicateChunk(fname, idx, meta)
-					}
-				}
+			time.Sleep(opts.ReplicationInterval)
+
+			// converge every chunk towards its file's replication factor
+			s.State.Mu.Lock()
+			for fname := range s.State.Files {
+				s.reconcileFile(fname)
 			}
-			s.State.Mu.RUnlock()
+			s.State.Mu.Unlock()
+
+			s.compactContainers()
+			s.checkDecommissions()
 		}
 	}()
 }
 
+// A copy is made by the source node when it next heartbeats; the target
+// is polled for it every copyPollInterval until copyTimeout.
+const (
+	copyPollInterval = time.Second
+	copyTimeout      = time.Minute
+)
+
+// replicateChunk adds a replica of a chunk on a new node, copied there
+// from a live replica.
 func (s *Server) replicateChunk(
 	filename string,
 	chunkIndex int,
 	meta ChunkMetadata,
 ) {
 
-	// Step 1: pick source & target
-	source := pickSource(meta.Nodes)
-	if source == "" {
+	// Step 1: pick source & target from the chunk's current replicas
+	s.State.Mu.RLock()
+	chunk, ok := s.State.Files[filename][chunkIndex]
+	if !ok || chunk.ChunkId != meta.ChunkId {
+		s.State.Mu.RUnlock()
 		return
 	}
-
-	target := pickTarget(s.State.Nodes, meta.Nodes)
-	if target == "" {
-		return // nowhere to replicate to
+	source := s.pickSource(chunk.Nodes)
+	target := pickTarget(s.placementCandidates(), chunk.Nodes, s.opts().Placement)
+	targetAddr := s.addressOf(target)
+	s.State.Mu.RUnlock()
+	if source == "" || target == "" {
+		return // no live copy, or nowhere to replicate to
 	}
 
-	// Step 2: fetch chunk from source
-	data, err := fetchChunk(source, meta.ChunkId)
-	if err != nil {
-		return
-	}
+	// Step 2: have the source push the chunk to the target on its next
+	// heartbeat, so the data never passes through this server
+	s.initReplication()
+	s.throttle.wait(meta.stored(), source, target)
+	s.State.Mu.Lock()
+	s.queueCommand(source, &pb.NodeCommand{
+		Type:     pb.NodeCommand_REPLICATE_CHUNK,
+		ChunkIds: []string{meta.ChunkId},
+		Target:   targetAddr,
+		Token:    s.chunkToken(common.ChunkReplicate, common.ReplicateSubject(meta.ChunkId, targetAddr), 0, 0),
+	})
+	s.State.Mu.Unlock()
 
-	// Step 3: store chunk on target
-	err = StoreChunk(target, meta.ChunkId, data)
-	if err != nil {
+	// Step 3: wait for the copy to reach the target
+	if !s.awaitChunk(targetAddr, meta.ChunkId, copyTimeout) {
+		log.Printf("Copy of %s from %s to %s did not arrive in time", meta.ChunkId, source, target)
 		return
 	}
 
 	// Step 4: update metadata atomically
 	s.State.Mu.Lock()
-	defer s.State.Mu.Unlock()
+	if !s.commitReplica(filename, chunkIndex, meta.ChunkId, source, target) {
+		chunk, ok := s.State.Files[filename][chunkIndex]
+		listed := ok && chunk.ChunkId == meta.ChunkId && containsNode(chunk.Nodes, target)
+		s.State.Mu.Unlock()
 
-	// re-validate source and target
-	if _, ok := s.State.Nodes[source]; !ok {
-		return
-	}
-	if _, ok := s.State.Nodes[target]; !ok {
+		// the copy is not referenced by metadata; reclaim it
+		if !listed {
+			s.deleteChunk(targetAddr, meta.ChunkId)
+		}
 		return
 	}
+	s.State.Mu.Unlock()
+}
 
-	// re-validate chunk still needs replication
-	chunk := s.State.Files[filename][chunkIndex]
-	if len(chunk.Nodes) >= common.ReplicationFactor {
-		return
+// commitReplica journals and records a copy of a chunk made on target,
+// after checking that the chunk is unchanged and still short of replicas,
+// that the copy came from a live replica still listed on it, and that the
+// target is live and not already listed.
+// Caller must hold the state write lock.
+func (s *Server) commitReplica(filename string, chunkIndex int, chunkId, source, target string) bool {
+	chunk, ok := s.State.Files[filename][chunkIndex]
+	if !ok || chunk.ChunkId != chunkId || !s.liveReplica(chunk, source) ||
+		containsNode(chunk.Nodes, target) || s.State.Nodes[target].Liveness != NodeLive {
+		return false
+	}
+	if s.usableReplicas(chunk.Nodes) >= s.replicationFor(filename) {
+		return false
 	}
 
 	payload, err := json.Marshal(struct {
@@ -81,7 +123,7 @@ func (s *Server) replicateChunk(
 		Node:       target,
 	})
 	if err != nil {
-		return
+		return false
 	}
 
 	err = s.WAL.Append(WALEntry{
@@ -89,11 +131,88 @@ func (s *Server) replicateChunk(
 		Data: payload,
 	})
 	if err != nil {
-		return
+		return false
 	}
 
-	// now update metadata
 	chunk.Nodes = append(chunk.Nodes, target)
 	s.State.Files[filename][chunkIndex] = chunk
+	return true
+}
 
+// trimChunk drops replicas of a chunk above the file's replication factor.
+func (s *Server) trimChunk(
+	filename string,
+	chunkIndex int,
+	meta ChunkMetadata,
+) {
+	s.State.Mu.Lock()
+
+	// re-validate chunk is still over-replicated
+	chunk, ok := s.State.Files[filename][chunkIndex]
+	if !ok || chunk.ChunkId != meta.ChunkId {
+		s.State.Mu.Unlock()
+		return
+	}
+
+	removed := make(map[string]string) // node ID -> address of dropped replicas
+	usage := s.nodeUsage()
+	for s.presentReplicas(chunk.Nodes) > s.replicationFor(filename) {
+		victim := s.pickTrimVictim(chunk.Nodes, usage)
+
+		if err := s.removeReplica(filename, chunkIndex, victim); err != nil {
+			break
+		}
+
+		chunk = s.State.Files[filename][chunkIndex]
+		usage[victim] -= chunk.stored()
+		if addr := s.addressOf(victim); addr != "" {
+			removed[victim] = addr
+		}
+	}
+	s.State.Mu.Unlock()
+
+	// metadata no longer points at these replicas, so deleting is safe
+	for id, addr := range removed {
+		if err := s.deleteChunk(addr, chunk.ChunkId); err != nil {
+			log.Printf("Failed to delete replica of %s on %s: %v", chunk.ChunkId, id, err)
+
+			// retry when the node next checks in
+			s.State.Mu.Lock()
+			s.queueCommand(id, &pb.NodeCommand{
+				Type:     pb.NodeCommand_DELETE_CHUNKS,
+				ChunkIds: []string{chunk.ChunkId},
+			})
+			s.State.Mu.Unlock()
+		}
+	}
+}
+
+// removeReplica journals and drops one replica from a chunk's locations.
+// Caller must hold the state write lock.
+func (s *Server) removeReplica(filename string, chunkIndex int, node string) error {
+	payload, err := json.Marshal(struct {
+		Filename   string
+		ChunkIndex int
+		Node       string
+	}{
+		Filename:   filename,
+		ChunkIndex: chunkIndex,
+		Node:       node,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "REMOVE_REPLICA",
+		Data: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	chunk := s.State.Files[filename][chunkIndex]
+	chunk.Nodes = removeNode(chunk.Nodes, node)
+	s.State.Files[filename][chunkIndex] = chunk
+	return nil
 }
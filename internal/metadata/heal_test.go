@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"testing"
+	"time"
+)
+
+func TestReplicateChunk(t *testing.T) {
+	key := make([]byte, common.KeySize)
+	dst := &fakeNode{held: map[string]bool{}}
+	dstAddr := startFakeNode(t, dst)
+
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	s.ChunkKey = key
+	s.State.Nodes["dn1"] = NodeStatus{Address: "src"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: dstAddr}
+	meta := ChunkMetadata{ChunkId: "c0", Nodes: []string{"dn1"}}
+	s.State.Files["f"] = map[int]ChunkMetadata{0: meta}
+
+	setLiveness := func(id, liveness string) {
+		s.State.Mu.Lock()
+		defer s.State.Mu.Unlock()
+		n := s.State.Nodes[id]
+		n.Liveness = liveness
+		s.State.Nodes[id] = n
+	}
+	replicas := func() []string {
+		s.State.Mu.RLock()
+		defer s.State.Mu.RUnlock()
+		return s.State.Files["f"][0].Nodes
+	}
+
+	// replicate runs replicateChunk while playing the source node: it
+	// carries out the copy it is told to make, after calling during
+	replicate := func(during func()) {
+		t.Helper()
+		done := make(chan struct{})
+		go func() {
+			s.replicateChunk("f", 0, meta)
+			close(done)
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			s.State.Mu.Lock()
+			cmds := s.takeCommands("dn1")
+			s.State.Mu.Unlock()
+			if len(cmds) == 0 {
+				continue
+			}
+
+			cmd := cmds[0]
+			if cmd.Type != pb.NodeCommand_REPLICATE_CHUNK || cmd.ChunkIds[0] != "c0" || cmd.Target != dstAddr {
+				t.Fatalf("unexpected command %v", cmd)
+			}
+			subject := common.ReplicateSubject("c0", dstAddr)
+			if err := common.VerifyChunkToken(key, cmd.Token, common.ChunkReplicate, subject, 0, 0); err != nil {
+				t.Fatalf("command token does not allow the copy: %v", err)
+			}
+			during()
+			dst.add("c0")
+			<-done
+			return
+		}
+	}
+
+	// a suspect replica is not copied from
+	setLiveness("dn1", NodeSuspect)
+	replicate(func() { t.Fatal("asked a suspect node for a copy") })
+	setLiveness("dn1", NodeLive)
+
+	// the source goes suspect during the copy: the copy is discarded
+	replicate(func() { setLiveness("dn1", NodeSuspect) })
+	if dst.holds("c0") || len(replicas()) != 1 {
+		t.Fatalf("copy from a source lost mid-copy kept: replicas %v", replicas())
+	}
+	setLiveness("dn1", NodeLive)
+
+	// the source's replica is dropped during the copy
+	replicate(func() {
+		s.State.Mu.Lock()
+		defer s.State.Mu.Unlock()
+		s.removeReplica("f", 0, "dn1")
+	})
+	if dst.holds("c0") || len(replicas()) != 0 {
+		t.Fatalf("copy of a dropped replica kept: replicas %v", replicas())
+	}
+
+	s.State.Files["f"] = map[int]ChunkMetadata{0: meta}
+	replicate(func() {})
+	if !dst.holds("c0") || !containsNode(replicas(), "dn2") {
+		t.Fatalf("replica not added: replicas %v", replicas())
+	}
+}
+
+func TestTrimChunk(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	meta := ChunkMetadata{ChunkId: "c0", Nodes: []string{"a", "b", "c"}}
+	s.State.Files["f"] = map[int]ChunkMetadata{0: meta}
+	s.State.Replication["f"] = 1
+
+	s.trimChunk("f", 0, meta)
+
+	if n := len(s.State.Files["f"][0].Nodes); n != 1 {
+		t.Fatalf("expected 1 replica after trim, got %d", n)
+	}
+
+	s2 := NewServerWithOptions(s.Opts)
+	s2.State.Files["f"] = map[int]ChunkMetadata{0: meta}
+	s2.ReplayWAL(s.Opts.WALPath)
+
+	if n := len(s2.State.Files["f"][0].Nodes); n != 1 {
+		t.Fatalf("expected 1 replica after replay, got %d", n)
+	}
+}
+
+func TestPickTrimVictim(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b"}
+	s.State.Files["big"] = map[int]ChunkMetadata{
+		0: {ChunkId: "c0", Nodes: []string{"dn2"}, Size: 100},
+	}
+
+	usage := s.nodeUsage()
+
+	if v := s.pickTrimVictim([]string{"dn1", "dn2"}, usage); v != "dn2" {
+		t.Fatalf("expected fullest node dn2, got %s", v)
+	}
+	if v := s.pickTrimVictim([]string{"dn1", "dn2", "gone"}, usage); v != "gone" {
+		t.Fatalf("expected unregistered node, got %s", v)
+	}
+}
@@ -1,14 +1,67 @@
 package metadata
 
 import (
+	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
 	"context"
-	"os"
+	"fmt"
+	"path/filepath"
 	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// newTestServer returns a server with the given options whose WAL and
+// snapshot live in a temporary directory of the test.
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	opts.WALPath = filepath.Join(dir, "metadata.wal")
+	opts.SnapshotPath = filepath.Join(dir, "metadata.snapshot")
+	return NewServerWithOptions(opts)
+}
+
+// replay returns a new server rebuilt from the WAL of s.
+func replay(s *Server) *Server {
+	r := NewServerWithOptions(s.Opts)
+	r.ReplayWAL(s.Opts.WALPath)
+	return r
+}
+
+// mustRegister registers a DataNode, failing the test if it cannot.
+func mustRegister(t *testing.T, s *Server, id, addr string) {
+	t.Helper()
+	if _, err := s.RegisterNode(context.Background(), &pb.NodeInfo{NodeId: id, Address: addr}); err != nil {
+		t.Fatalf("RegisterNode %s: %v", id, err)
+	}
+}
+
+// mustCreate creates a file as the caller in ctx, failing the test if it
+// cannot.
+func mustCreate(t *testing.T, ctx context.Context, s *Server, req *pb.FileRequest) {
+	t.Helper()
+	if _, err := s.CreateFile(ctx, req); err != nil {
+		t.Fatalf("CreateFile %s: %v", req.Filename, err)
+	}
+}
+
+// mustMkdir creates a directory as the caller in ctx, failing the test if
+// it cannot.
+func mustMkdir(t *testing.T, ctx context.Context, s *Server, name string) {
+	t.Helper()
+	if _, err := s.Mkdir(ctx, &pb.FileRequest{Filename: name}); err != nil {
+		t.Fatalf("Mkdir %s: %v", name, err)
+	}
+}
+
+// chunkID is the ID a client gives the test chunk called name.
+func chunkID(name string) string {
+	return common.ChunkId(name, 0)
+}
+
 func TestChunkOrdering(t *testing.T) {
-	s := NewServer()
+	s := newTestServer(t, Options{})
 
 	s.State.Files["a.txt"] = map[int]ChunkMetadata{
 		2: {ChunkId: "c2"},
@@ -45,12 +98,8 @@ func TestReplicationLock(t *testing.T) {
 }
 
 func TestWALReplay(t *testing.T) {
-	// Use a temp WAL file for this test
-	walPath := "/tmp/test_wal_" + t.Name() + ".wal"
-	defer os.Remove(walPath)
-
-	// Create first server with temp WAL
-	s := &Server{State: NewState(), WAL: NewWAL(walPath)}
+	// Create first server
+	s := newTestServer(t, Options{})
 	ctx := context.Background()
 
 	_, err := s.CreateFile(ctx, &pb.FileRequest{Filename: "x.txt"})
@@ -59,8 +108,7 @@ func TestWALReplay(t *testing.T) {
 	}
 
 	// Create second server and replay
-	s2 := &Server{State: NewState(), WAL: NewWAL(walPath)}
-	s2.ReplayWAL(walPath)
+	s2 := replay(s)
 
 	if _, ok := s2.State.Files["x.txt"]; !ok {
 		t.Fatal("WAL replay failed - file not found after replay")
@@ -68,7 +116,7 @@ func TestWALReplay(t *testing.T) {
 }
 
 func TestRegisterNode(t *testing.T) {
-	s := NewServer()
+	s := newTestServer(t, Options{})
 	ctx := context.Background()
 
 	_, err := s.RegisterNode(ctx, &pb.NodeInfo{
@@ -87,21 +135,18 @@ func TestRegisterNode(t *testing.T) {
 }
 
 func TestAllocateChunk(t *testing.T) {
-	walPath := "/tmp/test_wal_" + t.Name() + ".wal"
-	defer os.Remove(walPath)
-
-	s := &Server{State: NewState(), WAL: NewWAL(walPath)}
+	s := newTestServer(t, Options{})
 	ctx := context.Background()
 
 	// Register a node first
 	s.State.Nodes["dn1"] = NodeStatus{Address: "localhost:6001"}
 
 	// Create file
-	s.CreateFile(ctx, &pb.FileRequest{Filename: "test.txt"})
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "test.txt"})
 
 	// Allocate chunk
 	resp, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{
-		ChunkId:    "chunk123",
+		ChunkId:    chunkID("chunk123"),
 		Filename:   "test.txt",
 		ChunkIndex: 0,
 	})
@@ -109,13 +154,13 @@ func TestAllocateChunk(t *testing.T) {
 		t.Fatalf("AllocateChunk failed: %v", err)
 	}
 
-	if resp.ChunkId != "chunk123" {
+	if resp.ChunkId != chunkID("chunk123") {
 		t.Fatalf("wrong chunk ID: got %s", resp.ChunkId)
 	}
 }
 
 func TestHeartbeat(t *testing.T) {
-	s := NewServer()
+	s := newTestServer(t, Options{})
 	ctx := context.Background()
 
 	// Register node first
@@ -131,13 +176,209 @@ func TestHeartbeat(t *testing.T) {
 		t.Fatal("Heartbeat should return Ok=true for registered node")
 	}
 
-	// Heartbeat from unknown node
+	// Heartbeat from unknown node: it is told to register
 	resp, err = s.Heartbeat(ctx, &pb.NodeHeartbeat{NodeId: "unknown"})
+	if err != nil || resp.Ok {
+		t.Fatalf("Heartbeat from an unknown node: got %v, %v", resp, err)
+	}
+	if len(resp.Commands) != 1 || resp.Commands[0].Type != pb.NodeCommand_REGISTER {
+		t.Fatalf("expected a register request, got %v", resp.Commands)
+	}
+}
+
+func TestAllocateChunkUsesReplicationFactorOption(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	ctx := context.Background()
+
+	s.State.Nodes["dn1"] = NodeStatus{Address: "localhost:6001"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "localhost:6002"}
+	s.State.Nodes["dn3"] = NodeStatus{Address: "localhost:6003"}
+
+	resp, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+		ChunkId:    chunkID("chunk123"),
+		Filename:   "test.txt",
+		ChunkIndex: 0,
+	})
 	if err != nil {
-		t.Fatalf("Heartbeat failed: %v", err)
+		t.Fatalf("AllocateChunk failed: %v", err)
+	}
+
+	if len(resp.Nodes) != 2 {
+		t.Fatalf("expected 2 replicas, got %d", len(resp.Nodes))
+	}
+}
+
+func TestChunkLocationsByNodeID(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+
+	mustRegister(t, s, "dn1", "host1:6001")
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "f"})
+	if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: chunkID("c0"), Filename: "f"}); err != nil {
+		t.Fatalf("AllocateChunk failed: %v", err)
+	}
+	if nodes := s.State.Files["f"][0].Nodes; len(nodes) != 1 || nodes[0] != "dn1" {
+		t.Fatalf("expected location by node ID, got %v", nodes)
+	}
+
+	// the node comes back on a new address; its replicas follow it
+	mustRegister(t, s, "dn1", "host2:6001")
+	meta, err := s.GetFile(ctx, &pb.FileRequest{Filename: "f"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if nodes := meta.Chunks[0].Nodes; len(nodes) != 1 || nodes[0] != "host2:6001" {
+		t.Fatalf("expected the current address, got %v", nodes)
+	}
+
+	s2 := replay(s)
+	if nodes := s2.State.Files["f"][0].Nodes; len(nodes) != 1 || nodes[0] != "dn1" {
+		t.Fatalf("expected node ID after replay, got %v", nodes)
+	}
+}
+
+func TestReplayAddressLocations(t *testing.T) {
+	// entries written when chunk locations were addresses
+	s := newTestServer(t, Options{})
+	for _, e := range []WALEntry{
+		{Type: "REGISTER_NODE", Data: []byte(`{"node_id":"dn1","address":"localhost:6001"}`)},
+		{Type: "REGISTER_NODE", Data: []byte(`{"node_id":"dn2","address":"localhost:6002"}`)},
+		{Type: "ALLOCATE_CHUNK", Data: []byte(`{"Filename":"f","ChunkIndex":0,"ChunkId":"c0","Nodes":["localhost:6001"]}`)},
+		{Type: "ADD_REPLICA", Data: []byte(`{"Filename":"f","ChunkIndex":0,"Node":"localhost:6002"}`)},
+		{Type: "REMOVE_REPLICA", Data: []byte(`{"Filename":"f","ChunkIndex":0,"Node":"dn1"}`)},
+	} {
+		if err := s.WAL.Append(e); err != nil {
+			t.Fatalf("WAL append failed: %v", err)
+		}
 	}
 
-	if resp.Ok {
-		t.Fatal("Heartbeat should return Ok=false for unknown node")
+	r := replay(s)
+
+	if nodes := r.State.Files["f"][0].Nodes; len(nodes) != 1 || nodes[0] != "dn2" {
+		t.Fatalf("expected addresses mapped to node IDs, got %v", nodes)
+	}
+}
+
+func TestCompressedChunks(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+
+	mustRegister(t, s, "dn1", "localhost:6001")
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "app.log"})
+	for _, req := range []*pb.AllocateChunkRequest{
+		{ChunkId: chunkID("c0"), Filename: "app.log", ChunkIndex: 0, Size: 1000, Codec: "zstd", StoredSize: 100},
+		{ChunkId: chunkID("c1"), Filename: "app.log", ChunkIndex: 1, Size: 50},
+	} {
+		if _, err := s.AllocateChunk(ctx, req); err != nil {
+			t.Fatalf("AllocateChunk %s failed: %v", req.ChunkId, err)
+		}
+	}
+	if _, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+		ChunkId: chunkID("c2"), Filename: "app.log", ChunkIndex: 2, Size: 50, Codec: "zstd",
+	}); err == nil {
+		t.Fatal("a compressed chunk without a stored size should be rejected")
+	}
+
+	info, err := s.StatFile(ctx, &pb.FileRequest{Filename: "app.log"})
+	if err != nil {
+		t.Fatalf("StatFile failed: %v", err)
+	}
+	if info.Size != 1050 || info.StoredSize != 150 {
+		t.Fatalf("expected 1050 bytes stored as 150, got %d as %d", info.Size, info.StoredSize)
+	}
+
+	meta, err := s.GetFile(ctx, &pb.FileRequest{Filename: "app.log"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if c := meta.Chunks[0]; c.Codec != "zstd" || c.Size != 1000 || c.StoredSize != 100 {
+		t.Fatalf("unexpected chunk metadata: %v", c)
+	}
+
+	s2 := replay(s)
+	if c := s2.State.Files["app.log"][0]; c.Codec != "zstd" || c.stored() != 100 {
+		t.Fatalf("codec not replayed: %+v", c)
+	}
+}
+
+func TestEncryptedFileKeys(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	wrapped := []byte("wrapped-data-key")
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "secret", WrappedKey: wrapped, KeyId: "k1"})
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "plain"})
+	if _, err := s.RenameFile(ctx, &pb.RenameRequest{Src: "secret", Dst: "vault/secret"}); err != nil {
+		t.Fatalf("RenameFile failed: %v", err)
+	}
+
+	meta, err := s.GetFile(ctx, &pb.FileRequest{Filename: "vault/secret"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if string(meta.WrappedKey) != string(wrapped) || meta.KeyId != "k1" {
+		t.Fatalf("wrapped key lost: %q %q", meta.WrappedKey, meta.KeyId)
+	}
+	if info, err := s.StatFile(ctx, &pb.FileRequest{Filename: "plain"}); err != nil || info.KeyId != "" {
+		t.Fatalf("plain file reported as encrypted with %q: %v", info.GetKeyId(), err)
+	}
+
+	s2 := replay(s)
+	if key := s2.State.Keys["vault/secret"]; string(key.Wrapped) != string(wrapped) || key.KeyId != "k1" {
+		t.Fatalf("wrapped key not replayed: %+v", key)
+	}
+
+	if _, err := s.DeleteFile(ctx, &pb.FileRequest{Filename: "vault/secret"}); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "vault/secret"})
+	if _, ok := s.State.Keys["vault/secret"]; ok {
+		t.Fatal("a re-created file inherited the deleted file's key")
+	}
+}
+
+func TestReallocateChunk(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		s.State.Nodes[fmt.Sprintf("dn%d", i)] = NodeStatus{Address: fmt.Sprintf("localhost:600%d", i)}
+	}
+
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "f"})
+	req := &pb.AllocateChunkRequest{ChunkId: chunkID("c1"), Filename: "f", Size: 10}
+	first, err := s.AllocateChunk(ctx, req)
+	if err != nil {
+		t.Fatalf("AllocateChunk: %v", err)
+	}
+
+	// the uploader could store it on neither node
+	req.ExcludeNodes = first.Nodes
+	moved, err := s.AllocateChunk(ctx, req)
+	if err != nil {
+		t.Fatalf("AllocateChunk excluding %v: %v", first.Nodes, err)
+	}
+	if len(moved.Nodes) != 2 {
+		t.Fatalf("moved to %v", moved.Nodes)
+	}
+	for _, addr := range moved.Nodes {
+		if addr == first.Nodes[0] || addr == first.Nodes[1] {
+			t.Fatalf("moved to %v, which includes a failed node of %v", moved.Nodes, first.Nodes)
+		}
+	}
+	req.ExcludeNodes = append(first.Nodes, moved.Nodes...)
+	if _, err := s.AllocateChunk(ctx, req); status.Code(err) != codes.Unavailable {
+		t.Fatalf("AllocateChunk excluding every node: got %v", err)
+	}
+
+	// the move survives a restart, after which the chunk stays put
+	r := NewServerWithOptions(s.Opts)
+	r.State.Nodes = s.State.Nodes
+	r.ReplayWAL(s.Opts.WALPath)
+	if got := r.replicaAddresses(r.State.Files["f"][0].Nodes); len(got) != 2 || got[0] != moved.Nodes[0] {
+		t.Fatalf("replayed replicas %v, want %v", got, moved.Nodes)
+	}
+	req.ExcludeNodes = moved.Nodes
+	if _, err := r.AllocateChunk(ctx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("moving a chunk replayed from the WAL: got %v", err)
 	}
 }
@@ -0,0 +1,431 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+)
+
+func (s *Server) ListFiles(ctx context.Context, req *pb.ListRequest) (*pb.FileList, error) {
+	dir := cleanPath(req.Prefix)
+	if reserved(dir) {
+		return nil, notFound("file or directory", dir)
+	}
+
+	id := Caller(ctx)
+
+	s.State.Mu.RLock()
+	defer s.State.Mu.RUnlock()
+
+	// Listing a file returns just that file
+	if chunks, ok := s.State.Files[dir]; ok {
+		if err := s.access(id, dir, 0); err != nil {
+			return nil, err
+		}
+		return &pb.FileList{Files: []*pb.FileInfo{s.fileInfo(dir, chunks)}}, nil
+	}
+	if !s.dirExists(dir) {
+		return nil, notFound("file or directory", dir)
+	}
+	if err := s.access(id, dir, permRead); err != nil {
+		return nil, err
+	}
+
+	// a recursive listing leaves out what is under directories the caller
+	// cannot list
+	hidden := func(name string) bool {
+		return req.Recursive && parent(name) != dir && s.access(id, parent(name), permRead|permExec) != nil
+	}
+
+	seen := make(map[string]bool)
+	var res []*pb.FileInfo
+
+	addDir := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, s.withPerm(&pb.FileInfo{Filename: name, IsDir: true}))
+		}
+	}
+
+	// child returns the direct child of dir that leads to name
+	child := func(name string) (string, bool) {
+		rel := strings.TrimPrefix(name, dir+"/")
+		if dir == "" {
+			rel = name
+		}
+		first, _, nested := strings.Cut(rel, "/")
+		if dir == "" {
+			return first, nested
+		}
+		return dir + "/" + first, nested
+	}
+
+	for name, chunks := range s.State.Files {
+		if !isUnder(name, dir) || reserved(name) || hidden(name) {
+			continue
+		}
+		if c, nested := child(name); nested && !req.Recursive {
+			addDir(c)
+			continue
+		}
+		res = append(res, s.fileInfo(name, chunks))
+	}
+
+	for name := range s.State.Dirs {
+		if !isUnder(name, dir) || hidden(name) {
+			continue
+		}
+		if c, _ := child(name); !req.Recursive {
+			addDir(c)
+			continue
+		}
+		addDir(name)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Filename < res[j].Filename })
+
+	return &pb.FileList{Files: res}, nil
+}
+
+func (s *Server) StatFile(ctx context.Context, req *pb.FileRequest) (*pb.FileInfo, error) {
+	filename := cleanPath(req.Filename)
+	if reserved(filename) {
+		return nil, notFound("file or directory", filename)
+	}
+
+	s.State.Mu.RLock()
+	defer s.State.Mu.RUnlock()
+
+	if err := s.access(Caller(ctx), filename, 0); err != nil {
+		return nil, err
+	}
+	if chunks, ok := s.State.Files[filename]; ok {
+		return s.fileInfo(filename, chunks), nil
+	}
+	if s.dirExists(filename) {
+		return s.withPerm(&pb.FileInfo{Filename: filename, IsDir: true}), nil
+	}
+
+	return nil, notFound("file or directory", filename)
+}
+
+func (s *Server) DeleteFile(ctx context.Context, req *pb.FileRequest) (*pb.Ack, error) {
+	filename := cleanPath(req.Filename)
+	if reserved(filename) {
+		return nil, notFound("file or directory", filename)
+	}
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if err := s.accessParent(Caller(ctx), filename); err != nil {
+		return nil, err
+	}
+	if _, isFile := s.State.Files[filename]; !isFile {
+		if filename == "" || !s.dirExists(filename) {
+			return nil, notFound("file or directory", filename)
+		}
+		if s.dirHasChildren(filename) {
+			return nil, failedPrecondition("NOT_EMPTY", filename, "%s: directory not empty", filename)
+		}
+	}
+
+	if err := s.removeFile(filename); err != nil {
+		return nil, err
+	}
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// removeFile deletes a file or an empty directory. The replicas of a
+// file's chunks are reclaimed in the background; a packed file's bytes
+// stay in its container until the container is compacted.
+// Caller must hold the state write lock.
+func (s *Server) removeFile(filename string) error {
+	filenameJSON, err := json.Marshal(filename)
+	if err != nil {
+		return err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "DELETE_FILE",
+		Data: filenameJSON,
+	})
+	if err != nil {
+		return err
+	}
+
+	chunks, isFile := s.State.Files[filename]
+	s.applyDelete(filename)
+
+	// Replicas are garbage now; reclaim them in the background
+	if isFile {
+		go s.deleteReplicas(s.chunkAddresses(chunks))
+	}
+	return nil
+}
+
+func (s *Server) RenameFile(ctx context.Context, req *pb.RenameRequest) (*pb.Ack, error) {
+	src, dst := cleanPath(req.Src), cleanPath(req.Dst)
+	if src == "" || dst == "" {
+		return nil, invalidArgument("src", "cannot rename the root directory")
+	}
+	if reserved(src) || reserved(dst) {
+		return nil, invalidArgument("src", "invalid rename of %s to %s", src, dst)
+	}
+
+	id := Caller(ctx)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if err := s.accessParent(id, src); err != nil {
+		return nil, err
+	}
+	if err := s.accessParent(id, dst); err != nil {
+		return nil, err
+	}
+	if _, ok := s.State.Files[src]; !ok && !s.dirExists(src) {
+		return nil, notFound("file or directory", src)
+	}
+	if _, ok := s.State.Files[dst]; ok || s.dirExists(dst) {
+		return nil, alreadyExists("file", dst)
+	}
+	if isUnder(dst, src) {
+		return nil, invalidArgument("dst", "cannot move %s into itself", src)
+	}
+	if err := s.checkParents(dst); err != nil {
+		return nil, err
+	}
+	if err := s.checkMove(src, dst); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(struct {
+		Src string `json:"src"`
+		Dst string `json:"dst"`
+	}{
+		Src: src,
+		Dst: dst,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "RENAME_FILE",
+		Data: payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyRename(src, dst)
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+func (s *Server) Mkdir(ctx context.Context, req *pb.FileRequest) (*pb.Ack, error) {
+	dir := cleanPath(req.Filename)
+	if dir == "" {
+		return &pb.Ack{Ok: true}, nil
+	}
+	if reserved(dir) {
+		return nil, invalidArgument("filename", "invalid directory name %q", req.Filename)
+	}
+
+	id := Caller(ctx)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if _, ok := s.State.Files[dir]; ok {
+		return nil, alreadyExists("file", dir)
+	}
+	if s.dirExists(dir) {
+		return &pb.Ack{Ok: true}, nil
+	}
+	if err := s.accessParent(id, dir); err != nil {
+		return nil, err
+	}
+	if err := s.checkParents(dir); err != nil {
+		return nil, err
+	}
+
+	// directories without an owner are logged as just their name
+	perm := newPerm(id, DefaultDirMode)
+	var dirJSON []byte
+	var err error
+	if perm == nil {
+		dirJSON, err = json.Marshal(dir)
+	} else {
+		dirJSON, err = json.Marshal(struct {
+			Dir  string `json:"dir"`
+			Perm *Perm  `json:"perm"`
+		}{
+			Dir:  dir,
+			Perm: perm,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "MKDIR",
+		Data: dirJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.State.Dirs[dir] = true
+	if perm != nil {
+		s.State.Perms[dir] = *perm
+	}
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// applyDelete forgets a file or directory.
+// Caller must hold the state write lock.
+func (s *Server) applyDelete(name string) {
+	s.account(name, -1)
+	delete(s.State.Files, name)
+	delete(s.State.Dirs, name)
+	delete(s.State.Replication, name)
+	delete(s.State.Keys, name)
+	delete(s.State.Perms, name)
+	delete(s.State.DirQuotas, name)
+	delete(s.State.DirUsage, name)
+}
+
+// applyRename moves a file, or a directory and everything below it.
+// Caller must hold the state write lock.
+func (s *Server) applyRename(src, dst string) {
+	var moved []string
+	move := func(from, to string) {
+		s.account(from, -1)
+		moved = append(moved, to)
+		s.State.Files[to] = s.State.Files[from]
+		delete(s.State.Files, from)
+		if rf, ok := s.State.Replication[from]; ok {
+			s.State.Replication[to] = rf
+			delete(s.State.Replication, from)
+		}
+		if key, ok := s.State.Keys[from]; ok {
+			s.State.Keys[to] = key
+			delete(s.State.Keys, from)
+		}
+		if p, ok := s.State.Perms[from]; ok {
+			s.State.Perms[to] = p
+			delete(s.State.Perms, from)
+		}
+	}
+
+	if _, ok := s.State.Files[src]; ok {
+		move(src, dst)
+		s.account(dst, 1)
+		return
+	}
+
+	for name := range s.State.Files {
+		if isUnder(name, src) {
+			move(name, dst+strings.TrimPrefix(name, src))
+		}
+	}
+	for name := range s.State.Dirs {
+		if name == src || isUnder(name, src) {
+			delete(s.State.Dirs, name)
+			s.State.Dirs[dst+strings.TrimPrefix(name, src)] = true
+		}
+	}
+	for name, p := range s.State.Perms {
+		if name == src || isUnder(name, src) {
+			delete(s.State.Perms, name)
+			s.State.Perms[dst+strings.TrimPrefix(name, src)] = p
+		}
+	}
+	s.moveQuotas(src, dst)
+
+	// the files count again at their new paths
+	for _, name := range moved {
+		s.account(name, 1)
+	}
+}
+
+// dirExists reports whether dir was created explicitly or is implied by
+// a file or directory below it. Caller must hold the state lock.
+func (s *Server) dirExists(dir string) bool {
+	return dir == "" || s.State.Dirs[dir] || s.dirHasChildren(dir)
+}
+
+// checkParents refuses a name below a file: every directory above it must
+// be one. Caller must hold the state lock.
+func (s *Server) checkParents(name string) error {
+	for dir := parent(name); dir != ""; dir = parent(dir) {
+		if _, ok := s.State.Files[dir]; ok {
+			return failedPrecondition("NOT_A_DIRECTORY", dir, "%s: not a directory", dir)
+		}
+	}
+	return nil
+}
+
+func (s *Server) dirHasChildren(dir string) bool {
+	for name := range s.State.Files {
+		if isUnder(name, dir) {
+			return true
+		}
+	}
+	for name := range s.State.Dirs {
+		if isUnder(name, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileInfo summarises a file. Caller must hold the state lock.
+func (s *Server) fileInfo(name string, chunks map[int]ChunkMetadata) *pb.FileInfo {
+	info := &pb.FileInfo{
+		Filename:    name,
+		Size:        fileSize(chunks),
+		NumChunks:   int32(len(chunks)),
+		Replication: int32(s.replicationFor(name)),
+	}
+	for _, c := range chunks {
+		info.StoredSize += c.stored()
+	}
+	info.KeyId = s.State.Keys[name].KeyId
+
+	// a packed file has the replicas of its container
+	if c, ok := chunks[0]; ok && c.packed() {
+		info.Packed = true
+		info.Replication = int32(s.replicationFor(c.Container))
+	}
+	return s.withPerm(info)
+}
+
+// chunkAddresses copies chunks with replica locations resolved to node
+// addresses. Caller must hold the state lock.
+func (s *Server) chunkAddresses(chunks map[int]ChunkMetadata) map[int]ChunkMetadata {
+	res := make(map[int]ChunkMetadata, len(chunks))
+	for idx, c := range chunks {
+		c.Nodes = s.replicaAddresses(c.Nodes)
+		res[idx] = c
+	}
+	return res
+}
+
+func (s *Server) deleteReplicas(chunks map[int]ChunkMetadata) {
+	for _, c := range chunks {
+		for _, addr := range c.Nodes {
+			if err := s.deleteChunk(addr, c.ChunkId); err != nil {
+				log.Printf("Failed to delete chunk %s on %s: %v", c.ChunkId, addr, err)
+			}
+		}
+	}
+}
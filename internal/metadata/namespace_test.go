@@ -0,0 +1,72 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNamespaceOperations(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	mustMkdir(t, ctx, s, "/logs")
+	for _, name := range []string{"logs/a.txt", "logs/old/b.txt", "top.txt"} {
+		mustCreate(t, ctx, s, &pb.FileRequest{Filename: name})
+	}
+
+	list, err := s.ListFiles(ctx, &pb.ListRequest{Prefix: "logs"})
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(list.Files) != 2 || list.Files[0].Filename != "logs/a.txt" || !list.Files[1].IsDir {
+		t.Fatalf("unexpected listing: %v", list.Files)
+	}
+
+	// a directory implied by its files is still taken, and nothing goes
+	// below a file
+	if _, err := s.CreateFile(ctx, &pb.FileRequest{Filename: "logs/old"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("CreateFile over an implied directory: got %v, want AlreadyExists", err)
+	}
+	if _, err := s.CreateFile(ctx, &pb.FileRequest{Filename: "top.txt/x"}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("CreateFile below a file: got %v, want FailedPrecondition", err)
+	}
+	if _, err := s.Mkdir(ctx, &pb.FileRequest{Filename: "top.txt/d/e"}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Mkdir below a file: got %v, want FailedPrecondition", err)
+	}
+	if _, err := s.RenameFile(ctx, &pb.RenameRequest{Src: "logs/a.txt", Dst: "top.txt/a.txt"}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("RenameFile below a file: got %v, want FailedPrecondition", err)
+	}
+
+	if _, err := s.DeleteFile(ctx, &pb.FileRequest{Filename: "logs"}); err == nil {
+		t.Fatal("deleting a non-empty directory should fail")
+	}
+
+	if _, err := s.RenameFile(ctx, &pb.RenameRequest{Src: "logs", Dst: "archive"}); err != nil {
+		t.Fatalf("RenameFile failed: %v", err)
+	}
+	if _, err := s.DeleteFile(ctx, &pb.FileRequest{Filename: "top.txt"}); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+
+	// Replay must reproduce the same namespace
+	s2 := replay(s)
+
+	for _, st := range []*State{s.State, s2.State} {
+		if _, ok := st.Files["archive/old/b.txt"]; !ok {
+			t.Fatal("renamed file missing")
+		}
+		if _, ok := st.Files["logs/a.txt"]; ok {
+			t.Fatal("old name still present after rename")
+		}
+		if _, ok := st.Files["top.txt"]; ok {
+			t.Fatal("deleted file still present")
+		}
+		if !st.Dirs["archive"] {
+			t.Fatal("renamed directory missing")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	"time"
+)
+
+// Options holds the tunables of a metadata Server.
+// Zero values fall back to the defaults from DefaultOptions.
+type Options struct {
+	WALPath             string
+	SnapshotPath        string
+	SnapshotInterval    time.Duration
+	NodeTTL             time.Duration // heartbeat silence after which a node is suspect
+	NodeDeadAfter       time.Duration // heartbeat silence after which a node is dead
+	CleanupInterval     time.Duration
+	ReplicationInterval time.Duration
+	ReplicationFactor   int
+	Placement           string // one of the Placement* policies
+	HighWaterPercent    int    // disk usage above which nodes get no new replicas
+	MaxReplications     int    // concurrent replica copies or trims
+	NodeBandwidthMB     int    // per-node replication bandwidth in MB/s, 0 = unlimited
+	BalanceInterval     time.Duration
+	BalanceThreshold    float64 // allowed distance from mean utilisation, 0.1 = 10%
+	BalanceMaxBytes     int64   // bytes moved per balancing run
+	ChunkTokenTTL       time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		WALPath:             "metadata.wal",
+		SnapshotPath:        "metadata.snapshot",
+		NodeTTL:             10 * time.Second,
+		NodeDeadAfter:       60 * time.Second,
+		CleanupInterval:     5 * time.Second,
+		ReplicationInterval: 10 * time.Second,
+		ReplicationFactor:   common.ReplicationFactor,
+		Placement:           PlacementSpread,
+		HighWaterPercent:    90,
+		MaxReplications:     4,
+		BalanceThreshold:    0.1,
+		BalanceMaxBytes:     10 << 30,
+		ChunkTokenTTL:       10 * time.Minute,
+	}
+}
+
+// OptionsFromConfig maps config/metadata.yaml onto Options.
+func OptionsFromConfig(cfg common.MetadataConfig) Options {
+	return Options{
+		WALPath:           cfg.WAL.Path,
+		SnapshotPath:      cfg.Snapshot.Path,
+		SnapshotInterval:  time.Duration(cfg.Snapshot.IntervalSeconds) * time.Second,
+		NodeTTL:           time.Duration(cfg.Heartbeat.TTLSeconds) * time.Second,
+		NodeDeadAfter:     time.Duration(cfg.Heartbeat.DeadSeconds) * time.Second,
+		CleanupInterval:   time.Duration(cfg.Heartbeat.CleanupIntervalSeconds) * time.Second,
+		ReplicationFactor: cfg.ReplicationFactor,
+		Placement:         cfg.Placement.Policy,
+		HighWaterPercent:  cfg.Placement.HighWaterPercent,
+
+		ReplicationInterval: time.Duration(cfg.Replication.IntervalSeconds) * time.Second,
+		MaxReplications:     cfg.Replication.MaxConcurrent,
+		NodeBandwidthMB:     cfg.Replication.NodeBandwidthMB,
+
+		BalanceInterval:  time.Duration(cfg.Balancer.IntervalSeconds) * time.Second,
+		BalanceThreshold: float64(cfg.Balancer.ThresholdPercent) / 100,
+		BalanceMaxBytes:  int64(cfg.Balancer.MaxMoveMB) << 20,
+
+		ChunkTokenTTL: time.Duration(cfg.Auth.ChunkTokenSeconds) * time.Second,
+	}.withDefaults()
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.WALPath == "" {
+		o.WALPath = d.WALPath
+	}
+	if o.SnapshotPath == "" {
+		o.SnapshotPath = d.SnapshotPath
+	}
+	if o.NodeTTL <= 0 {
+		o.NodeTTL = d.NodeTTL
+	}
+	if o.NodeDeadAfter <= 0 {
+		o.NodeDeadAfter = d.NodeDeadAfter
+	}
+	if o.NodeDeadAfter < o.NodeTTL {
+		o.NodeDeadAfter = o.NodeTTL
+	}
+	if o.CleanupInterval <= 0 {
+		o.CleanupInterval = d.CleanupInterval
+	}
+	if o.ReplicationInterval <= 0 {
+		o.ReplicationInterval = d.ReplicationInterval
+	}
+	if o.ReplicationFactor <= 0 {
+		o.ReplicationFactor = d.ReplicationFactor
+	}
+	if o.Placement == "" {
+		o.Placement = d.Placement
+	}
+	if o.HighWaterPercent <= 0 {
+		o.HighWaterPercent = d.HighWaterPercent
+	}
+	if o.MaxReplications <= 0 {
+		o.MaxReplications = d.MaxReplications
+	}
+	if o.BalanceThreshold <= 0 {
+		o.BalanceThreshold = d.BalanceThreshold
+	}
+	if o.BalanceMaxBytes <= 0 {
+		o.BalanceMaxBytes = d.BalanceMaxBytes
+	}
+	if o.ChunkTokenTTL <= 0 {
+		o.ChunkTokenTTL = d.ChunkTokenTTL
+	}
+	return o
+}
@@ -0,0 +1,25 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	"testing"
+	"time"
+)
+
+func TestOptionsFromConfig(t *testing.T) {
+	var cfg common.MetadataConfig
+	cfg.WAL.Path = "custom.wal"
+	cfg.Heartbeat.TTLSeconds = 30
+
+	opts := OptionsFromConfig(cfg)
+
+	if opts.WALPath != "custom.wal" {
+		t.Fatalf("wrong WAL path: got %s", opts.WALPath)
+	}
+	if opts.NodeTTL != 30*time.Second {
+		t.Fatalf("wrong node TTL: got %v", opts.NodeTTL)
+	}
+	if opts.ReplicationFactor != common.ReplicationFactor {
+		t.Fatalf("expected default replication factor, got %d", opts.ReplicationFactor)
+	}
+}
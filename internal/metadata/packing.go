@@ -0,0 +1,373 @@
+package metadata
+
+import (
+	"DFS_GO/internal/common"
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// containerDir holds the containers small files are packed into. Each
+// container is a file of one chunk, so it is replicated, balanced and
+// healed like any other; clients neither see nor touch it.
+const containerDir = ".packed"
+
+// containerGrace keeps a new container from being collected as empty
+// before the client has packed its files into it.
+const containerGrace = time.Minute
+
+func containerPath(chunkId string) string {
+	return containerDir + "/" + chunkId
+}
+
+// reserved reports whether a path belongs to the server.
+func reserved(name string) bool {
+	return name == containerDir || isUnder(name, containerDir)
+}
+
+// packedFile is where a packed file's bytes are in its container.
+type packedFile struct {
+	Filename string `json:"filename"`
+	Offset   int64  `json:"offset"`
+	Length   int64  `json:"length"`
+	Perm     *Perm  `json:"perm,omitempty"` // of a new file; nil keeps them
+}
+
+// chunkNodes returns the nodes holding a chunk's bytes: its own replicas,
+// or its container's when packed. Caller must hold the state lock.
+func (s *Server) chunkNodes(c ChunkMetadata) []string {
+	if c.packed() {
+		return s.State.Files[c.Container][0].Nodes
+	}
+	return c.Nodes
+}
+
+// AllocateContainer places a new container chunk that a client is about to
+// fill with small files.
+func (s *Server) AllocateContainer(ctx context.Context, req *pb.AllocateContainerRequest) (*pb.ChunkMetadata, error) {
+	if !common.ValidChunkId(req.ChunkId) {
+		return nil, invalidArgument("chunk_id", "invalid container ID %q", req.ChunkId)
+	}
+	if req.Size <= 0 || req.Replication < 0 {
+		return nil, invalidArgument("size", "invalid container size %d or replication %d", req.Size, req.Replication)
+	}
+	name := containerPath(req.ChunkId)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	id := Caller(ctx)
+	var meta ChunkMetadata
+	var err error
+	switch c, exists := s.State.Files[name][0]; {
+	case exists && len(req.ExcludeNodes) > 0 && !s.containerUsed(name):
+		// the client could store it on none of its nodes
+		if err := s.checkContainerOwner(id, name, req.ChunkId); err != nil {
+			return nil, err
+		}
+		meta, err = s.reallocateChunk(name, 0, c, req.ExcludeNodes)
+	case exists || (s.ChunkKey != nil && s.chunkInUse(req.ChunkId)):
+		return nil, alreadyExists("container", req.ChunkId)
+	default:
+		// the allocating user alone may pack files into it
+		if err := s.createFile(name, int(req.Replication), FileKey{}, newPerm(id, DefaultFileMode)); err != nil {
+			return nil, err
+		}
+		meta, err = s.allocateChunk(name, 0, ChunkMetadata{ChunkId: req.ChunkId, Size: req.Size}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := s.chunkInfo(meta)
+	info.Token = s.chunkToken(common.ChunkWrite, meta.ChunkId, 0, 0)
+	return info, nil
+}
+
+// containerUsed reports whether files are packed in a container.
+// Caller must hold the state lock.
+func (s *Server) containerUsed(container string) bool {
+	for _, chunks := range s.State.Files {
+		if c, ok := chunks[0]; ok && c.Container == container {
+			return true
+		}
+	}
+	return false
+}
+
+// checkContainerOwner refuses a container to anyone but the user who
+// allocated it. Caller must hold the state lock.
+func (s *Server) checkContainerOwner(id Identity, container, containerId string) error {
+	if owner := s.State.Perms[container].Owner; owner != id.User {
+		return status.Errorf(codes.PermissionDenied, "container %s belongs to another user", containerId)
+	}
+	return nil
+}
+
+// PackFiles creates files whose bytes are slices of a container the client
+// has written. Either all the files are created or none is.
+func (s *Server) PackFiles(ctx context.Context, req *pb.PackFilesRequest) (*pb.Ack, error) {
+	container := containerPath(req.ContainerId)
+	id := Caller(ctx)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	c, ok := s.State.Files[container][0]
+	if !ok {
+		return nil, notFound("container", req.ContainerId)
+	}
+	if err := s.checkContainerOwner(id, container, req.ContainerId); err != nil {
+		return nil, err
+	}
+
+	// slices of the container already taken, by these files or earlier ones
+	var taken []packedFile
+	for name, chunks := range s.State.Files {
+		if p, ok := chunks[0]; ok && p.Container == container {
+			taken = append(taken, packedFile{Filename: name, Offset: p.Offset, Length: p.Size})
+		}
+	}
+
+	files := make([]packedFile, 0, len(req.Files))
+	adds := make([]growth, 0, len(req.Files))
+	rf := int64(s.replicationFor(container))
+	seen := make(map[string]bool)
+	for _, f := range req.Files {
+		name := cleanPath(f.Filename)
+		if name == "" || reserved(name) {
+			return nil, invalidArgument("files.filename", "invalid filename %q", f.Filename)
+		}
+		if _, exists := s.State.Files[name]; exists || seen[name] {
+			return nil, alreadyExists("file", name)
+		}
+		if s.dirExists(name) {
+			return nil, alreadyExists("directory", name)
+		}
+		if err := s.accessParent(id, name); err != nil {
+			return nil, err
+		}
+		if err := s.checkParents(name); err != nil {
+			return nil, err
+		}
+		for prev := range seen {
+			if isUnder(name, prev) || isUnder(prev, name) {
+				return nil, invalidArgument("files.filename", "%s and %s cannot both be files", prev, name)
+			}
+		}
+		if f.Offset < 0 || f.Length <= 0 || f.Offset+f.Length > c.Size {
+			return nil, invalidArgument("files.length", "%s: range %d+%d outside container", name, f.Offset, f.Length)
+		}
+		for _, t := range taken {
+			if f.Offset < t.Offset+t.Length && t.Offset < f.Offset+f.Length {
+				return nil, invalidArgument("files.offset", "%s: range %d+%d overlaps %s", name, f.Offset, f.Length, t.Filename)
+			}
+		}
+		seen[name] = true
+		pf := packedFile{
+			Filename: name,
+			Offset:   f.Offset,
+			Length:   f.Length,
+			Perm:     newPerm(id, DefaultFileMode),
+		}
+		files = append(files, pf)
+		taken = append(taken, pf)
+		adds = append(adds, growth{name: name, bytes: f.Length * rf, files: 1})
+	}
+	if err := s.checkQuota(id.User, adds...); err != nil {
+		return nil, err
+	}
+
+	if err := s.packFiles(container, files); err != nil {
+		return nil, err
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+// packFiles points files at their bytes in a container.
+// Caller must hold the state write lock.
+func (s *Server) packFiles(container string, files []packedFile) error {
+	payload, err := json.Marshal(struct {
+		Container string       `json:"container"`
+		Files     []packedFile `json:"files"`
+	}{
+		Container: container,
+		Files:     files,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "PACK_FILES",
+		Data: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.applyPack(container, files)
+	return nil
+}
+
+// applyPack replaces each file's contents with its slice of the container.
+// Caller must hold the state write lock.
+func (s *Server) applyPack(container string, files []packedFile) {
+	c, ok := s.State.Files[container][0]
+	if !ok {
+		return
+	}
+	for _, f := range files {
+		s.account(f.Filename, -1)
+		s.State.Files[f.Filename] = map[int]ChunkMetadata{
+			0: {
+				ChunkId:   c.ChunkId,
+				Size:      f.Length,
+				Container: container,
+				Offset:    f.Offset,
+			},
+		}
+		if f.Perm != nil {
+			s.State.Perms[f.Filename] = *f.Perm
+		}
+		s.account(f.Filename, 1)
+	}
+}
+
+// compactContainers reclaims the space of deleted packed files: empty
+// containers are deleted, and one mostly dead container is rewritten in
+// the background.
+func (s *Server) compactContainers() {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	live := make(map[string]int64)
+	for _, chunks := range s.State.Files {
+		if c, ok := chunks[0]; ok && c.packed() {
+			live[c.Container] += c.Size
+		}
+	}
+
+	rewrite := ""
+	for name, chunks := range s.State.Files {
+		c, ok := chunks[0]
+		if !isUnder(name, containerDir) || !ok || s.compacting[name] ||
+			time.Since(c.allocated) < containerGrace {
+			continue
+		}
+
+		switch {
+		case live[name] == 0:
+			if err := s.removeFile(name); err != nil {
+				log.Printf("Failed to delete empty container %s: %v", name, err)
+			}
+		case live[name]*2 < c.Size && rewrite == "":
+			rewrite = name
+		}
+	}
+
+	if rewrite != "" {
+		if s.compacting == nil {
+			s.compacting = make(map[string]bool)
+		}
+		s.compacting[rewrite] = true
+		go s.rewriteContainer(rewrite)
+	}
+}
+
+// rewriteContainer copies the files still packed in a container into a new
+// one, leaving the old container empty for the next compaction pass.
+func (s *Server) rewriteContainer(name string) {
+	defer func() {
+		s.State.Mu.Lock()
+		delete(s.compacting, name)
+		s.State.Mu.Unlock()
+	}()
+
+	s.State.Mu.RLock()
+	old := s.State.Files[name][0]
+	addrs := s.replicaAddresses(old.Nodes)
+	var files []packedFile
+	for fname, chunks := range s.State.Files {
+		if c, ok := chunks[0]; ok && c.Container == name {
+			files = append(files, packedFile{Filename: fname, Offset: c.Offset, Length: c.Size})
+		}
+	}
+	s.State.Mu.RUnlock()
+
+	var data []byte
+	var err error
+	for _, addr := range addrs {
+		if data, err = s.fetchChunk(addr, old.ChunkId); err == nil {
+			break
+		}
+	}
+	if data == nil {
+		log.Printf("Failed to read container %s for compaction: %v", name, err)
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Offset < files[j].Offset })
+	var packed []byte
+	moved := make([]packedFile, 0, len(files))
+	for _, f := range files {
+		if f.Offset+f.Length > int64(len(data)) {
+			log.Printf("Container %s is shorter than %s expects, not compacting", name, f.Filename)
+			return
+		}
+		moved = append(moved, packedFile{Filename: f.Filename, Offset: int64(len(packed)), Length: f.Length})
+		packed = append(packed, data[f.Offset:f.Offset+f.Length]...)
+	}
+
+	chunkId := common.ChunkId(fmt.Sprintf("%s@%d", name, time.Now().UnixNano()), 0)
+	container := containerPath(chunkId)
+
+	s.State.Mu.Lock()
+	err = s.createFile(container, s.State.Replication[name], FileKey{}, nil)
+	var meta ChunkMetadata
+	if err == nil {
+		meta, err = s.allocateChunk(container, 0, ChunkMetadata{ChunkId: chunkId, Size: int64(len(packed))}, nil)
+	}
+	targets := s.replicaAddresses(meta.Nodes)
+	s.State.Mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to allocate container for compacting %s: %v", name, err)
+		return
+	}
+
+	stored := 0
+	for _, addr := range targets {
+		if err := s.StoreChunk(addr, chunkId, packed); err != nil {
+			log.Printf("Failed to store container %s on %s: %v", chunkId, addr, err)
+			continue
+		}
+		stored++
+	}
+	if stored == 0 {
+		// the new container stays empty and is collected later
+		return
+	}
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	// files deleted or replaced meanwhile stay as they are
+	var still []packedFile
+	for i, f := range files {
+		if c, ok := s.State.Files[f.Filename][0]; ok && c.Container == name && c.Offset == f.Offset {
+			still = append(still, moved[i])
+		}
+	}
+	if err := s.packFiles(container, still); err != nil {
+		log.Printf("Failed to move files out of container %s: %v", name, err)
+		return
+	}
+	log.Printf("Compacted container %s: %d files, %d of %d bytes kept", name, len(still), len(packed), old.Size)
+}
@@ -0,0 +1,156 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSmallFilePacking(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+
+	mustRegister(t, s, "dn1", "localhost:6001")
+	container, err := s.AllocateContainer(ctx, &pb.AllocateContainerRequest{ChunkId: chunkID("box"), Size: 10})
+	if err != nil {
+		t.Fatalf("AllocateContainer failed: %v", err)
+	}
+	if len(container.Nodes) != 1 || container.Nodes[0] != "localhost:6001" {
+		t.Fatalf("unexpected container placement: %v", container.Nodes)
+	}
+
+	pack := func(files ...*pb.PackedFile) error {
+		_, err := s.PackFiles(ctx, &pb.PackFilesRequest{ContainerId: chunkID("box"), Files: files})
+		return err
+	}
+	if err := pack(&pb.PackedFile{Filename: "small/a", Offset: 0, Length: 4},
+		&pb.PackedFile{Filename: "small/b", Offset: 4, Length: 6}); err != nil {
+		t.Fatalf("PackFiles failed: %v", err)
+	}
+	if err := pack(&pb.PackedFile{Filename: "small/c", Offset: 8, Length: 4}); err == nil {
+		t.Fatal("a range beyond the container should be rejected")
+	}
+	if err := pack(&pb.PackedFile{Filename: "small/c", Offset: 0, Length: 1},
+		&pb.PackedFile{Filename: "small/a", Offset: 0, Length: 1}); err == nil {
+		t.Fatal("packing over an existing file should be rejected")
+	}
+	if err := pack(&pb.PackedFile{Filename: "small", Offset: 0, Length: 1}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("packing over an implied directory: got %v, want AlreadyExists", err)
+	}
+	if err := pack(&pb.PackedFile{Filename: "small/a/x", Offset: 0, Length: 1}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("packing below a file: got %v, want FailedPrecondition", err)
+	}
+	if err := pack(&pb.PackedFile{Filename: "small/c", Offset: 0, Length: 1},
+		&pb.PackedFile{Filename: "small/c/d", Offset: 1, Length: 1}); err == nil {
+		t.Fatal("packing a file below another of the batch should be rejected")
+	}
+	if _, ok := s.State.Files["small/c"]; ok {
+		t.Fatal("a rejected batch should create no files")
+	}
+
+	meta, err := s.GetFile(ctx, &pb.FileRequest{Filename: "small/b"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	c := meta.Chunks[0]
+	if !c.Packed || c.ChunkId != chunkID("box") || c.Offset != 4 || c.Size != 6 ||
+		len(c.Nodes) != 1 || c.Nodes[0] != "localhost:6001" {
+		t.Fatalf("unexpected packed chunk: %v", c)
+	}
+
+	// neither the container chunk nor a new chunk can be written through
+	// a packed file
+	for _, idx := range []int32{0, 1} {
+		_, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{ChunkId: chunkID("box"), Filename: "small/b", ChunkIndex: idx, Size: 6})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("AllocateChunk %d of a packed file: got %v, want FailedPrecondition", idx, err)
+		}
+	}
+
+	list, err := s.ListFiles(ctx, &pb.ListRequest{Recursive: true})
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	for _, f := range list.Files {
+		if reserved(f.Filename) {
+			t.Fatalf("container visible in listing: %s", f.Filename)
+		}
+	}
+	if _, err := s.CreateFile(ctx, &pb.FileRequest{Filename: ".packed/x"}); err == nil {
+		t.Fatal("creating a file among the containers should fail")
+	}
+
+	s2 := replay(s)
+	if got := s2.State.Files["small/a"][0]; got.Container != containerPath(chunkID("box")) || got.Size != 4 {
+		t.Fatalf("packed file not replayed: %+v", got)
+	}
+
+	// a container is collected once its files are gone
+	for _, name := range []string{"small/a", "small/b"} {
+		if _, err := s.DeleteFile(ctx, &pb.FileRequest{Filename: name}); err != nil {
+			t.Fatalf("DeleteFile %s failed: %v", name, err)
+		}
+	}
+	s.compactContainers()
+	if _, ok := s.State.Files[containerPath(chunkID("box"))]; !ok {
+		t.Fatal("a fresh container should be kept for its client")
+	}
+	c0 := s.State.Files[containerPath(chunkID("box"))][0]
+	c0.allocated = time.Time{}
+	s.State.Files[containerPath(chunkID("box"))][0] = c0
+	s.compactContainers()
+	if _, ok := s.State.Files[containerPath(chunkID("box"))]; ok {
+		t.Fatal("empty container not collected")
+	}
+}
+
+func TestPackFilesChecks(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	ctx := context.Background()
+	alice := context.WithValue(ctx, callerKey{}, Identity{User: "alice"})
+	bob := context.WithValue(ctx, callerKey{}, Identity{User: "bob"})
+
+	mustRegister(t, s, "dn1", "localhost:6001")
+	box := chunkID("box")
+	if _, err := s.AllocateContainer(alice, &pb.AllocateContainerRequest{ChunkId: box, Size: 10}); err != nil {
+		t.Fatalf("AllocateContainer failed: %v", err)
+	}
+	pack := func(ctx context.Context, files ...*pb.PackedFile) error {
+		_, err := s.PackFiles(ctx, &pb.PackFilesRequest{ContainerId: box, Files: files})
+		return err
+	}
+
+	// only the user who allocated a container may use it
+	if err := pack(bob, &pb.PackedFile{Filename: "/b", Offset: 0, Length: 4}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("PackFiles into another user's container: got %v, want PermissionDenied", err)
+	}
+	_, err := s.AllocateContainer(bob, &pb.AllocateContainerRequest{ChunkId: box, Size: 10, ExcludeNodes: []string{"localhost:6001"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("moving another user's container: got %v, want PermissionDenied", err)
+	}
+
+	// files cannot share bytes of the container
+	if err := pack(alice, &pb.PackedFile{Filename: "/a", Offset: 0, Length: 4}); err != nil {
+		t.Fatalf("PackFiles failed: %v", err)
+	}
+	if err := pack(alice, &pb.PackedFile{Filename: "/c", Offset: 2, Length: 4}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("packing over a packed file's bytes: got %v, want InvalidArgument", err)
+	}
+	if err := pack(alice, &pb.PackedFile{Filename: "/c", Offset: 4, Length: 3},
+		&pb.PackedFile{Filename: "/d", Offset: 6, Length: 4}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("packing overlapping files: got %v, want InvalidArgument", err)
+	}
+	if err := pack(alice, &pb.PackedFile{Filename: "/c", Offset: 4, Length: 6}); err != nil {
+		t.Fatalf("PackFiles next to a packed file: %v", err)
+	}
+
+	// the owner is journaled with the container
+	s2 := replay(s)
+	if owner := s2.State.Perms[containerPath(box)].Owner; owner != "alice" {
+		t.Fatalf("container owner after replay: %q, want alice", owner)
+	}
+}
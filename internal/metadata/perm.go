@@ -0,0 +1,224 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"path"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Permission bits, as in the rwx of each class of the mode
+const (
+	permRead  = 4
+	permWrite = 2
+	permExec  = 1
+)
+
+// Modes of new files and directories
+const (
+	DefaultFileMode = 0644
+	DefaultDirMode  = 0755
+)
+
+// allows reports whether p grants every bit of want to id.
+func (p Perm) allows(id Identity, want uint32) bool {
+	bits := p.Mode & 7
+	switch {
+	case id.User == p.Owner:
+		bits = p.Mode >> 6 & 7
+	case p.Group != "" && id.inGroup(p.Group):
+		bits = p.Mode >> 3 & 7
+	}
+	return bits&want == want
+}
+
+// superuser reports whether id bypasses permissions: administrators do,
+// and everyone does on a server without authentication.
+func (s *Server) superuser(id Identity) bool {
+	return s.Auth == nil || s.Auth.Admins[id.User]
+}
+
+// parent returns the directory holding name; the root is "".
+func parent(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// access checks that id may search every directory above name and has
+// the want bits on name itself. Paths without permissions, such as
+// directories only implied by the files below them, have those of the
+// nearest directory above with some, or let everyone in when there is
+// none. Caller must hold the state lock.
+func (s *Server) access(id Identity, name string, want uint32) error {
+	if s.superuser(id) {
+		return nil
+	}
+	for dir := name; dir != ""; {
+		dir = parent(dir)
+		if p, ok := s.State.Perms[dir]; ok && !p.allows(id, permExec) {
+			return status.Errorf(codes.PermissionDenied, "/%s: permission denied", name)
+		}
+	}
+	if p, ok := s.inheritedPerm(name); ok && !p.allows(id, want) {
+		return status.Errorf(codes.PermissionDenied, "/%s: permission denied", name)
+	}
+	return nil
+}
+
+// inheritedPerm returns the permissions of name or, failing that, of the
+// nearest directory above it with some. Caller must hold the state lock.
+func (s *Server) inheritedPerm(name string) (Perm, bool) {
+	for {
+		if p, ok := s.State.Perms[name]; ok {
+			return p, true
+		}
+		if name == "" {
+			return Perm{}, false
+		}
+		name = parent(name)
+	}
+}
+
+// accessParent checks that id may add or remove entries in the directory
+// holding name. Caller must hold the state lock.
+func (s *Server) accessParent(id Identity, name string) error {
+	return s.access(id, parent(name), permWrite|permExec)
+}
+
+// newPerm is the permissions of a path id creates. Anonymous callers, on
+// a server without authentication, leave new paths open to everyone.
+func newPerm(id Identity, mode uint32) *Perm {
+	if id.User == "" {
+		return nil
+	}
+	p := &Perm{Owner: id.User, Mode: mode}
+	if len(id.Groups) > 0 {
+		p.Group = id.Groups[0]
+	}
+	return p
+}
+
+// withPerm fills in the owner, group and mode of a file or directory.
+// Caller must hold the state lock.
+func (s *Server) withPerm(info *pb.FileInfo) *pb.FileInfo {
+	if p, ok := s.State.Perms[info.Filename]; ok {
+		info.Owner, info.Group, info.Mode = p.Owner, p.Group, p.Mode
+	}
+	return info
+}
+
+func (s *Server) Chmod(ctx context.Context, req *pb.ChmodRequest) (*pb.Ack, error) {
+	name := cleanPath(req.Filename)
+	if reserved(name) || req.Mode > 0777 {
+		return nil, invalidArgument("mode", "invalid chmod of %q to %o", req.Filename, req.Mode)
+	}
+	id := Caller(ctx)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	p, err := s.changePerm(id, name)
+	if err != nil {
+		return nil, err
+	}
+	p.Mode = req.Mode
+	if err := s.setPerm(name, p); err != nil {
+		return nil, err
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+// Chown changes the owner, which only administrators may do, or the
+// group, which the owner may set to one of their own groups.
+func (s *Server) Chown(ctx context.Context, req *pb.ChownRequest) (*pb.Ack, error) {
+	name := cleanPath(req.Filename)
+	if reserved(name) || (req.Owner == "" && req.Group == "") {
+		return nil, invalidArgument("owner", "invalid chown of %q", req.Filename)
+	}
+	id := Caller(ctx)
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	p, err := s.changePerm(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Owner != "" && req.Owner != p.Owner && !s.superuser(id) {
+		return nil, status.Errorf(codes.PermissionDenied, "/%s: only an administrator can change the owner", name)
+	}
+	if req.Group != "" && !s.superuser(id) && !id.inGroup(req.Group) {
+		return nil, status.Errorf(codes.PermissionDenied, "/%s: %s is not in group %s", name, id.User, req.Group)
+	}
+	if req.Owner != "" {
+		p.Owner = req.Owner
+	}
+	if req.Group != "" {
+		p.Group = req.Group
+	}
+	if err := s.setPerm(name, p); err != nil {
+		return nil, err
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+// changePerm returns the current permissions of a path id wants to
+// change, which only its owner and administrators may. Nobody owns a path
+// without permissions, so only administrators can give it some.
+// Caller must hold the state lock.
+func (s *Server) changePerm(id Identity, name string) (Perm, error) {
+	if _, ok := s.State.Files[name]; !ok && !s.dirExists(name) {
+		return Perm{}, notFound("file or directory", name)
+	}
+	if err := s.access(id, name, 0); err != nil {
+		return Perm{}, err
+	}
+
+	p, ok := s.State.Perms[name]
+	if !ok {
+		p = Perm{Mode: 0777}
+	}
+	if !s.superuser(id) && id.User != p.Owner {
+		return Perm{}, status.Errorf(codes.PermissionDenied, "/%s: not the owner", name)
+	}
+	return p, nil
+}
+
+// setPerm records the permissions of a path.
+// Caller must hold the state write lock.
+func (s *Server) setPerm(name string, p Perm) error {
+	payload, err := json.Marshal(struct {
+		Path string `json:"path"`
+		Perm
+	}{
+		Path: name,
+		Perm: p,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "SET_PERM",
+		Data: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.applyPerm(name, p)
+	return nil
+}
+
+// applyPerm sets the permissions of a path, moving a file's usage to its
+// new owner. Caller must hold the state write lock.
+func (s *Server) applyPerm(name string, p Perm) {
+	s.account(name, -1)
+	s.State.Perms[name] = p
+	s.account(name, 1)
+}
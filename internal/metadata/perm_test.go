@@ -0,0 +1,86 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissions(t *testing.T) {
+	s := newTestServer(t, Options{})
+	s.Auth = &Auth{Admins: map[string]bool{"root": true}}
+	as := func(user string, groups ...string) context.Context {
+		return context.WithValue(context.Background(), callerKey{}, Identity{User: user, Groups: groups})
+	}
+	alice, bob, carol, root := as("alice", "eng"), as("bob", "eng"), as("carol", "ops"), as("root")
+
+	mustMkdir(t, root, s, "/team")
+	if _, err := s.Chown(root, &pb.ChownRequest{Filename: "/team", Owner: "alice", Group: "eng"}); err != nil {
+		t.Fatalf("Chown as root: %v", err)
+	}
+	if _, err := s.CreateFile(carol, &pb.FileRequest{Filename: "/team/x"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CreateFile in a directory carol cannot write: got %v", err)
+	}
+	if _, err := s.CreateFile(carol, &pb.FileRequest{Filename: "/team/implied/x"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CreateFile below an implied directory: got %v", err)
+	}
+	if _, err := s.CreateFile(alice, &pb.FileRequest{Filename: "/team/a"}); err != nil {
+		t.Fatalf("CreateFile as alice: %v", err)
+	}
+	info, err := s.StatFile(alice, &pb.FileRequest{Filename: "/team/a"})
+	if err != nil {
+		t.Fatalf("StatFile as alice: %v", err)
+	}
+	if info.Owner != "alice" || info.Group != "eng" || info.Mode != DefaultFileMode {
+		t.Fatalf("new file: owner %q group %q mode %o", info.Owner, info.Group, info.Mode)
+	}
+
+	// 0644: the group reads, others read, only the owner writes
+	if _, err := s.GetFile(bob, &pb.FileRequest{Filename: "/team/a"}); err != nil {
+		t.Fatalf("GetFile as a group member: %v", err)
+	}
+	if _, err := s.AllocateChunk(bob, &pb.AllocateChunkRequest{ChunkId: chunkID("c1"), Filename: "/team/a"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("AllocateChunk as a group member: got %v", err)
+	}
+	if _, err := s.Chmod(bob, &pb.ChmodRequest{Filename: "/team/a", Mode: 0666}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Chmod by someone else: got %v", err)
+	}
+	if _, err := s.Chmod(alice, &pb.ChmodRequest{Filename: "/team/a", Mode: 0640}); err != nil {
+		t.Fatalf("Chmod by the owner: %v", err)
+	}
+	if _, err := s.GetFile(carol, &pb.FileRequest{Filename: "/team/a"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("GetFile by others after chmod 640: got %v", err)
+	}
+	if _, err := s.Chown(alice, &pb.ChownRequest{Filename: "/team/a", Owner: "bob"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("giving a file away: got %v", err)
+	}
+	if _, err := s.Chown(alice, &pb.ChownRequest{Filename: "/team/a", Group: "ops"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Chown to a group alice is not in: got %v", err)
+	}
+
+	// a directory others cannot search hides everything below it
+	if _, err := s.Chmod(alice, &pb.ChmodRequest{Filename: "/team", Mode: 0750}); err != nil {
+		t.Fatalf("Chmod of a directory by its owner: %v", err)
+	}
+	if _, err := s.StatFile(carol, &pb.FileRequest{Filename: "/team/a"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("StatFile below an unsearchable directory: got %v", err)
+	}
+	if _, err := s.DeleteFile(bob, &pb.FileRequest{Filename: "/team/a"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("DeleteFile in a directory bob cannot write: got %v", err)
+	}
+	if _, err := s.RenameFile(alice, &pb.RenameRequest{Src: "/team", Dst: "/eng"}); err != nil {
+		t.Fatalf("RenameFile as alice: %v", err)
+	}
+
+	// permissions follow renames and survive a restart
+	r := replay(s)
+	if p := r.State.Perms["eng/a"]; p != (Perm{Owner: "alice", Group: "eng", Mode: 0640}) {
+		t.Fatalf("replayed file permissions: %+v", p)
+	}
+	if p := r.State.Perms["eng"]; p != (Perm{Owner: "alice", Group: "eng", Mode: 0750}) {
+		t.Fatalf("replayed directory permissions: %+v", p)
+	}
+}
@@ -1,40 +1,304 @@
 package metadata
 
-// PickNodes selects up to `replicationFactor` live nodes.
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Placement policies
+const (
+	// PlacementSpread puts each replica in a different zone, then rack, where possible.
+	PlacementSpread = "spread"
+	// PlacementLocalRemote puts the first replica on one rack and the rest
+	// together on a second rack, trading some spread for cheaper writes.
+	PlacementLocalRemote = "local-remote"
+	// PlacementRandom ignores failure domains.
+	PlacementRandom = "random"
+)
+
+func IsPlacementPolicy(p string) bool {
+	return p == PlacementSpread || p == PlacementLocalRemote || p == PlacementRandom
+}
+
+// PickNodes selects the IDs of up to n nodes for new replicas under the
+// given policy. existing lists the node IDs already holding a replica: they
+// are never picked again but count towards rack and zone spread.
 // nodes MUST contain only healthy nodes.
-// This function is replication-aware and must not be used for single-node placement.
-func PickNodes(nodes map[string]NodeStatus, n int) []string {
+func PickNodes(nodes map[string]NodeStatus, existing []string, n int, policy string) []string {
+	// replicas placed so far, in placement order
+	var placed []NodeStatus
+	taken := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		taken[id] = true
+		if node, ok := nodes[id]; ok {
+			placed = append(placed, node)
+		}
+	}
+
+	candidates := make([]string, 0, len(nodes))
+	for id := range nodes {
+		if !taken[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	weightedShuffle(candidates, nodes)
+
 	res := make([]string, 0, n)
-	for _, node := range nodes {
-		res = append(res, node.Address)
-		if len(res) == n {
-			break
+	for len(res) < n && len(candidates) > 0 {
+		best := 0
+		for i := 1; i < len(candidates); i++ {
+			if placementScore(policy, placed, nodes[candidates[i]]) < placementScore(policy, placed, nodes[candidates[best]]) {
+				best = i
+			}
 		}
+
+		res = append(res, candidates[best])
+		placed = append(placed, nodes[candidates[best]])
+		candidates = append(candidates[:best], candidates[best+1:]...)
 	}
 	return res
 }
 
-func PickReplicaNodes(nodes map[string]NodeStatus, rf int) []string {
-	return PickNodes(nodes, rf)
+func PickReplicaNodes(nodes map[string]NodeStatus, rf int, policy string) []string {
+	return PickNodes(nodes, nil, rf, policy)
 }
 
-func pickSource(nodes []string) string {
-	if len(nodes) == 0 {
-		return ""
+// weightedShuffle randomly orders ids so that nodes with more free space
+// and fewer active transfers tend to come first (Efraimidis-Spirakis).
+func weightedShuffle(ids []string, nodes map[string]NodeStatus) {
+	keys := make(map[string]float64, len(ids))
+	for _, id := range ids {
+		w := nodes[id].placementWeight()
+		if w <= 0 {
+			w = 1e-9
+		}
+		keys[id] = math.Pow(rand.Float64(), 1/w)
 	}
-	return nodes[0]
+	sort.SliceStable(ids, func(i, j int) bool {
+		return keys[ids[i]] > keys[ids[j]]
+	})
 }
 
-func pickTarget(all map[string]NodeStatus, existing []string) string {
-	exists := make(map[string]bool)
-	for _, n := range existing {
-		exists[n] = true
+// placementCandidates returns the nodes allowed to receive new replicas:
+// those live, in service and below the disk high-water mark.
+// Caller must hold the state lock.
+func (s *Server) placementCandidates() map[string]NodeStatus {
+	limit := float64(s.opts().HighWaterPercent) / 100
+	now := time.Now()
+	res := make(map[string]NodeStatus, len(s.State.Nodes))
+	for id, node := range s.State.Nodes {
+		if node.Liveness != NodeLive || node.draining() || node.inMaintenance(now) {
+			continue
+		}
+		if node.utilization() < limit {
+			res[id] = node
+		}
 	}
+	return res
+}
 
-	for _, node := range all {
-		if !exists[node.Address] {
-			return node.Address
+// placementScore ranks a candidate for the next replica; lower is better.
+func placementScore(policy string, placed []NodeStatus, c NodeStatus) int {
+	switch policy {
+	case PlacementRandom:
+		return 0
+	case PlacementLocalRemote:
+		switch {
+		case len(placed) == 0:
+			return 0
+		case len(placed) == 1:
+			if c.rackKey() != placed[0].rackKey() {
+				return 0
+			}
+			return 1
+		case c.rackKey() == placed[1].rackKey() && c.rackKey() != placed[0].rackKey():
+			return 0
+		case c.rackKey() != placed[0].rackKey():
+			return 1
+		default:
+			return 2
+		}
+	default:
+		score := 0
+		for _, p := range placed {
+			if p.Zone == c.Zone {
+				score += 1000
+			}
+			if p.rackKey() == c.rackKey() {
+				score++
+			}
+		}
+		return score
+	}
+}
+
+// pickSource picks a replica to copy from. Only live nodes qualify: a
+// suspect node may not answer, and a copy is only as good as its source.
+// Caller must hold the state lock.
+func (s *Server) pickSource(nodes []string) string {
+	for _, id := range nodes {
+		if node, ok := s.State.Nodes[id]; ok && node.Liveness == NodeLive {
+			return id
 		}
 	}
 	return ""
 }
+
+// liveReplica reports whether node id is live and still listed as holding
+// chunk. Caller must hold the state lock.
+func (s *Server) liveReplica(chunk ChunkMetadata, id string) bool {
+	node, ok := s.State.Nodes[id]
+	return ok && node.Liveness == NodeLive && containsNode(chunk.Nodes, id)
+}
+
+func pickTarget(all map[string]NodeStatus, existing []string, policy string) string {
+	picked := PickNodes(all, existing, 1, policy)
+	if len(picked) == 0 {
+		return ""
+	}
+	return picked[0]
+}
+
+// pickTrimVictim chooses which replica to drop from an over-replicated chunk.
+// Replicas on unregistered nodes go first since they are unreachable anyway,
+// then replicas on decommissioning nodes, then replicas sharing a rack with
+// another replica, then the replica on the fullest node. Replicas on dead
+// nodes are kept for when the node returns.
+// Caller must hold the state lock.
+func (s *Server) pickTrimVictim(nodes []string, usage map[string]int64) string {
+	racks := make(map[string]int)
+	for _, id := range nodes {
+		if node, ok := s.State.Nodes[id]; ok {
+			racks[node.rackKey()]++
+		}
+	}
+
+	victim, victimCrowd, victimNode := "", 0, NodeStatus{}
+	for _, id := range nodes {
+		node, ok := s.State.Nodes[id]
+		if !ok {
+			return id
+		}
+		if node.dead() {
+			continue
+		}
+		if node.draining() {
+			if !victimNode.draining() {
+				victim, victimCrowd, victimNode = id, 0, node
+			}
+			continue
+		}
+		if victimNode.draining() {
+			continue
+		}
+
+		crowd := racks[node.rackKey()]
+		if victim == "" || crowd > victimCrowd ||
+			(crowd == victimCrowd && fuller(node, usage[id], victimNode, usage[victim])) {
+			victim, victimCrowd, victimNode = id, crowd, node
+		}
+	}
+	return victim
+}
+
+// fuller compares two nodes by reported disk utilisation, falling back to
+// the bytes metadata places on them when utilisation is equal or unknown.
+func fuller(a NodeStatus, aBytes int64, b NodeStatus, bBytes int64) bool {
+	if a.utilization() != b.utilization() {
+		return a.utilization() > b.utilization()
+	}
+	return aBytes > bBytes
+}
+
+// nodeUsage sums the bytes each node stores according to chunk metadata.
+// Caller must hold the state lock.
+func (s *Server) nodeUsage() map[string]int64 {
+	usage := make(map[string]int64)
+	for _, chunks := range s.State.Files {
+		for _, c := range chunks {
+			for _, id := range c.Nodes {
+				usage[id] += c.stored()
+			}
+		}
+	}
+	return usage
+}
+
+// liveReplicas counts the replicas held by registered nodes that are not dead.
+// Caller must hold the state lock.
+func (s *Server) liveReplicas(nodes []string) int {
+	live := 0
+	for _, id := range nodes {
+		if node, ok := s.State.Nodes[id]; ok && !node.dead() {
+			live++
+		}
+	}
+	return live
+}
+
+// reachableReplicas returns the replicas not on dead nodes.
+// Caller must hold the state lock.
+func (s *Server) reachableReplicas(nodes []string) []string {
+	res := make([]string, 0, len(nodes))
+	for _, id := range nodes {
+		if node, ok := s.State.Nodes[id]; !ok || !node.dead() {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// presentReplicas counts the replicas trimming may remove: all but those
+// on dead nodes. Caller must hold the state lock.
+func (s *Server) presentReplicas(nodes []string) int {
+	return len(s.reachableReplicas(nodes))
+}
+
+// usableReplicas counts the replicas that count towards the replication
+// factor: all but those on decommissioning or dead nodes.
+// Caller must hold the state lock.
+func (s *Server) usableReplicas(nodes []string) int {
+	usable := 0
+	for _, id := range nodes {
+		if node, ok := s.State.Nodes[id]; !ok || !node.draining() && !node.dead() {
+			usable++
+		}
+	}
+	return usable
+}
+
+// replicaAddresses resolves replica locations to the addresses of the
+// nodes that can serve them, leaving out unknown and dead nodes.
+// Caller must hold the state lock.
+func (s *Server) replicaAddresses(ids []string) []string {
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if node, ok := s.State.Nodes[id]; ok && !node.dead() {
+			res = append(res, node.Address)
+		}
+	}
+	return res
+}
+
+// addressOf returns the address of a node, or "" when it is unknown.
+// Caller must hold the state lock.
+func (s *Server) addressOf(id string) string {
+	return s.State.Nodes[id].Address
+}
+
+// nodeIDFor maps a replica location to a node ID. WAL entries written
+// before locations were keyed by node ID hold the node's address instead.
+// Caller must hold the state lock.
+func (s *Server) nodeIDFor(loc string) string {
+	if _, ok := s.State.Nodes[loc]; ok {
+		return loc
+	}
+	for id, node := range s.State.Nodes {
+		if node.Address == loc {
+			return id
+		}
+	}
+	return loc
+}
@@ -0,0 +1,100 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRackAwarePlacement(t *testing.T) {
+	nodes := map[string]NodeStatus{
+		"dn1": {Address: "a1", Rack: "r1"},
+		"dn2": {Address: "a2", Rack: "r1"},
+		"dn3": {Address: "b1", Rack: "r2"},
+		"dn4": {Address: "b2", Rack: "r2"},
+		"dn5": {Address: "c1", Rack: "r3"},
+	}
+	rackOf := func(id string) string {
+		return nodes[id].Rack
+	}
+
+	for i := 0; i < 20; i++ {
+		picked := PickNodes(nodes, nil, 3, PlacementSpread)
+		racks := map[string]bool{}
+		for _, id := range picked {
+			racks[rackOf(id)] = true
+		}
+		if len(racks) != 3 {
+			t.Fatalf("spread placed replicas on %d racks: %v", len(racks), picked)
+		}
+
+	}
+
+	// with two racks of two nodes, local-remote is always one + two
+	delete(nodes, "dn5")
+	for i := 0; i < 20; i++ {
+		picked := PickNodes(nodes, nil, 3, PlacementLocalRemote)
+		r0, r1, r2 := rackOf(picked[0]), rackOf(picked[1]), rackOf(picked[2])
+		if r0 == r1 || r1 != r2 {
+			t.Fatalf("local-remote placement broken: %s %s %s", r0, r1, r2)
+		}
+	}
+	nodes["dn5"] = NodeStatus{Address: "c1", Rack: "r3"}
+
+	// existing replicas count towards spread and are never picked again
+	target := pickTarget(nodes, []string{"dn1", "dn3"}, PlacementSpread)
+	if target != "dn5" {
+		t.Fatalf("expected target on the unused rack, got %s", target)
+	}
+}
+
+func TestCapacityAwarePlacement(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	ctx := context.Background()
+
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b"}
+	s.State.Nodes["dn3"] = NodeStatus{Address: "full"}
+
+	for _, hb := range []*pb.NodeHeartbeat{
+		{NodeId: "dn1", CapacityBytes: 100, UsedBytes: 10},
+		{NodeId: "dn2", CapacityBytes: 100, UsedBytes: 50, ActiveTransfers: 3},
+		{NodeId: "dn3", CapacityBytes: 100, UsedBytes: 99},
+	} {
+		if _, err := s.Heartbeat(ctx, hb); err != nil {
+			t.Fatalf("Heartbeat from %s failed: %v", hb.NodeId, err)
+		}
+	}
+
+	if node := s.State.Nodes["dn2"]; node.Used != 50 || node.ActiveTransfers != 3 {
+		t.Fatalf("heartbeat stats not stored: %+v", node)
+	}
+
+	for i := 0; i < 20; i++ {
+		resp, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+			ChunkId:    chunkID(fmt.Sprintf("c%d", i)),
+			Filename:   "f",
+			ChunkIndex: int32(i),
+		})
+		if err != nil {
+			t.Fatalf("AllocateChunk failed: %v", err)
+		}
+		for _, addr := range resp.Nodes {
+			if addr == "full" {
+				t.Fatal("node above the high-water mark received a replica")
+			}
+		}
+	}
+
+	// the emptier, idle node should usually come first
+	first := 0
+	for i := 0; i < 200; i++ {
+		if PickNodes(s.placementCandidates(), nil, 1, PlacementRandom)[0] == "dn1" {
+			first++
+		}
+	}
+	if first < 120 {
+		t.Fatalf("placement ignores free space and load: a picked %d/200 times", first)
+	}
+}
@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+)
+
+// replTask is one chunk waiting to be brought back to its target replica count.
+type replTask struct {
+	Filename   string
+	ChunkIndex int
+	Meta       ChunkMetadata
+	Live       int  // live replicas when the task was queued
+	Trim       bool // drop replicas instead of adding them
+}
+
+func (t replTask) key() string {
+	return fmt.Sprintf("%s:%d", t.Filename, t.ChunkIndex)
+}
+
+// replHeap orders tasks by risk: missing replicas before extra ones,
+// then fewest live replicas first.
+type replHeap []replTask
+
+func (h replHeap) Len() int { return len(h) }
+func (h replHeap) Less(i, j int) bool {
+	if h[i].Trim != h[j].Trim {
+		return !h[i].Trim
+	}
+	return h[i].Live < h[j].Live
+}
+func (h replHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *replHeap) Push(x any)   { *h = append(*h, x.(replTask)) }
+func (h *replHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
+
+// replQueue is a blocking priority queue of replication work.
+type replQueue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	tasks replHeap
+}
+
+func newReplQueue() *replQueue {
+	q := &replQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *replQueue) push(t replTask) {
+	q.mu.Lock()
+	heap.Push(&q.tasks, t)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop blocks until a task is available and returns the most urgent one.
+func (q *replQueue) pop() replTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.tasks) == 0 {
+		q.cond.Wait()
+	}
+	return heap.Pop(&q.tasks).(replTask)
+}
+
+func (q *replQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tasks)
+}
@@ -0,0 +1,317 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Quota limits the bytes, counting every replica, and the number of files
+// of a user or below a directory. Zero means unlimited.
+type Quota struct {
+	Bytes int64 `json:"bytes,omitempty"`
+	Files int64 `json:"files,omitempty"`
+}
+
+// charge returns the bytes a file takes across its replicas.
+// Caller must hold the state lock.
+func (s *Server) charge(name string, chunks map[int]ChunkMetadata) int64 {
+	var total int64
+	for _, c := range chunks {
+		rf := s.replicationFor(name)
+		if c.packed() {
+			rf = s.replicationFor(c.Container)
+		}
+		total += c.stored() * int64(rf)
+	}
+	return total
+}
+
+// Usage is what the files of a user, or below a directory, take: their
+// bytes across all replicas and their number.
+type Usage struct {
+	Bytes int64
+	Files int64
+}
+
+// account adds what a file takes to the usage of its owner and of the
+// directories with a quota above it or, with sign -1, takes it away.
+// Whatever changes a file's chunks, replication, owner or path takes the
+// file out of the usage before and puts it back after, so the counters
+// stay right without walking the namespace, on replay as well.
+// Caller must hold the state write lock.
+func (s *Server) account(name string, sign int64) {
+	chunks, ok := s.State.Files[name]
+	if !ok || reserved(name) {
+		return
+	}
+	bytes := sign * s.charge(name, chunks)
+
+	owner := s.State.Perms[name].Owner
+	u := s.State.UserUsage[owner]
+	u.Bytes += bytes
+	u.Files += sign
+	if u == (Usage{}) {
+		delete(s.State.UserUsage, owner)
+	} else {
+		s.State.UserUsage[owner] = u
+	}
+
+	for dir := name; dir != ""; {
+		dir = parent(dir)
+		if u, ok := s.State.DirUsage[dir]; ok {
+			u.Bytes += bytes
+			u.Files += sign
+			s.State.DirUsage[dir] = u
+		}
+	}
+}
+
+// scanUsage adds up the files matching a predicate. It walks the whole
+// namespace, so it only runs to start counting a new directory quota and
+// to size a rename. Caller must hold the state lock.
+func (s *Server) scanUsage(match func(name string) bool) Usage {
+	var u Usage
+	for name, chunks := range s.State.Files {
+		if reserved(name) || !match(name) {
+			continue
+		}
+		u.Bytes += s.charge(name, chunks)
+		u.Files++
+	}
+	return u
+}
+
+// exceeded returns a RESOURCE_EXHAUSTED error when adding bytes and files
+// to the usage would go over the quota of what.
+func (q Quota) exceeded(what string, bytes, files, addBytes, addFiles int64) error {
+	var msg string
+	switch {
+	case q.Bytes > 0 && addBytes > 0 && bytes+addBytes > q.Bytes:
+		msg = fmt.Sprintf("%s is over its quota of %d bytes: %d in use, %d more requested", what, q.Bytes, bytes, addBytes)
+	case q.Files > 0 && addFiles > 0 && files+addFiles > q.Files:
+		msg = fmt.Sprintf("%s is over its quota of %d files", what, q.Files)
+	default:
+		return nil
+	}
+	return withDetails(status.New(codes.ResourceExhausted, msg),
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: what, Description: msg},
+		}})
+}
+
+// growth is what a file is about to add to the usage: its new bytes, and
+// one file when it is new.
+type growth struct {
+	name  string
+	bytes int64
+	files int64
+}
+
+// checkQuota checks that files of owner may grow within the quota of the
+// owner and those of the directories above them.
+// Caller must hold the state lock.
+func (s *Server) checkQuota(owner string, adds ...growth) error {
+	var bytes, files int64
+	dirs := make(map[string]bool)
+	for _, g := range adds {
+		bytes += g.bytes
+		files += g.files
+		for dir := g.name; dir != ""; {
+			dir = parent(dir)
+			if _, ok := s.State.DirQuotas[dir]; ok {
+				dirs[dir] = true
+			}
+		}
+	}
+	if bytes <= 0 && files <= 0 {
+		return nil
+	}
+
+	if q, ok := s.State.UserQuotas[owner]; ok && owner != "" {
+		u := s.State.UserUsage[owner]
+		if err := q.exceeded("user "+owner, u.Bytes, u.Files, bytes, files); err != nil {
+			return err
+		}
+	}
+	for dir := range dirs {
+		var addBytes, addFiles int64
+		for _, g := range adds {
+			if isUnder(g.name, dir) {
+				addBytes += g.bytes
+				addFiles += g.files
+			}
+		}
+		u := s.State.DirUsage[dir]
+		if err := s.State.DirQuotas[dir].exceeded("/"+dir, u.Bytes, u.Files, addBytes, addFiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMove checks that moving src to dst keeps the directories it enters
+// within their quotas. Caller must hold the state lock.
+func (s *Server) checkMove(src, dst string) error {
+	moved := func(name string) bool { return name == src || isUnder(name, src) }
+	var m *Usage
+	for dir := dst; dir != ""; {
+		dir = parent(dir)
+		q, ok := s.State.DirQuotas[dir]
+		if !ok || isUnder(src, dir) {
+			continue
+		}
+		if m == nil {
+			u := s.scanUsage(moved)
+			m = &u
+		}
+		u := s.State.DirUsage[dir]
+		if err := q.exceeded("/"+dir, u.Bytes, u.Files, m.Bytes, m.Files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetQuota sets the quota of a user or a directory; zero limits remove it.
+func (s *Server) SetQuota(ctx context.Context, req *pb.SetQuotaRequest) (*pb.Ack, error) {
+	if (req.User == "") == (req.Dir == "") {
+		return nil, invalidArgument("user", "a quota is set on either a user or a directory")
+	}
+	if req.MaxBytes < 0 || req.MaxFiles < 0 {
+		return nil, invalidArgument("max_bytes", "invalid quota of %d bytes and %d files", req.MaxBytes, req.MaxFiles)
+	}
+	dir := cleanPath(req.Dir)
+	if req.Dir != "" && reserved(dir) {
+		return nil, invalidArgument("dir", "invalid directory %q", req.Dir)
+	}
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	if req.Dir != "" && !s.dirExists(dir) {
+		return nil, notFound("directory", dir)
+	}
+
+	payload, err := json.Marshal(struct {
+		User  string `json:"user,omitempty"`
+		Dir   string `json:"dir,omitempty"`
+		Quota Quota  `json:"quota"`
+	}{
+		User:  req.User,
+		Dir:   dir,
+		Quota: Quota{Bytes: req.MaxBytes, Files: req.MaxFiles},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "SET_QUOTA",
+		Data: payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyQuota(req.User, req.Dir != "", dir, Quota{Bytes: req.MaxBytes, Files: req.MaxFiles})
+	return &pb.Ack{Ok: true}, nil
+}
+
+// applyQuota sets or, with zero limits, removes a quota. A directory's
+// usage is counted from when it first gets a quota.
+// Caller must hold the state write lock.
+func (s *Server) applyQuota(user string, isDir bool, dir string, q Quota) {
+	if !isDir {
+		if q == (Quota{}) {
+			delete(s.State.UserQuotas, user)
+		} else {
+			s.State.UserQuotas[user] = q
+		}
+		return
+	}
+
+	if q == (Quota{}) {
+		delete(s.State.DirQuotas, dir)
+		delete(s.State.DirUsage, dir)
+		return
+	}
+	if _, ok := s.State.DirQuotas[dir]; !ok {
+		s.State.DirUsage[dir] = s.scanUsage(func(name string) bool { return isUnder(name, dir) })
+	}
+	s.State.DirQuotas[dir] = q
+}
+
+// GetQuota returns the quotas with their usage. Only administrators may
+// look at the quota of another user.
+func (s *Server) GetQuota(ctx context.Context, req *pb.QuotaRequest) (*pb.QuotaList, error) {
+	id := Caller(ctx)
+	if req.User != "" && req.User != id.User && !s.superuser(id) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s cannot see the quota of %s", id.User, req.User)
+	}
+
+	s.State.Mu.RLock()
+	defer s.State.Mu.RUnlock()
+
+	res := &pb.QuotaList{}
+	addUser := func(user string) {
+		if q, ok := s.State.UserQuotas[user]; ok {
+			u := s.State.UserUsage[user]
+			res.Quotas = append(res.Quotas, &pb.Quota{User: user, MaxBytes: q.Bytes, MaxFiles: q.Files, Bytes: u.Bytes, Files: u.Files})
+		}
+	}
+	addDir := func(dir string) {
+		if q, ok := s.State.DirQuotas[dir]; ok {
+			u := s.State.DirUsage[dir]
+			res.Quotas = append(res.Quotas, &pb.Quota{Dir: "/" + dir, MaxBytes: q.Bytes, MaxFiles: q.Files, Bytes: u.Bytes, Files: u.Files})
+		}
+	}
+
+	switch {
+	case req.User != "":
+		addUser(req.User)
+	case req.Dir != "":
+		dir := cleanPath(req.Dir)
+		for {
+			addDir(dir)
+			if dir == "" {
+				break
+			}
+			dir = parent(dir)
+		}
+	default:
+		addUser(id.User)
+		dirs := make([]string, 0, len(s.State.DirQuotas))
+		for dir := range s.State.DirQuotas {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			addDir(dir)
+		}
+	}
+	return res, nil
+}
+
+// moveQuotas carries the quotas of a directory and those below it, with
+// their usage, along when it is renamed. Caller must hold the state write
+// lock.
+func (s *Server) moveQuotas(src, dst string) {
+	for dir, q := range s.State.DirQuotas {
+		if dir == src || isUnder(dir, src) {
+			to := dst + strings.TrimPrefix(dir, src)
+			u := s.State.DirUsage[dir]
+			delete(s.State.DirQuotas, dir)
+			delete(s.State.DirUsage, dir)
+			s.State.DirQuotas[to] = q
+			s.State.DirUsage[to] = u
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuotas(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 2})
+	s.Auth = &Auth{Admins: map[string]bool{"root": true}}
+	s.State.Nodes["dn1"] = NodeStatus{Address: "localhost:6001"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "localhost:6002"}
+	as := func(user string) context.Context {
+		return context.WithValue(context.Background(), callerKey{}, Identity{User: user})
+	}
+	alice, bob, root := as("alice"), as("bob"), as("root")
+
+	if _, err := s.SetQuota(root, &pb.SetQuotaRequest{User: "alice", MaxBytes: 1000}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	mustMkdir(t, root, s, "/shared")
+	if _, err := s.Chmod(root, &pb.ChmodRequest{Filename: "/shared", Mode: 0777}); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	if _, err := s.SetQuota(root, &pb.SetQuotaRequest{Dir: "/shared", MaxFiles: 2}); err != nil {
+		t.Fatalf("SetQuota on a directory: %v", err)
+	}
+
+	// bytes count every replica
+	mustCreate(t, alice, s, &pb.FileRequest{Filename: "/a"})
+	if _, err := s.AllocateChunk(alice, &pb.AllocateChunkRequest{ChunkId: chunkID("c1"), Filename: "/a", Size: 400}); err != nil {
+		t.Fatalf("AllocateChunk within quota: %v", err)
+	}
+	_, err := s.AllocateChunk(alice, &pb.AllocateChunkRequest{ChunkId: chunkID("c2"), Filename: "/a", ChunkIndex: 1, Size: 200})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("AllocateChunk over quota: got %v", err)
+	}
+	if _, err := s.SetReplication(alice, &pb.SetReplicationRequest{Filename: "/a", Replication: 3}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("SetReplication over quota: got %v", err)
+	}
+	if _, err := s.AllocateChunk(bob, &pb.AllocateChunkRequest{ChunkId: chunkID("c3"), Filename: "/b", Size: 600}); err == nil {
+		t.Fatalf("AllocateChunk for a missing file succeeded")
+	}
+
+	// directory quotas apply to everyone below them
+	for i, user := range []context.Context{alice, bob} {
+		if _, err := s.CreateFile(user, &pb.FileRequest{Filename: fmt.Sprintf("/shared/%d", i)}); err != nil {
+			t.Fatalf("CreateFile %d in /shared: %v", i, err)
+		}
+	}
+	if _, err := s.CreateFile(bob, &pb.FileRequest{Filename: "/shared/2"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("CreateFile over a directory quota: got %v", err)
+	}
+	mustCreate(t, bob, s, &pb.FileRequest{Filename: "/b"})
+	if _, err := s.RenameFile(bob, &pb.RenameRequest{Src: "/b", Dst: "/shared/b"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("RenameFile into a full directory: got %v", err)
+	}
+
+	if _, err := s.GetQuota(bob, &pb.QuotaRequest{User: "alice"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("GetQuota of another user: got %v", err)
+	}
+	res, err := s.GetQuota(alice, &pb.QuotaRequest{})
+	if err != nil || len(res.Quotas) != 2 {
+		t.Fatalf("GetQuota: %v %v", res, err)
+	}
+	if q := res.Quotas[0]; q.User != "alice" || q.Bytes != 800 || q.Files != 2 {
+		t.Fatalf("alice's usage: %v", q)
+	}
+	if q := res.Quotas[1]; q.Dir != "/shared" || q.Files != 2 || q.MaxFiles != 2 {
+		t.Fatalf("/shared usage: %v", q)
+	}
+
+	// usage moves with the owner and goes with the file
+	if _, err := s.Chown(root, &pb.ChownRequest{Filename: "/shared/1", Owner: "alice"}); err != nil {
+		t.Fatalf("Chown: %v", err)
+	}
+	if u := s.State.UserUsage["alice"]; u != (Usage{Bytes: 800, Files: 3}) {
+		t.Fatalf("alice's usage after chown: %+v", u)
+	}
+	if _, err := s.DeleteFile(alice, &pb.FileRequest{Filename: "/a"}); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if u := s.State.UserUsage["alice"]; u != (Usage{Files: 2}) {
+		t.Fatalf("alice's usage after delete: %+v", u)
+	}
+
+	// quotas follow renames and survive a restart
+	if _, err := s.RenameFile(root, &pb.RenameRequest{Src: "/shared", Dst: "/common"}); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	r := replay(s)
+	if q := r.State.UserQuotas["alice"]; q != (Quota{Bytes: 1000}) {
+		t.Fatalf("replayed user quota: %+v", q)
+	}
+	if q, ok := r.State.DirQuotas["common"]; !ok || q != (Quota{Files: 2}) || len(r.State.DirQuotas) != 1 {
+		t.Fatalf("replayed directory quotas: %+v", r.State.DirQuotas)
+	}
+
+	// the usage counters agree with a walk of the namespace
+	for _, srv := range []*Server{s, r} {
+		for _, user := range []string{"alice", "bob", "root"} {
+			want := srv.scanUsage(func(name string) bool { return srv.State.Perms[name].Owner == user })
+			if got := srv.State.UserUsage[user]; got != want {
+				t.Fatalf("usage of %s: counted %+v, walked %+v", user, got, want)
+			}
+		}
+		want := srv.scanUsage(func(name string) bool { return isUnder(name, "common") })
+		if got := srv.State.DirUsage["common"]; got != want || want.Files != 2 {
+			t.Fatalf("usage of /common: counted %+v, walked %+v", got, want)
+		}
+	}
+}
@@ -3,12 +3,140 @@ package metadata
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"time"
 )
 
+// Snapshot is the logged state as of a SNAPSHOT entry of the WAL. Usage
+// counters and what heartbeats report are not part of it.
 type Snapshot struct {
-	Files map[string]map[int]ChunkMetadata
+	Seq         int64
+	Nodes       map[string]snapshotNode
+	Files       map[string]map[int]ChunkMetadata
+	Dirs        map[string]bool
+	Replication map[string]int
+	Keys        map[string]FileKey
+	Perms       map[string]Perm
+	UserQuotas  map[string]Quota
+	DirQuotas   map[string]Quota
+}
+
+// snapshotNode is what the WAL records of a node.
+type snapshotNode struct {
+	Address          string
+	Rack             string `json:",omitempty"`
+	Zone             string `json:",omitempty"`
+	AdminState       string `json:",omitempty"`
+	MaintenanceUntil time.Time
+}
+
+// Recover rebuilds the state from the last snapshot and the WAL entries
+// logged after it. A WAL the snapshot already holds, left by a crash
+// between writing the snapshot and resetting the WAL, is reset now.
+func (s *Server) Recover() error {
+	opts := s.opts()
+	snap, err := readSnapshot(opts.SnapshotPath)
+	if err != nil {
+		return err
+	}
+	if snap.Seq == 0 {
+		s.ReplayWAL(opts.WALPath)
+		return nil
+	}
+
+	s.State.Mu.Lock()
+	s.loadSnapshot(snap)
+	s.State.Mu.Unlock()
+
+	if walSnapshotSeq(opts.WALPath) != snap.Seq {
+		return s.WAL.Reset(snap.Seq)
+	}
+	s.ReplayWAL(opts.WALPath)
+	return nil
+}
+
+// readSnapshot reads the snapshot at path; without one, or with one
+// written before snapshots were sequenced, its Seq is 0.
+func readSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// walSnapshotSeq returns the snapshot the WAL at path continues from, or
+// 0 when it does not start with a SNAPSHOT entry.
+func walSnapshotSeq(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return 0
+	}
+	var e WALEntry
+	var seq int64
+	if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Type != "SNAPSHOT" ||
+		json.Unmarshal(e.Data, &seq) != nil {
+		return 0
+	}
+	return seq
+}
+
+// loadSnapshot replaces the logged state with a snapshot's and recounts
+// usage. Caller must hold the state write lock.
+func (s *Server) loadSnapshot(snap *Snapshot) {
+	st := s.State
+	st.Nodes = make(map[string]NodeStatus, len(snap.Nodes))
+	for id, n := range snap.Nodes {
+		st.Nodes[id] = NodeStatus{
+			Address:          n.Address,
+			Rack:             n.Rack,
+			Zone:             n.Zone,
+			AdminState:       n.AdminState,
+			MaintenanceUntil: n.MaintenanceUntil,
+		}
+	}
+	st.Files = orEmpty(snap.Files)
+	st.Dirs = orEmpty(snap.Dirs)
+	st.Replication = orEmpty(snap.Replication)
+	st.Keys = orEmpty(snap.Keys)
+	st.Perms = orEmpty(snap.Perms)
+	st.UserQuotas = orEmpty(snap.UserQuotas)
+	st.DirQuotas = orEmpty(snap.DirQuotas)
+
+	st.UserUsage = make(map[string]Usage)
+	st.DirUsage = make(map[string]Usage, len(st.DirQuotas))
+	for dir := range st.DirQuotas {
+		st.DirUsage[dir] = Usage{}
+	}
+	for name := range st.Files {
+		s.account(name, 1)
+	}
+	s.snapshotSeq = snap.Seq
+}
+
+// orEmpty returns m, or an empty map when m is nil.
+func orEmpty[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return make(map[K]V)
+	}
+	return m
 }
 
 func (s *Server) ReplayWAL(path string) {
@@ -29,43 +157,188 @@ func (s *Server) ReplayWAL(path string) {
 			var payload struct {
 				NodeID  string `json:"node_id"`
 				Address string `json:"address"`
+				Rack    string `json:"rack"`
+				Zone    string `json:"zone"`
 			}
 			if err := json.Unmarshal(e.Data, &payload); err != nil {
 				continue
 			}
 
+			prev := s.State.Nodes[payload.NodeID]
 			s.State.Nodes[payload.NodeID] = NodeStatus{
-				Address:  payload.Address,
-				Lastseen: time.Time{},
+				Address:          payload.Address,
+				Rack:             payload.Rack,
+				Zone:             payload.Zone,
+				Lastseen:         time.Time{},
+				AdminState:       prev.AdminState,
+				MaintenanceUntil: prev.MaintenanceUntil,
 			}
 		case "CREATE_FILE":
-			var filename string
-			if err := json.Unmarshal(e.Data, &filename); err != nil {
-				continue
+			var payload struct {
+				Filename    string `json:"filename"`
+				Replication int    `json:"replication"`
+				WrappedKey  []byte `json:"wrapped_key"`
+				KeyId       string `json:"key_id"`
+				Perm        *Perm  `json:"perm"`
 			}
-
-			if _, ok := s.State.Files[filename]; !ok {
-				s.State.Files[filename] = make(map[int]ChunkMetadata)
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				// older entries carry just the filename
+				if err := json.Unmarshal(e.Data, &payload.Filename); err != nil {
+					continue
+				}
 			}
+
+			key := FileKey{Wrapped: payload.WrappedKey, KeyId: payload.KeyId}
+			s.applyCreate(payload.Filename, payload.Replication, key, payload.Perm)
 		case "ALLOCATE_CHUNK":
 			var payload struct {
 				Filename   string   `json:"filename"`
 				ChunkIndex int      `json:"chunkIndex"`
 				ChunkId    string   `json:"chunkId"`
 				Nodes      []string `json:"nodes"`
+				Size       int64    `json:"size"`
+				Codec      string   `json:"codec"`
+				StoredSize int64    `json:"storedSize"`
 			}
 			if err := json.Unmarshal(e.Data, &payload); err != nil {
 				continue
 			}
 
-			if _, ok := s.State.Files[payload.Filename]; !ok {
-				s.State.Files[payload.Filename] = make(map[int]ChunkMetadata)
+			for i, loc := range payload.Nodes {
+				payload.Nodes[i] = s.nodeIDFor(loc)
 			}
 
-			s.State.Files[payload.Filename][payload.ChunkIndex] = ChunkMetadata{
-				ChunkId: payload.ChunkId,
-				Nodes:   payload.Nodes,
+			s.applyChunk(payload.Filename, payload.ChunkIndex, ChunkMetadata{
+				ChunkId:    payload.ChunkId,
+				Nodes:      payload.Nodes,
+				Size:       payload.Size,
+				Codec:      payload.Codec,
+				StoredSize: payload.StoredSize,
+			})
+		case "DELETE_FILE":
+			var filename string
+			if err := json.Unmarshal(e.Data, &filename); err != nil {
+				continue
+			}
+
+			s.applyDelete(filename)
+		case "RENAME_FILE":
+			var payload struct {
+				Src string `json:"src"`
+				Dst string `json:"dst"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
 			}
+
+			s.applyRename(payload.Src, payload.Dst)
+		case "SET_REPLICATION":
+			var payload struct {
+				Filename    string `json:"filename"`
+				Replication int    `json:"replication"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			s.applyReplication(payload.Filename, payload.Replication)
+		case "REMOVE_REPLICA":
+			var payload struct {
+				Filename   string `json:"filename"`
+				ChunkIndex int    `json:"chunkIndex"`
+				Node       string `json:"node"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			chunk, ok := s.State.Files[payload.Filename][payload.ChunkIndex]
+			if !ok {
+				continue
+			}
+
+			chunk.Nodes = removeNode(chunk.Nodes, s.nodeIDFor(payload.Node))
+			s.State.Files[payload.Filename][payload.ChunkIndex] = chunk
+		case "MOVE_REPLICA":
+			var payload struct {
+				Filename   string `json:"filename"`
+				ChunkIndex int    `json:"chunkIndex"`
+				From       string `json:"from"`
+				To         string `json:"to"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			chunk, ok := s.State.Files[payload.Filename][payload.ChunkIndex]
+			if !ok {
+				continue
+			}
+
+			chunk.Nodes = replaceNode(chunk.Nodes, s.nodeIDFor(payload.From), s.nodeIDFor(payload.To))
+			s.State.Files[payload.Filename][payload.ChunkIndex] = chunk
+		case "MKDIR":
+			var payload struct {
+				Dir  string `json:"dir"`
+				Perm *Perm  `json:"perm"`
+			}
+			// directories without an owner carry just their name
+			if err := json.Unmarshal(e.Data, &payload.Dir); err != nil {
+				if err := json.Unmarshal(e.Data, &payload); err != nil {
+					continue
+				}
+			}
+
+			s.State.Dirs[payload.Dir] = true
+			if payload.Perm != nil {
+				s.State.Perms[payload.Dir] = *payload.Perm
+			}
+		case "SET_QUOTA":
+			var payload struct {
+				User  string  `json:"user"`
+				Dir   *string `json:"dir"`
+				Quota Quota   `json:"quota"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			dir := ""
+			if payload.Dir != nil {
+				dir = *payload.Dir
+			}
+			s.applyQuota(payload.User, payload.User == "", dir, payload.Quota)
+		case "SET_PERM":
+			var payload struct {
+				Path string `json:"path"`
+				Perm
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			s.applyPerm(payload.Path, payload.Perm)
+		case "SET_NODE_STATE":
+			var payload struct {
+				NodeID string    `json:"node_id"`
+				State  string    `json:"state"`
+				Until  time.Time `json:"until"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			s.applyNodeState(payload.NodeID, payload.State, payload.Until)
+		case "PACK_FILES":
+			var payload struct {
+				Container string       `json:"container"`
+				Files     []packedFile `json:"files"`
+			}
+			if err := json.Unmarshal(e.Data, &payload); err != nil {
+				continue
+			}
+
+			s.applyPack(payload.Container, payload.Files)
 		case "ADD_REPLICA":
 			var payload struct {
 				Filename   string `json:"filename"`
@@ -76,7 +349,11 @@ func (s *Server) ReplayWAL(path string) {
 				continue
 			}
 
-			chunk := s.State.Files[payload.Filename][payload.ChunkIndex]
+			chunk, ok := s.State.Files[payload.Filename][payload.ChunkIndex]
+			if !ok {
+				continue
+			}
+			payload.Node = s.nodeIDFor(payload.Node)
 
 			// avoid duplicates
 			for _, n := range chunk.Nodes {
@@ -93,10 +370,88 @@ func (s *Server) ReplayWAL(path string) {
 	}
 }
 
-func (s *Server) WriteSnapShot() {
+// WriteSnapShot writes the logged state to the snapshot file and resets
+// the WAL, which from then on only holds what changed since.
+func (s *Server) WriteSnapShot() error {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	// every WAL append happens under the write lock, so nothing is logged
+	// between taking the snapshot and resetting the WAL
 	s.State.Mu.RLock()
 	defer s.State.Mu.RUnlock()
 
-	b, _ := json.Marshal(s.State.Files)
-	os.WriteFile("metadata.snapshot", b, 0644)
+	st := s.State
+	snap := Snapshot{
+		Seq:         s.snapshotSeq + 1,
+		Nodes:       make(map[string]snapshotNode, len(st.Nodes)),
+		Files:       st.Files,
+		Dirs:        st.Dirs,
+		Replication: st.Replication,
+		Keys:        st.Keys,
+		Perms:       st.Perms,
+		UserQuotas:  st.UserQuotas,
+		DirQuotas:   st.DirQuotas,
+	}
+	for id, n := range st.Nodes {
+		snap.Nodes[id] = snapshotNode{
+			Address:          n.Address,
+			Rack:             n.Rack,
+			Zone:             n.Zone,
+			AdminState:       n.AdminState,
+			MaintenanceUntil: n.MaintenanceUntil,
+		}
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	path := s.opts().SnapshotPath
+	if err := writeFileSync(path, b); err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	if err := s.WAL.Reset(snap.Seq); err != nil {
+		return fmt.Errorf("reset WAL after snapshot %d: %w", snap.Seq, err)
+	}
+	s.snapshotSeq = snap.Seq
+	return nil
+}
+
+// writeFileSync replaces path with data, durably and atomically.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// StartSnapshotLoop periodically writes a snapshot of the logged state.
+// It is a no-op when no snapshot interval is configured.
+func (s *Server) StartSnapshotLoop() {
+	opts := s.opts()
+	if opts.SnapshotInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(opts.SnapshotInterval)
+			if err := s.WriteSnapShot(); err != nil {
+				log.Printf("Snapshot failed: %v", err)
+			}
+		}
+	}()
 }
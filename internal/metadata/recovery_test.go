@@ -0,0 +1,108 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := newTestServer(t, Options{ReplicationFactor: 1})
+	s.Auth = &Auth{Admins: map[string]bool{"root": true}}
+	root := context.WithValue(context.Background(), callerKey{}, Identity{User: "root"})
+	alice := context.WithValue(context.Background(), callerKey{}, Identity{User: "alice"})
+
+	mustRegister(t, s, "dn1", "localhost:6001")
+	mustMkdir(t, root, s, "/home")
+	if _, err := s.Chmod(root, &pb.ChmodRequest{Filename: "/home", Mode: 0777}); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	if _, err := s.SetQuota(root, &pb.SetQuotaRequest{Dir: "/home", MaxFiles: 10}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	mustCreate(t, alice, s, &pb.FileRequest{Filename: "/home/a", WrappedKey: []byte("key"), KeyId: "k1"})
+	if _, err := s.AllocateChunk(alice, &pb.AllocateChunkRequest{ChunkId: chunkID("a0"), Filename: "/home/a", Size: 100}); err != nil {
+		t.Fatalf("AllocateChunk: %v", err)
+	}
+	if _, err := s.Decommission(root, &pb.NodeRequest{NodeId: "dn1"}); err != nil {
+		t.Fatalf("Decommission: %v", err)
+	}
+
+	if err := s.WriteSnapShot(); err != nil {
+		t.Fatalf("WriteSnapShot: %v", err)
+	}
+	walAfterSnapshot, err := os.ReadFile(s.Opts.WALPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq := walSnapshotSeq(s.Opts.WALPath); seq != 1 {
+		t.Fatalf("WAL after the snapshot starts from snapshot %d, want 1", seq)
+	}
+
+	// changes after the snapshot come from the WAL
+	mustCreate(t, alice, s, &pb.FileRequest{Filename: "/home/b"})
+	if _, err := s.RenameFile(alice, &pb.RenameRequest{Src: "/home/a", Dst: "/home/c"}); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+
+	// allocation times are kept in memory only
+	logged := func(files map[string]map[int]ChunkMetadata) map[string]map[int]ChunkMetadata {
+		res := make(map[string]map[int]ChunkMetadata, len(files))
+		for name, chunks := range files {
+			res[name] = make(map[int]ChunkMetadata, len(chunks))
+			for i, c := range chunks {
+				c.allocated = time.Time{}
+				res[name][i] = c
+			}
+		}
+		return res
+	}
+	check := func(what string, r *Server) {
+		t.Helper()
+		for _, m := range []struct {
+			name      string
+			got, want any
+		}{
+			{"files", r.State.Files, logged(s.State.Files)},
+			{"dirs", r.State.Dirs, s.State.Dirs},
+			{"keys", r.State.Keys, s.State.Keys},
+			{"perms", r.State.Perms, s.State.Perms},
+			{"directory quotas", r.State.DirQuotas, s.State.DirQuotas},
+			{"user usage", r.State.UserUsage, s.State.UserUsage},
+			{"directory usage", r.State.DirUsage, s.State.DirUsage},
+		} {
+			if !reflect.DeepEqual(m.got, m.want) {
+				t.Fatalf("%s: %s %+v, want %+v", what, m.name, m.got, m.want)
+			}
+		}
+		if st := r.State.Nodes["dn1"].AdminState; st != AdminDecommissioning {
+			t.Fatalf("%s: node admin state %q", what, st)
+		}
+	}
+
+	r := NewServerWithOptions(s.Opts)
+	if err := r.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	check("recovered", r)
+
+	// a crash between writing a snapshot and resetting the WAL leaves a
+	// WAL the snapshot already holds, which is not replayed on top of it
+	if err := r.WriteSnapShot(); err != nil {
+		t.Fatalf("WriteSnapShot: %v", err)
+	}
+	if err := os.WriteFile(s.Opts.WALPath, walAfterSnapshot, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = NewServerWithOptions(s.Opts)
+	if err := r.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	check("recovered over a stale WAL", r)
+	if seq := walSnapshotSeq(s.Opts.WALPath); seq != 2 {
+		t.Fatalf("stale WAL not reset: starts from snapshot %d", seq)
+	}
+}
@@ -1,18 +1,18 @@
 package metadata
 
 import (
+	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
 	"context"
 	"time"
-
-	"google.golang.org/grpc"
 )
 
-func fetchChunk(addr, ChunkId string) ([]byte, error) {
+func (s *Server) fetchChunk(addr, ChunkId string) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	conn, err := transport.Dial(addr, s.TLS)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,7 @@ func fetchChunk(addr, ChunkId string) ([]byte, error) {
 
 	resp, err := dn.GetChunk(ctx, &pb.ChunkRequest{
 		ChunkId: ChunkId,
+		Token:   s.chunkToken(common.ChunkRead, ChunkId, 0, 0),
 	})
 	if err != nil {
 		return nil, err
@@ -29,11 +30,11 @@ func fetchChunk(addr, ChunkId string) ([]byte, error) {
 	return resp.Data, nil
 }
 
-func StoreChunk(addr, ChunkId string, data []byte) error {
+func (s *Server) StoreChunk(addr, ChunkId string, data []byte) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	conn, err := transport.Dial(addr, s.TLS)
 	if err != nil {
 		return err
 	}
@@ -44,7 +45,84 @@ func StoreChunk(addr, ChunkId string, data []byte) error {
 	_, err = dn.StoreChunk(ctx, &pb.Chunk{
 		ChunkId: ChunkId,
 		Data:    data,
+		Token:   s.chunkToken(common.ChunkWrite, ChunkId, 0, 0),
+	})
+
+	return err
+}
+
+func (s *Server) deleteChunk(addr, ChunkId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := transport.Dial(addr, s.TLS)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	dn := pb.NewDataNodeServiceClient(conn)
+
+	_, err = dn.DeleteChunk(ctx, &pb.ChunkRequest{
+		ChunkId: ChunkId,
+		Token:   s.chunkToken(common.ChunkDelete, ChunkId, 0, 0),
+	})
+
+	return err
+}
+
+// copyChunk asks the DataNode at from to push a chunk to the DataNode at to.
+func (s *Server) copyChunk(from, to, ChunkId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := transport.Dial(from, s.TLS)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	dn := pb.NewDataNodeServiceClient(conn)
+
+	_, err = dn.ReplicateChunk(ctx, &pb.ReplicateRequest{
+		ChunkId: ChunkId,
+		Target:  to,
+		Token:   s.chunkToken(common.ChunkReplicate, common.ReplicateSubject(ChunkId, to), 0, 0),
 	})
 
 	return err
 }
+
+// awaitChunk polls the DataNode at addr until it holds a chunk, and
+// reports whether it did before timeout.
+func (s *Server) awaitChunk(addr, chunkId string, timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		time.Sleep(copyPollInterval)
+		if held, err := s.checkChunks(addr, []string{chunkId}); err == nil && len(held) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkChunks returns the subset of ids the DataNode at addr holds.
+func (s *Server) checkChunks(addr string, ids []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := transport.Dial(addr, s.TLS)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dn := pb.NewDataNodeServiceClient(conn)
+
+	resp, err := dn.CheckChunks(ctx, &pb.ChunkList{
+		ChunkIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ChunkIds, nil
+}
@@ -1,5 +1,11 @@
 package metadata
 
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"encoding/json"
+)
+
 // Scanner
 func (s *Server) FindUnderReplicatedChunks(rf int) []struct {
 	Filename   string
@@ -17,7 +23,7 @@ func (s *Server) FindUnderReplicatedChunks(rf int) []struct {
 
 	for filename, chunks := range s.State.Files {
 		for idx, meta := range chunks {
-			if len(meta.Nodes) < rf {
+			if len(meta.Nodes) < rf && !meta.packed() {
 				res = append(res, struct {
 					Filename   string
 					ChunkIndex int
@@ -29,3 +35,142 @@ func (s *Server) FindUnderReplicatedChunks(rf int) []struct {
 
 	return res
 }
+
+func (s *Server) SetReplication(ctx context.Context, req *pb.SetReplicationRequest) (*pb.Ack, error) {
+	filename := cleanPath(req.Filename)
+	if req.Replication < 1 {
+		return nil, invalidArgument("replication", "invalid replication factor %d", req.Replication)
+	}
+
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
+
+	chunks, ok := s.State.Files[filename]
+	if !ok || reserved(filename) {
+		return nil, notFound("file", filename)
+	}
+	if err := s.access(Caller(ctx), filename, permWrite); err != nil {
+		return nil, err
+	}
+	if c, ok := chunks[0]; ok && c.packed() {
+		return nil, failedPrecondition("PACKED", filename, "%s is packed in a shared container and has its replication factor", filename)
+	}
+	if more := int(req.Replication) - s.replicationFor(filename); more > 0 {
+		g := growth{name: filename, bytes: s.charge(filename, chunks) / int64(s.replicationFor(filename)) * int64(more)}
+		if err := s.checkQuota(s.State.Perms[filename].Owner, g); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := json.Marshal(struct {
+		Filename    string `json:"filename"`
+		Replication int    `json:"replication"`
+	}{
+		Filename:    filename,
+		Replication: int(req.Replication),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.WAL.Append(WALEntry{
+		Type: "SET_REPLICATION",
+		Data: payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyReplication(filename, int(req.Replication))
+
+	// Converge now rather than waiting for the next heal pass
+	s.reconcileFile(filename)
+
+	return &pb.Ack{Ok: true}, nil
+}
+
+// applyReplication sets the replication factor of a file.
+// Caller must hold the state write lock.
+func (s *Server) applyReplication(filename string, rf int) {
+	s.account(filename, -1)
+	s.State.Replication[filename] = rf
+	s.account(filename, 1)
+}
+
+// reconcileFile queues replication or trimming for every chunk of filename
+// whose replica count is off target. Replicas on decommissioning nodes do
+// not count, so their chunks are copied elsewhere before they go; replicas
+// on dead nodes neither count nor get trimmed. Packed files are left to
+// their container.
+// Caller must hold the state write lock.
+func (s *Server) reconcileFile(filename string) {
+	rf := s.replicationFor(filename)
+	for idx, meta := range s.State.Files[filename] {
+		switch {
+		case meta.packed():
+		case s.usableReplicas(meta.Nodes) < rf:
+			s.enqueueChunk(filename, idx, meta, false)
+		case s.presentReplicas(meta.Nodes) > rf:
+			s.enqueueChunk(filename, idx, meta, true)
+		}
+	}
+}
+
+// reconcileNode reconciles every file with a replica on node id.
+// Caller must hold the state write lock.
+func (s *Server) reconcileNode(id string) {
+	for fname, chunks := range s.State.Files {
+		for _, meta := range chunks {
+			if containsNode(meta.Nodes, id) {
+				s.reconcileFile(fname)
+				break
+			}
+		}
+	}
+}
+
+// enqueueChunk queues a chunk unless it is already queued or in flight.
+// Caller must hold the state write lock.
+func (s *Server) enqueueChunk(filename string, idx int, meta ChunkMetadata, trim bool) {
+	t := replTask{
+		Filename:   filename,
+		ChunkIndex: idx,
+		Meta:       meta,
+		Live:       s.liveReplicas(meta.Nodes),
+		Trim:       trim,
+	}
+	if s.State.Replicating[t.key()] {
+		return
+	}
+	s.State.Replicating[t.key()] = true
+	s.queue().push(t)
+}
+
+func (s *Server) queue() *replQueue {
+	s.initReplication()
+	return s.replQ
+}
+
+func (s *Server) initReplication() {
+	s.replInit.Do(func() {
+		s.replQ = newReplQueue()
+		s.throttle = newBandwidthLimiter(s.opts().NodeBandwidthMB)
+	})
+}
+
+// replicationWorker drains the queue, one chunk at a time.
+func (s *Server) replicationWorker() {
+	for {
+		t := s.queue().pop()
+
+		if t.Trim {
+			s.trimChunk(t.Filename, t.ChunkIndex, t.Meta)
+		} else {
+			s.replicateChunk(t.Filename, t.ChunkIndex, t.Meta)
+		}
+
+		s.State.Mu.Lock()
+		delete(s.State.Replicating, t.key())
+		s.State.Mu.Unlock()
+	}
+}
@@ -0,0 +1,68 @@
+package metadata
+
+import (
+	pb "DFS_GO/internal/proto"
+	"context"
+	"testing"
+)
+
+func TestPerFileReplication(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	for _, id := range []string{"dn1", "dn2", "dn3"} {
+		s.State.Nodes[id] = NodeStatus{Address: id}
+	}
+
+	mustCreate(t, ctx, s, &pb.FileRequest{Filename: "scratch.bin", Replication: 1})
+
+	resp, err := s.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+		ChunkId:    chunkID("c0"),
+		Filename:   "scratch.bin",
+		ChunkIndex: 0,
+	})
+	if err != nil {
+		t.Fatalf("AllocateChunk failed: %v", err)
+	}
+	if len(resp.Nodes) != 1 {
+		t.Fatalf("expected 1 replica, got %d", len(resp.Nodes))
+	}
+
+	if _, err := s.SetReplication(ctx, &pb.SetReplicationRequest{Filename: "scratch.bin", Replication: 5}); err != nil {
+		t.Fatalf("SetReplication failed: %v", err)
+	}
+
+	s2 := replay(s)
+
+	if rf := s2.replicationFor("scratch.bin"); rf != 5 {
+		t.Fatalf("replication not replayed: got %d", rf)
+	}
+}
+
+func TestReplicationQueuePriority(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	s.State.Nodes["dn1"] = NodeStatus{Address: "a"}
+	s.State.Nodes["dn2"] = NodeStatus{Address: "b"}
+	s.State.Files["f"] = map[int]ChunkMetadata{
+		0: {ChunkId: "c0", Nodes: []string{"dn1", "dn2"}},
+		1: {ChunkId: "c1", Nodes: []string{"dn1"}},
+		2: {ChunkId: "c2", Nodes: []string{"dn1", "dn2", "x", "y"}},
+	}
+
+	s.State.Mu.Lock()
+	s.reconcileFile("f")
+	s.reconcileFile("f") // second pass must not duplicate in-flight work
+	s.State.Mu.Unlock()
+
+	if n := s.queue().Len(); n != 3 {
+		t.Fatalf("expected 3 queued tasks, got %d", n)
+	}
+
+	order := []string{"c1", "c0", "c2"}
+	for _, want := range order {
+		if got := s.queue().pop(); got.Meta.ChunkId != want {
+			t.Fatalf("expected %s next, got %s", want, got.Meta.ChunkId)
+		}
+	}
+}
@@ -3,10 +3,12 @@ package metadata
 import (
 	"DFS_GO/internal/common"
 	pb "DFS_GO/internal/proto"
+	"DFS_GO/internal/transport"
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,20 +16,73 @@ type Server struct {
 	pb.UnimplementedMetadataServiceServer
 	State *State
 	WAL   *WAL
+	Opts  Options
+
+	// TLS for connections to DataNodes; nil dials them in plaintext
+	TLS *transport.TLS
+
+	// Auth authenticates clients; nil lets anyone in
+	Auth *Auth
+
+	// ChunkKey signs the chunk tokens DataNodes check; nil runs without
+	ChunkKey []byte
+
+	replInit sync.Once
+	replQ    *replQueue
+	throttle *bandwidthLimiter
+
+	// commands waiting for a node's next heartbeat; guarded by State.Mu
+	commands map[string][]*pb.NodeCommand
+
+	// set while a balancing run is moving replicas
+	balancing atomic.Bool
+
+	// containers being rewritten by compaction; guarded by State.Mu
+	compacting map[string]bool
+
+	// sequence number of the last snapshot, written one at a time
+	snapshotMu  sync.Mutex
+	snapshotSeq int64
 }
 
 func NewServer() *Server {
-	return &Server{State: NewState(), WAL: NewWAL("metadata.wal")}
+	return NewServerWithOptions(DefaultOptions())
+}
+
+func NewServerWithOptions(opts Options) *Server {
+	opts = opts.withDefaults()
+	return &Server{State: NewState(), WAL: NewWAL(opts.WALPath), Opts: opts}
+}
+
+// opts returns the server options with defaults filled in, so a Server
+// built as a struct literal still behaves sensibly.
+func (s *Server) opts() Options {
+	return s.Opts.withDefaults()
+}
+
+// replicationFor returns the target replica count of a file.
+// Caller must hold the state lock.
+func (s *Server) replicationFor(filename string) int {
+	if rf := s.State.Replication[filename]; rf > 0 {
+		return rf
+	}
+	return s.opts().ReplicationFactor
 }
 
 func (s *Server) RegisterNode(ctx context.Context, n *pb.NodeInfo) (*pb.Ack, error) {
+	s.State.Mu.Lock()
+	defer s.State.Mu.Unlock()
 
 	payload, err := json.Marshal(struct {
 		NodeID  string `json:"node_id"`
 		Address string `json:"address"`
+		Rack    string `json:"rack,omitempty"`
+		Zone    string `json:"zone,omitempty"`
 	}{
 		NodeID:  n.NodeId,
 		Address: n.Address,
+		Rack:    n.Rack,
+		Zone:    n.Zone,
 	})
 	if err != nil {
 		return nil, err
@@ -41,13 +96,21 @@ func (s *Server) RegisterNode(ctx context.Context, n *pb.NodeInfo) (*pb.Ack, err
 	}
 
 	//register the Node
-	s.State.Mu.Lock()
-	defer s.State.Mu.Unlock()
-
-	s.State.Nodes[n.NodeId] = NodeStatus{
-		Address:  n.Address,
-		Lastseen: time.Now(),
+	// admin and liveness state survive a restart of the node
+	prev := s.State.Nodes[n.NodeId]
+	node := NodeStatus{
+		Address:          n.Address,
+		Rack:             n.Rack,
+		Zone:             n.Zone,
+		Lastseen:         time.Now(),
+		AdminState:       prev.AdminState,
+		MaintenanceUntil: prev.MaintenanceUntil,
+		Liveness:         prev.Liveness,
 	}
+	s.State.Nodes[n.NodeId] = s.reviveNode(n.NodeId, node)
+
+	// a (re)started node may have lost or kept chunks behind our back
+	s.queueCommand(n.NodeId, &pb.NodeCommand{Type: pb.NodeCommand_BLOCK_REPORT})
 	return &pb.Ack{Ok: true}, nil
 }
 
@@ -61,67 +124,237 @@ func (s *Server) CreateFile(ctx context.Context, req *pb.FileRequest) (*pb.FileM
 	*/
 
 	// Intent to upload File
+	filename := cleanPath(req.Filename)
+	if filename == "" || reserved(filename) {
+		return nil, invalidArgument("filename", "invalid filename %q", req.Filename)
+	}
+	if req.Replication < 0 {
+		return nil, invalidArgument("replication", "invalid replication factor %d", req.Replication)
+	}
+
+	id := Caller(ctx)
+
 	s.State.Mu.Lock()
 	defer s.State.Mu.Unlock()
 
-	if _, exists := s.State.Files[req.Filename]; exists {
-		return nil, fmt.Errorf("file exists")
+	if err := s.accessParent(id, filename); err != nil {
+		return nil, err
+	}
+	if _, exists := s.State.Files[filename]; exists {
+		return nil, alreadyExists("file", filename)
+	}
+	if s.dirExists(filename) {
+		return nil, alreadyExists("directory", filename)
+	}
+	if err := s.checkParents(filename); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(id.User, growth{name: filename, files: 1}); err != nil {
+		return nil, err
+	}
+
+	key := FileKey{Wrapped: req.WrappedKey, KeyId: req.KeyId}
+	if err := s.createFile(filename, int(req.Replication), key, newPerm(id, DefaultFileMode)); err != nil {
+		return nil, err
 	}
 
-	// Marshal filename as JSON string for valid json.RawMessage
-	filenameJSON, err := json.Marshal(req.Filename)
+	return &pb.FileMetadata{
+		Filename:    filename,
+		Replication: int32(s.replicationFor(filename)),
+	}, nil
+}
+
+// createFile records a new, empty file, with the wrapped data key of an
+// encrypted one and its permissions when it has an owner.
+// Caller must hold the state write lock.
+func (s *Server) createFile(filename string, replication int, key FileKey, perm *Perm) error {
+	payload, err := json.Marshal(struct {
+		Filename    string `json:"filename"`
+		Replication int    `json:"replication"`
+		WrappedKey  []byte `json:"wrapped_key,omitempty"`
+		KeyId       string `json:"key_id,omitempty"`
+		Perm        *Perm  `json:"perm,omitempty"`
+	}{
+		Filename:    filename,
+		Replication: replication,
+		WrappedKey:  key.Wrapped,
+		KeyId:       key.KeyId,
+		Perm:        perm,
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	err = s.WAL.Append(WALEntry{
 		Type: "CREATE_FILE",
-		Data: filenameJSON,
+		Data: payload,
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	s.State.Files[req.Filename] = make(map[int]ChunkMetadata)
+	s.applyCreate(filename, replication, key, perm)
+	return nil
+}
 
-	return &pb.FileMetadata{
-		Filename: req.Filename,
-	}, nil
+// applyCreate records a file; one that exists keeps its chunks.
+// Caller must hold the state write lock.
+func (s *Server) applyCreate(filename string, replication int, key FileKey, perm *Perm) {
+	s.account(filename, -1)
+	if _, ok := s.State.Files[filename]; !ok {
+		s.State.Files[filename] = make(map[int]ChunkMetadata)
+	}
+	if replication > 0 {
+		s.State.Replication[filename] = replication
+	}
+	if len(key.Wrapped) > 0 {
+		s.State.Keys[filename] = key
+	}
+	if perm != nil {
+		s.State.Perms[filename] = *perm
+	}
+	s.account(filename, 1)
 }
 
 func (s *Server) AllocateChunk(ctx context.Context, req *pb.AllocateChunkRequest) (*pb.ChunkMetadata, error) {
+	filename := cleanPath(req.Filename)
+	if reserved(filename) {
+		return nil, invalidArgument("filename", "invalid filename %q", req.Filename)
+	}
+	if !common.ValidChunkId(req.ChunkId) {
+		return nil, invalidArgument("chunk_id", "invalid chunk ID %q", req.ChunkId)
+	}
+	if req.ChunkIndex < 0 {
+		return nil, invalidArgument("chunk_index", "negative chunk index %d", req.ChunkIndex)
+	}
+	if req.Codec != "" && req.StoredSize <= 0 {
+		return nil, invalidArgument("stored_size", "compressed chunk %s without a stored size", req.ChunkId)
+	}
+
+	id := Caller(ctx)
 
 	s.State.Mu.Lock()
 	defer s.State.Mu.Unlock()
 
-	// Ensure file entry exists
-	if _, ok := s.State.Files[req.Filename]; !ok {
-		s.State.Files[req.Filename] = make(map[int]ChunkMetadata)
+	// With authentication the file must have been created, so that it
+	// has an owner
+	_, exists := s.State.Files[filename]
+	if !exists && s.Auth != nil {
+		return nil, notFound("file", filename)
+	}
+	if err := s.access(id, filename, permWrite); err != nil {
+		return nil, err
+	}
+	if !exists {
+		if s.dirExists(filename) {
+			return nil, alreadyExists("directory", filename)
+		}
+		if err := s.checkParents(filename); err != nil {
+			return nil, err
+		}
+	}
+	// A packed file is a slice of a shared container; writing its chunk,
+	// or adding more, would touch the other files in it
+	if c, ok := s.State.Files[filename][0]; ok && c.packed() {
+		return nil, failedPrecondition("PACKED", filename, "%s is packed in a shared container and cannot be written", filename)
 	}
 
-	// If chunk already exists, return existing metadata (idempotent)
-	if meta, ok := s.State.Files[req.Filename][int(req.ChunkIndex)]; ok {
-		return &pb.ChunkMetadata{
-			ChunkId: meta.ChunkId,
-			Nodes:   meta.Nodes,
-		}, nil
+	// If chunk already exists, return existing metadata (idempotent),
+	// unless its uploader asks for other nodes
+	if meta, ok := s.State.Files[filename][int(req.ChunkIndex)]; ok {
+		if len(req.ExcludeNodes) > 0 && meta.ChunkId == req.ChunkId {
+			var err error
+			if meta, err = s.reallocateChunk(filename, int(req.ChunkIndex), meta, req.ExcludeNodes); err != nil {
+				return nil, err
+			}
+		}
+		info := s.chunkInfo(meta)
+		info.Token = s.chunkToken(common.ChunkWrite, meta.ChunkId, 0, 0)
+		return info, nil
+	}
+	if s.ChunkKey != nil && s.chunkInUse(req.ChunkId) {
+		return nil, alreadyExists("chunk", req.ChunkId)
+	}
+
+	// The chunk is charged, once per replica, to the owner of the file
+	stored := req.StoredSize
+	if stored <= 0 {
+		stored = req.Size
+	}
+	g := growth{name: filename, bytes: stored * int64(s.replicationFor(filename))}
+	if !exists {
+		g.files = 1
+	}
+	if err := s.checkQuota(s.State.Perms[filename].Owner, g); err != nil {
+		return nil, err
+	}
+
+	meta, err := s.allocateChunk(filename, int(req.ChunkIndex), ChunkMetadata{
+		ChunkId:    req.ChunkId,
+		Size:       req.Size,
+		Codec:      req.Codec,
+		StoredSize: req.StoredSize,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := s.chunkInfo(meta)
+	info.Token = s.chunkToken(common.ChunkWrite, meta.ChunkId, 0, 0)
+	return info, nil
+}
+
+// reallocateWindow is how long after its allocation a chunk may still be
+// moved to other DataNodes by the client uploading it.
+const reallocateWindow = 10 * time.Minute
+
+// reallocateChunk moves a chunk still being uploaded off the DataNodes,
+// given by address, that failed to store it.
+// Caller must hold the state write lock.
+func (s *Server) reallocateChunk(filename string, idx int, meta ChunkMetadata, failed []string) (ChunkMetadata, error) {
+	if meta.allocated.IsZero() || time.Since(meta.allocated) > reallocateWindow {
+		return ChunkMetadata{}, failedPrecondition("UPLOADED", meta.ChunkId, "chunk %s is no longer being uploaded", meta.ChunkId)
+	}
+	exclude := make([]string, 0, len(failed))
+	for _, addr := range failed {
+		exclude = append(exclude, s.nodeIDFor(addr))
 	}
+	log.Printf("Moving chunk %s off %v", meta.ChunkId, failed)
+	meta.Nodes = nil
+	return s.allocateChunk(filename, idx, meta, exclude)
+}
 
+// allocateChunk places a new chunk of a file on replica nodes, other than
+// the excluded ones. Caller must hold the state write lock.
+func (s *Server) allocateChunk(filename string, idx int, chunk ChunkMetadata, exclude []string) (ChunkMetadata, error) {
 	// Pick replica nodes (replication-aware)
-	nodes := PickReplicaNodes(s.State.Nodes, common.ReplicationFactor)
+	candidates := s.placementCandidates()
+	for _, id := range exclude {
+		delete(candidates, id)
+	}
+	nodes := PickReplicaNodes(candidates, s.replicationFor(filename), s.opts().Placement)
+	if len(nodes) == 0 {
+		return ChunkMetadata{}, unavailable("no DataNode available for chunk %s", chunk.ChunkId)
+	}
 	payload, err := json.Marshal(struct {
 		Filename   string
 		ChunkIndex int
 		ChunkId    string
 		Nodes      []string
+		Size       int64
+		Codec      string `json:",omitempty"`
+		StoredSize int64  `json:",omitempty"`
 	}{
-		Filename:   req.Filename,
-		ChunkIndex: int(req.ChunkIndex),
-		ChunkId:    req.ChunkId,
+		Filename:   filename,
+		ChunkIndex: idx,
+		ChunkId:    chunk.ChunkId,
 		Nodes:      nodes,
+		Size:       chunk.Size,
+		Codec:      chunk.Codec,
+		StoredSize: chunk.StoredSize,
 	})
 	if err != nil {
-		return nil, err
+		return ChunkMetadata{}, err
 	}
 
 	err = s.WAL.Append(WALEntry{
@@ -129,21 +362,39 @@ func (s *Server) AllocateChunk(ctx context.Context, req *pb.AllocateChunkRequest
 		Data: payload,
 	})
 	if err != nil {
-		return nil, err
+		return ChunkMetadata{}, err
 	}
 
-	meta := ChunkMetadata{
-		ChunkId: req.ChunkId,
-		Nodes:   nodes,
-	}
+	chunk.Nodes = nodes
+	chunk.allocated = time.Now()
+
+	s.applyChunk(filename, idx, chunk)
+	return chunk, nil
+}
 
-	// Store metadata indexed by chunk index
-	s.State.Files[req.Filename][int(req.ChunkIndex)] = meta
+// applyChunk stores the metadata of a chunk by its index, creating the
+// file if need be. Caller must hold the state write lock.
+func (s *Server) applyChunk(filename string, idx int, chunk ChunkMetadata) {
+	s.account(filename, -1)
+	if _, ok := s.State.Files[filename]; !ok {
+		s.State.Files[filename] = make(map[int]ChunkMetadata)
+	}
+	s.State.Files[filename][idx] = chunk
+	s.account(filename, 1)
+}
 
+// chunkInfo describes a chunk to clients, resolving its replicas to the
+// current addresses of live nodes. Caller must hold the state lock.
+func (s *Server) chunkInfo(meta ChunkMetadata) *pb.ChunkMetadata {
 	return &pb.ChunkMetadata{
-		ChunkId: meta.ChunkId,
-		Nodes:   meta.Nodes,
-	}, nil
+		ChunkId:    meta.ChunkId,
+		Nodes:      s.replicaAddresses(s.chunkNodes(meta)),
+		Size:       meta.Size,
+		Packed:     meta.packed(),
+		Offset:     meta.Offset,
+		Codec:      meta.Codec,
+		StoredSize: meta.StoredSize,
+	}
 }
 
 func (s *Server) GetFile(ctx context.Context, req *pb.FileRequest) (*pb.FileMetadata, error) {
@@ -151,41 +402,73 @@ func (s *Server) GetFile(ctx context.Context, req *pb.FileRequest) (*pb.FileMeta
 	s.State.Mu.RLock()
 	defer s.State.Mu.RUnlock()
 
-	chunksMap, ok := s.State.Files[req.Filename]
+	filename := cleanPath(req.Filename)
+	chunksMap, ok := s.State.Files[filename]
 
-	if !ok {
-		return nil, fmt.Errorf("File not Found: 404")
+	if !ok || reserved(filename) {
+		return nil, notFound("file", filename)
+	}
+	if err := s.access(Caller(ctx), filename, permRead); err != nil {
+		return nil, err
 	}
 
-	// Rebuild ordered slice from map
+	// Rebuild ordered slice from map, resolving node IDs to the current
+	// addresses and leaving out replicas on dead nodes
 	ordered := make([]*pb.ChunkMetadata, len(chunksMap))
 	for idx, meta := range chunksMap {
-		ordered[idx] = &pb.ChunkMetadata{
-			ChunkId: meta.ChunkId,
-			Nodes:   meta.Nodes,
+		// chunks can be allocated out of order; until every index up to
+		// the last is in, the file cannot be read
+		if idx < 0 || idx >= len(ordered) {
+			return nil, failedPrecondition("INCOMPLETE", filename, "%s is missing chunks", filename)
 		}
+		info := s.chunkInfo(meta)
+		if meta.packed() {
+			info.Token = s.chunkToken(common.ChunkRead, meta.ChunkId, meta.Offset, meta.Size)
+		} else {
+			info.Token = s.chunkToken(common.ChunkRead, meta.ChunkId, 0, 0)
+		}
+		ordered[idx] = info
 	}
 
+	key := s.State.Keys[filename]
 	return &pb.FileMetadata{
-		Filename: req.Filename,
-		Chunks:   ordered,
+		Filename:    filename,
+		Chunks:      ordered,
+		Replication: int32(s.replicationFor(filename)),
+		WrappedKey:  key.Wrapped,
+		KeyId:       key.KeyId,
 	}, nil
 }
 
-func (s *Server) Heartbeat(ctx context.Context, hb *pb.NodeHeartbeat) (*pb.Ack, error) {
+func (s *Server) Heartbeat(ctx context.Context, hb *pb.NodeHeartbeat) (*pb.HeartbeatResponse, error) {
 	// If no Heartbeat its DEAD!
 	s.State.Mu.Lock()
 	defer s.State.Mu.Unlock()
 
 	node, ok := s.State.Nodes[hb.NodeId]
 	if !ok {
-		log.Printf("Heartbeat from unknown node: %s", hb.NodeId)
-		return &pb.Ack{Ok: false}, nil
+		log.Printf("Heartbeat from unknown node %s, asking it to register", hb.NodeId)
+		return &pb.HeartbeatResponse{
+			Commands: []*pb.NodeCommand{{Type: pb.NodeCommand_REGISTER}},
+		}, nil
+	}
+
+	// first contact since this server restarted: resync the node's chunks
+	if node.Lastseen.IsZero() {
+		s.queueCommand(hb.NodeId, &pb.NodeCommand{Type: pb.NodeCommand_BLOCK_REPORT})
 	}
 
 	node.Lastseen = time.Now()
-	s.State.Nodes[hb.NodeId] = node
+	node.Capacity = hb.CapacityBytes
+	node.Used = hb.UsedBytes
+	node.Chunks = hb.ChunkCount
+	node.ActiveTransfers = int(hb.ActiveTransfers)
+	node.FailedVolumes = int(hb.FailedVolumes)
+	s.State.Nodes[hb.NodeId] = s.reviveNode(hb.NodeId, node)
 	log.Printf("Heartbeat received from node %s", hb.NodeId)
 
-	return &pb.Ack{Ok: true}, nil
+	return &pb.HeartbeatResponse{
+		Ok:       true,
+		Commands: s.takeCommands(hb.NodeId),
+	}, nil
 }
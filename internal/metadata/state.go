@@ -7,17 +7,143 @@ import (
 
 type ChunkMetadata struct {
 	ChunkId string
-	Nodes   []string
+	Nodes   []string // IDs of the nodes holding a replica
+	Size    int64
+
+	// Set when the file is packed into a shared container: the path of the
+	// container and where the file's Size bytes start in it. The replicas
+	// are the container's, so Nodes stays empty.
+	Container string `json:",omitempty"`
+	Offset    int64  `json:",omitempty"`
+
+	// The codec the client compressed the chunk with, and the bytes each
+	// replica takes when compression or encryption changed the length.
+	// Size stays the length of the file's data.
+	Codec      string `json:",omitempty"`
+	StoredSize int64  `json:",omitempty"`
+
+	allocated time.Time // in memory only; zero for chunks replayed from the WAL
+}
+
+// packed reports whether the chunk is a slice of a container.
+func (c ChunkMetadata) packed() bool {
+	return c.Container != ""
+}
+
+// stored returns the bytes a replica of the chunk takes on a DataNode.
+func (c ChunkMetadata) stored() int64 {
+	if c.StoredSize > 0 {
+		return c.StoredSize
+	}
+	return c.Size
+}
+
+// FileKey is the data key of a file encrypted by its client, wrapped by a
+// master key the server never sees.
+type FileKey struct {
+	Wrapped []byte
+	KeyId   string
+}
+
+// Perm is the owner, group and permission bits of a file or directory.
+type Perm struct {
+	Owner string `json:"owner"`
+	Group string `json:"group,omitempty"`
+	Mode  uint32 `json:"mode"`
 }
 
 type NodeStatus struct {
 	Address  string
+	Rack     string
+	Zone     string
 	Lastseen time.Time
+
+	// Reported by heartbeats; zero until the first one arrives
+	Capacity        int64
+	Used            int64
+	Chunks          int64
+	ActiveTransfers int
+	FailedVolumes   int
+
+	// Set by administrators; empty, for a node never taken out of
+	// service, means AdminInService
+	AdminState       string
+	MaintenanceUntil time.Time
+
+	// Derived from heartbeats; empty means live
+	Liveness string
+}
+
+// Liveness states of a node
+const (
+	NodeLive      = ""
+	NodeSuspect   = "suspect"   // missed heartbeats; gets no new replicas
+	NodeDead      = "dead"      // replicas no longer count or get served
+	NodeRejoining = "rejoining" // back from dead, replicas being verified
+)
+
+// dead reports whether the node's replicas must be treated as lost.
+func (n NodeStatus) dead() bool {
+	return n.Liveness == NodeDead || n.Liveness == NodeRejoining
+}
+
+// Admin states of a node
+const (
+	AdminInService       = "in-service"
+	AdminDecommissioning = "decommissioning"
+	AdminDecommissioned  = "decommissioned"
+	AdminMaintenance     = "maintenance"
+)
+
+// draining reports whether the node's replicas are on their way out and
+// must not count towards a chunk's replication factor.
+func (n NodeStatus) draining() bool {
+	return n.AdminState == AdminDecommissioning || n.AdminState == AdminDecommissioned
+}
+
+// inMaintenance reports whether the node is inside a maintenance window:
+// it receives no new replicas but its existing replicas still count.
+func (n NodeStatus) inMaintenance(now time.Time) bool {
+	return n.AdminState == AdminMaintenance && now.Before(n.MaintenanceUntil)
+}
+
+// adminState is the effective admin state; expired maintenance is in service.
+func (n NodeStatus) adminState(now time.Time) string {
+	if n.AdminState == "" || n.AdminState == AdminMaintenance && !n.inMaintenance(now) {
+		return AdminInService
+	}
+	return n.AdminState
+}
+
+// utilization is the fraction of the node's disk in use, 0 when unknown.
+func (n NodeStatus) utilization() float64 {
+	if n.Capacity <= 0 {
+		return 0
+	}
+	return float64(n.Used) / float64(n.Capacity)
+}
+
+// placementWeight favours nodes with free space and few transfers in flight.
+func (n NodeStatus) placementWeight() float64 {
+	return (1 - n.utilization()) / float64(1+n.ActiveTransfers)
+}
+
+// rackKey identifies a rack across zones, since rack names may repeat.
+func (n NodeStatus) rackKey() string {
+	return n.Zone + "/" + n.Rack
 }
 
 type State struct {
 	Nodes       map[string]NodeStatus
 	Files       map[string]map[int]ChunkMetadata
+	Dirs        map[string]bool
+	Replication map[string]int     // per-file replication factor, 0 means default
+	Keys        map[string]FileKey // wrapped data keys of encrypted files
+	Perms       map[string]Perm    // paths without one are open to everyone
+	UserQuotas  map[string]Quota
+	DirQuotas   map[string]Quota
+	UserUsage   map[string]Usage // kept by account, never logged
+	DirUsage    map[string]Usage // of the directories in DirQuotas
 	Mu          sync.RWMutex
 	Replicating map[string]bool
 }
@@ -26,6 +152,14 @@ func NewState() *State {
 	return &State{
 		Nodes:       make(map[string]NodeStatus),
 		Files:       make(map[string]map[int]ChunkMetadata),
+		Dirs:        make(map[string]bool),
+		Replication: make(map[string]int),
+		Keys:        make(map[string]FileKey),
+		Perms:       make(map[string]Perm),
+		UserQuotas:  make(map[string]Quota),
+		DirQuotas:   make(map[string]Quota),
+		UserUsage:   make(map[string]Usage),
+		DirUsage:    make(map[string]Usage),
 		Replicating: make(map[string]bool),
 	}
 }
@@ -0,0 +1,50 @@
+package metadata
+
+import (
+	"sync"
+	"time"
+)
+
+// bandwidthLimiter caps the bytes per second moved to or from each node.
+// Every transfer reserves the next free slot on the node, so concurrent
+// transfers to the same node queue up behind each other.
+type bandwidthLimiter struct {
+	mu   sync.Mutex
+	rate float64 // bytes per second, 0 means unlimited
+	next map[string]time.Time
+}
+
+func newBandwidthLimiter(mbPerSec int) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		rate: float64(mbPerSec) * 1024 * 1024,
+		next: make(map[string]time.Time),
+	}
+}
+
+// wait blocks until n more bytes may move between nodes.
+func (l *bandwidthLimiter) wait(n int64, nodes ...string) {
+	time.Sleep(l.reserve(n, time.Now(), nodes...))
+}
+
+// reserve books one slot for n bytes on all of nodes, starting when the
+// busiest of them is free, and returns how long after now it starts.
+func (l *bandwidthLimiter) reserve(n int64, now time.Time, nodes ...string) time.Duration {
+	if l.rate <= 0 || n <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := now
+	for _, node := range nodes {
+		if l.next[node].After(start) {
+			start = l.next[node]
+		}
+	}
+	end := start.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	for _, node := range nodes {
+		l.next[node] = end
+	}
+	return start.Sub(now)
+}
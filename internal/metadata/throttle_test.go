@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	l := newBandwidthLimiter(1)
+	now := time.Now()
+
+	// second transfer to "a" waits for the first; "b" is independent
+	for i, want := range []struct {
+		node  string
+		delay time.Duration
+	}{
+		{"a", 0},
+		{"a", 500 * time.Millisecond},
+		{"b", 0},
+		{"a", time.Second},
+	} {
+		if d := l.reserve(512*1024, now, want.node); d != want.delay {
+			t.Fatalf("transfer %d to %s: got delay %v, want %v", i, want.node, d, want.delay)
+		}
+	}
+
+	// a move between two nodes takes one slot on both, starting when the
+	// busier one is free
+	if d := l.reserve(512*1024, now, "b", "a"); d != 1500*time.Millisecond {
+		t.Fatalf("move from b to a: got delay %v, want 1.5s", d)
+	}
+	if d := l.reserve(512*1024, now, "b"); d != 2*time.Second {
+		t.Fatalf("transfer to b after the move: got delay %v, want 2s", d)
+	}
+
+	// the slots free up as time passes
+	if d := l.reserve(512*1024, now.Add(3*time.Second), "a"); d != 0 {
+		t.Fatalf("transfer after the backlog cleared: got delay %v, want 0", d)
+	}
+	if d := newBandwidthLimiter(0).reserve(512*1024, now, "a"); d != 0 {
+		t.Fatalf("unlimited bandwidth: got delay %v, want 0", d)
+	}
+}
@@ -1,5 +1,61 @@
 package metadata
 
+import (
+	"path"
+	"strings"
+)
+
+// cleanPath normalises a namespace path: no leading or trailing slash,
+// no "." or ".." elements. The root directory is the empty string.
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// isUnder reports whether name lives somewhere below dir.
+func isUnder(name, dir string) bool {
+	return dir == "" || strings.HasPrefix(name, dir+"/")
+}
+
+func containsNode(nodes []string, id string) bool {
+	for _, n := range nodes {
+		if n == id {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceNode returns nodes with from swapped for to, leaving the input untouched.
+func replaceNode(nodes []string, from, to string) []string {
+	res := make([]string, len(nodes))
+	for i, n := range nodes {
+		if n == from {
+			n = to
+		}
+		res[i] = n
+	}
+	return res
+}
+
+// removeNode returns nodes without id, leaving the input untouched.
+func removeNode(nodes []string, id string) []string {
+	res := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n != id {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func fileSize(chunks map[int]ChunkMetadata) int64 {
+	var size int64
+	for _, c := range chunks {
+		size += c.Size
+	}
+	return size
+}
+
 func buildOrderedChunks(chunks map[int]ChunkMetadata) []ChunkMetadata {
 
 	size := len(chunks)
@@ -3,6 +3,7 @@ package metadata
 import (
 	"encoding/json"
 	"os"
+	"strconv"
 	"sync"
 )
 
@@ -36,3 +37,26 @@ func (w *WAL) Append(entry WALEntry) error {
 	_, err = w.file.Write(append(b, '\n'))
 	return err
 }
+
+// Reset empties the log and starts it again with a SNAPSHOT entry naming
+// the snapshot that holds everything logged so far.
+func (w *WAL) Reset(seq int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b, err := json.Marshal(WALEntry{
+		Type: "SNAPSHOT",
+		Data: json.RawMessage(strconv.FormatInt(seq, 10)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
@@ -21,11 +21,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type NodeCommand_Type int32
+
+const (
+	NodeCommand_UNSPECIFIED     NodeCommand_Type = 0
+	NodeCommand_REGISTER        NodeCommand_Type = 1 // register again, the metadata server does not know this node
+	NodeCommand_BLOCK_REPORT    NodeCommand_Type = 2 // send a full block report
+	NodeCommand_DELETE_CHUNKS   NodeCommand_Type = 3 // delete chunk_ids
+	NodeCommand_REPLICATE_CHUNK NodeCommand_Type = 4 // push chunk_ids[0] to target
+)
+
+// Enum value maps for NodeCommand_Type.
+var (
+	NodeCommand_Type_name = map[int32]string{
+		0: "UNSPECIFIED",
+		1: "REGISTER",
+		2: "BLOCK_REPORT",
+		3: "DELETE_CHUNKS",
+		4: "REPLICATE_CHUNK",
+	}
+	NodeCommand_Type_value = map[string]int32{
+		"UNSPECIFIED":     0,
+		"REGISTER":        1,
+		"BLOCK_REPORT":    2,
+		"DELETE_CHUNKS":   3,
+		"REPLICATE_CHUNK": 4,
+	}
+)
+
+func (x NodeCommand_Type) Enum() *NodeCommand_Type {
+	p := new(NodeCommand_Type)
+	*p = x
+	return p
+}
+
+func (x NodeCommand_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NodeCommand_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_proto_dfs_proto_enumTypes[0].Descriptor()
+}
+
+func (NodeCommand_Type) Type() protoreflect.EnumType {
+	return &file_internal_proto_dfs_proto_enumTypes[0]
+}
+
+func (x NodeCommand_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NodeCommand_Type.Descriptor instead.
+func (NodeCommand_Type) EnumDescriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{1, 0}
+}
+
 type NodeHeartbeat struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	NodeId          string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	CapacityBytes   int64                  `protobuf:"varint,2,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"`
+	UsedBytes       int64                  `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	ChunkCount      int64                  `protobuf:"varint,4,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"`
+	ActiveTransfers int32                  `protobuf:"varint,5,opt,name=active_transfers,json=activeTransfers,proto3" json:"active_transfers,omitempty"`
+	FailedVolumes   int32                  `protobuf:"varint,6,opt,name=failed_volumes,json=failedVolumes,proto3" json:"failed_volumes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *NodeHeartbeat) Reset() {
@@ -65,28 +125,67 @@ func (x *NodeHeartbeat) GetNodeId() string {
 	return ""
 }
 
-type NodeInfo struct {
+func (x *NodeHeartbeat) GetCapacityBytes() int64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
+}
+
+func (x *NodeHeartbeat) GetUsedBytes() int64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *NodeHeartbeat) GetChunkCount() int64 {
+	if x != nil {
+		return x.ChunkCount
+	}
+	return 0
+}
+
+func (x *NodeHeartbeat) GetActiveTransfers() int32 {
+	if x != nil {
+		return x.ActiveTransfers
+	}
+	return 0
+}
+
+func (x *NodeHeartbeat) GetFailedVolumes() int32 {
+	if x != nil {
+		return x.FailedVolumes
+	}
+	return 0
+}
+
+// NodeCommand is an instruction for a DataNode, delivered with the
+// response to its heartbeat.
+type NodeCommand struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Type          NodeCommand_Type       `protobuf:"varint,1,opt,name=type,proto3,enum=dfs.NodeCommand_Type" json:"type,omitempty"`
+	ChunkIds      []string               `protobuf:"bytes,2,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`
+	Target        string                 `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"` // REPLICATE_CHUNK: address of the DataNode to copy to
+	Token         string                 `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`   // REPLICATE_CHUNK: replicate token naming the target
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NodeInfo) Reset() {
-	*x = NodeInfo{}
+func (x *NodeCommand) Reset() {
+	*x = NodeCommand{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NodeInfo) String() string {
+func (x *NodeCommand) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NodeInfo) ProtoMessage() {}
+func (*NodeCommand) ProtoMessage() {}
 
-func (x *NodeInfo) ProtoReflect() protoreflect.Message {
+func (x *NodeCommand) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -98,46 +197,61 @@ func (x *NodeInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
-func (*NodeInfo) Descriptor() ([]byte, []int) {
+// Deprecated: Use NodeCommand.ProtoReflect.Descriptor instead.
+func (*NodeCommand) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *NodeInfo) GetNodeId() string {
+func (x *NodeCommand) GetType() NodeCommand_Type {
 	if x != nil {
-		return x.NodeId
+		return x.Type
+	}
+	return NodeCommand_UNSPECIFIED
+}
+
+func (x *NodeCommand) GetChunkIds() []string {
+	if x != nil {
+		return x.ChunkIds
+	}
+	return nil
+}
+
+func (x *NodeCommand) GetTarget() string {
+	if x != nil {
+		return x.Target
 	}
 	return ""
 }
 
-func (x *NodeInfo) GetAddress() string {
+func (x *NodeCommand) GetToken() string {
 	if x != nil {
-		return x.Address
+		return x.Token
 	}
 	return ""
 }
 
-type FileRequest struct {
+type HeartbeatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Commands      []*NodeCommand         `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileRequest) Reset() {
-	*x = FileRequest{}
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileRequest) String() string {
+func (x *HeartbeatResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileRequest) ProtoMessage() {}
+func (*HeartbeatResponse) ProtoMessage() {}
 
-func (x *FileRequest) ProtoReflect() protoreflect.Message {
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -149,40 +263,47 @@ func (x *FileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
-func (*FileRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *FileRequest) GetFilename() string {
+func (x *HeartbeatResponse) GetOk() bool {
 	if x != nil {
-		return x.Filename
+		return x.Ok
 	}
-	return ""
+	return false
 }
 
-type Chunk struct {
+func (x *HeartbeatResponse) GetCommands() []*NodeCommand {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+type BlockReportRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	ChunkIds      []string               `protobuf:"bytes,2,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Chunk) Reset() {
-	*x = Chunk{}
+func (x *BlockReportRequest) Reset() {
+	*x = BlockReportRequest{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Chunk) String() string {
+func (x *BlockReportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Chunk) ProtoMessage() {}
+func (*BlockReportRequest) ProtoMessage() {}
 
-func (x *Chunk) ProtoReflect() protoreflect.Message {
+func (x *BlockReportRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -194,46 +315,49 @@ func (x *Chunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
-func (*Chunk) Descriptor() ([]byte, []int) {
+// Deprecated: Use BlockReportRequest.ProtoReflect.Descriptor instead.
+func (*BlockReportRequest) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *Chunk) GetChunkId() string {
+func (x *BlockReportRequest) GetNodeId() string {
 	if x != nil {
-		return x.ChunkId
+		return x.NodeId
 	}
 	return ""
 }
 
-func (x *Chunk) GetData() []byte {
+func (x *BlockReportRequest) GetChunkIds() []string {
 	if x != nil {
-		return x.Data
+		return x.ChunkIds
 	}
 	return nil
 }
 
-type ChunkRequest struct {
+type NodeInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Rack          string                 `protobuf:"bytes,3,opt,name=rack,proto3" json:"rack,omitempty"`
+	Zone          string                 `protobuf:"bytes,4,opt,name=zone,proto3" json:"zone,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ChunkRequest) Reset() {
-	*x = ChunkRequest{}
+func (x *NodeInfo) Reset() {
+	*x = NodeInfo{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ChunkRequest) String() string {
+func (x *NodeInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ChunkRequest) ProtoMessage() {}
+func (*NodeInfo) ProtoMessage() {}
 
-func (x *ChunkRequest) ProtoReflect() protoreflect.Message {
+func (x *NodeInfo) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -245,41 +369,65 @@ func (x *ChunkRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ChunkRequest.ProtoReflect.Descriptor instead.
-func (*ChunkRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
+func (*NodeInfo) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *ChunkRequest) GetChunkId() string {
+func (x *NodeInfo) GetNodeId() string {
 	if x != nil {
-		return x.ChunkId
+		return x.NodeId
 	}
 	return ""
 }
 
-type AllocateChunkRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	ChunkIndex    int32                  `protobuf:"varint,3,opt,name=chunk_index,json=chunkIndex,proto3" json:"chunk_index,omitempty"`
+func (x *NodeInfo) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetRack() string {
+	if x != nil {
+		return x.Rack
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+type FileRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Filename    string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Replication int32                  `protobuf:"varint,2,opt,name=replication,proto3" json:"replication,omitempty"` // 0 means the server default
+	// Set by CreateFile when the client encrypts the file: its data key,
+	// wrapped by the client's master key, and the ID of that master key
+	WrappedKey    []byte `protobuf:"bytes,3,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"`
+	KeyId         string `protobuf:"bytes,4,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AllocateChunkRequest) Reset() {
-	*x = AllocateChunkRequest{}
+func (x *FileRequest) Reset() {
+	*x = FileRequest{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AllocateChunkRequest) String() string {
+func (x *FileRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AllocateChunkRequest) ProtoMessage() {}
+func (*FileRequest) ProtoMessage() {}
 
-func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
+func (x *FileRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -291,54 +439,62 @@ func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AllocateChunkRequest.ProtoReflect.Descriptor instead.
-func (*AllocateChunkRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
+func (*FileRequest) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *AllocateChunkRequest) GetChunkId() string {
+func (x *FileRequest) GetFilename() string {
 	if x != nil {
-		return x.ChunkId
+		return x.Filename
 	}
 	return ""
 }
 
-func (x *AllocateChunkRequest) GetFilename() string {
+func (x *FileRequest) GetReplication() int32 {
 	if x != nil {
-		return x.Filename
+		return x.Replication
 	}
-	return ""
+	return 0
 }
 
-func (x *AllocateChunkRequest) GetChunkIndex() int32 {
+func (x *FileRequest) GetWrappedKey() []byte {
 	if x != nil {
-		return x.ChunkIndex
+		return x.WrappedKey
 	}
-	return 0
+	return nil
 }
 
-type FileMetadata struct {
+func (x *FileRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type Chunk struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Chunks        []*ChunkMetadata       `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"` // chunk access token from AllocateChunk
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileMetadata) Reset() {
-	*x = FileMetadata{}
+func (x *Chunk) Reset() {
+	*x = Chunk{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileMetadata) String() string {
+func (x *Chunk) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileMetadata) ProtoMessage() {}
+func (*Chunk) ProtoMessage() {}
 
-func (x *FileMetadata) ProtoReflect() protoreflect.Message {
+func (x *Chunk) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -350,47 +506,56 @@ func (x *FileMetadata) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileMetadata.ProtoReflect.Descriptor instead.
-func (*FileMetadata) Descriptor() ([]byte, []int) {
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *FileMetadata) GetFilename() string {
+func (x *Chunk) GetChunkId() string {
 	if x != nil {
-		return x.Filename
+		return x.ChunkId
 	}
 	return ""
 }
 
-func (x *FileMetadata) GetChunks() []*ChunkMetadata {
+func (x *Chunk) GetData() []byte {
 	if x != nil {
-		return x.Chunks
+		return x.Data
 	}
 	return nil
 }
 
-type ChunkMetadata struct {
+func (x *Chunk) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ChunkRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
-	Nodes         []string               `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"` // 0 reads the whole chunk
+	Token         string                 `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`    // chunk access token from GetFile
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ChunkMetadata) Reset() {
-	*x = ChunkMetadata{}
+func (x *ChunkRequest) Reset() {
+	*x = ChunkRequest{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ChunkMetadata) String() string {
+func (x *ChunkRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ChunkMetadata) ProtoMessage() {}
+func (*ChunkRequest) ProtoMessage() {}
 
-func (x *ChunkMetadata) ProtoReflect() protoreflect.Message {
+func (x *ChunkRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -402,46 +567,60 @@ func (x *ChunkMetadata) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ChunkMetadata.ProtoReflect.Descriptor instead.
-func (*ChunkMetadata) Descriptor() ([]byte, []int) {
+// Deprecated: Use ChunkRequest.ProtoReflect.Descriptor instead.
+func (*ChunkRequest) Descriptor() ([]byte, []int) {
 	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *ChunkMetadata) GetChunkId() string {
+func (x *ChunkRequest) GetChunkId() string {
 	if x != nil {
 		return x.ChunkId
 	}
 	return ""
 }
 
-func (x *ChunkMetadata) GetNodes() []string {
+func (x *ChunkRequest) GetOffset() int64 {
 	if x != nil {
-		return x.Nodes
+		return x.Offset
 	}
-	return nil
+	return 0
 }
 
-type Ack struct {
+func (x *ChunkRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ChunkRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ChunkList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ChunkIds      []string               `protobuf:"bytes,1,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Ack) Reset() {
-	*x = Ack{}
+func (x *ChunkList) Reset() {
+	*x = ChunkList{}
 	mi := &file_internal_proto_dfs_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Ack) String() string {
+func (x *ChunkList) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Ack) ProtoMessage() {}
+func (*ChunkList) ProtoMessage() {}
 
-func (x *Ack) ProtoReflect() protoreflect.Message {
+func (x *ChunkList) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_dfs_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -453,9 +632,1748 @@ func (x *Ack) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
+// Deprecated: Use ChunkList.ProtoReflect.Descriptor instead.
+func (*ChunkList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ChunkList) GetChunkIds() []string {
+	if x != nil {
+		return x.ChunkIds
+	}
+	return nil
+}
+
+type AllocateChunkRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId    string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Filename   string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	ChunkIndex int32                  `protobuf:"varint,3,opt,name=chunk_index,json=chunkIndex,proto3" json:"chunk_index,omitempty"`
+	Size       int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Codec      string                 `protobuf:"bytes,5,opt,name=codec,proto3" json:"codec,omitempty"`                              // compression of the stored bytes, empty for none
+	StoredSize int64                  `protobuf:"varint,6,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"` // bytes on the DataNodes when compressed
+	// DataNodes, by address, that failed to store the chunk: a chunk
+	// still being uploaded is moved to others
+	ExcludeNodes  []string `protobuf:"bytes,7,rep,name=exclude_nodes,json=excludeNodes,proto3" json:"exclude_nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateChunkRequest) Reset() {
+	*x = AllocateChunkRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateChunkRequest) ProtoMessage() {}
+
+func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateChunkRequest.ProtoReflect.Descriptor instead.
+func (*AllocateChunkRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AllocateChunkRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *AllocateChunkRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *AllocateChunkRequest) GetChunkIndex() int32 {
+	if x != nil {
+		return x.ChunkIndex
+	}
+	return 0
+}
+
+func (x *AllocateChunkRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *AllocateChunkRequest) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *AllocateChunkRequest) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *AllocateChunkRequest) GetExcludeNodes() []string {
+	if x != nil {
+		return x.ExcludeNodes
+	}
+	return nil
+}
+
+type FileMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Chunks        []*ChunkMetadata       `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Replication   int32                  `protobuf:"varint,3,opt,name=replication,proto3" json:"replication,omitempty"`
+	WrappedKey    []byte                 `protobuf:"bytes,4,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"` // empty unless the file is encrypted
+	KeyId         string                 `protobuf:"bytes,5,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileMetadata) Reset() {
+	*x = FileMetadata{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileMetadata) ProtoMessage() {}
+
+func (x *FileMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileMetadata.ProtoReflect.Descriptor instead.
+func (*FileMetadata) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FileMetadata) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetChunks() []*ChunkMetadata {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetWrappedKey() []byte {
+	if x != nil {
+		return x.WrappedKey
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type ChunkMetadata struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId    string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Nodes      []string               `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Size       int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Packed     bool                   `protobuf:"varint,4,opt,name=packed,proto3" json:"packed,omitempty"` // the file is size bytes at offset in a shared container
+	Offset     int64                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	Codec      string                 `protobuf:"bytes,6,opt,name=codec,proto3" json:"codec,omitempty"` // size is the uncompressed length
+	StoredSize int64                  `protobuf:"varint,7,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"`
+	// Lets the holder read the chunk, or write it when returned by
+	// AllocateChunk, until it expires; empty when the cluster runs
+	// without chunk tokens
+	Token         string `protobuf:"bytes,8,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChunkMetadata) Reset() {
+	*x = ChunkMetadata{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkMetadata) ProtoMessage() {}
+
+func (x *ChunkMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkMetadata.ProtoReflect.Descriptor instead.
+func (*ChunkMetadata) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ChunkMetadata) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ChunkMetadata) GetNodes() []string {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *ChunkMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ChunkMetadata) GetPacked() bool {
+	if x != nil {
+		return x.Packed
+	}
+	return false
+}
+
+func (x *ChunkMetadata) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ChunkMetadata) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *ChunkMetadata) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *ChunkMetadata) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// AllocateContainerRequest asks for replica nodes for a container chunk
+// that small files are packed into.
+type AllocateContainerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Replication   int32                  `protobuf:"varint,3,opt,name=replication,proto3" json:"replication,omitempty"`                      // 0 means the server default
+	ExcludeNodes  []string               `protobuf:"bytes,4,rep,name=exclude_nodes,json=excludeNodes,proto3" json:"exclude_nodes,omitempty"` // as in AllocateChunkRequest
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateContainerRequest) Reset() {
+	*x = AllocateContainerRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateContainerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateContainerRequest) ProtoMessage() {}
+
+func (x *AllocateContainerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateContainerRequest.ProtoReflect.Descriptor instead.
+func (*AllocateContainerRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AllocateContainerRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *AllocateContainerRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *AllocateContainerRequest) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *AllocateContainerRequest) GetExcludeNodes() []string {
+	if x != nil {
+		return x.ExcludeNodes
+	}
+	return nil
+}
+
+type PackedFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackedFile) Reset() {
+	*x = PackedFile{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackedFile) ProtoMessage() {}
+
+func (x *PackedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackedFile.ProtoReflect.Descriptor instead.
+func (*PackedFile) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PackedFile) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PackedFile) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *PackedFile) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+// PackFilesRequest creates files stored in a container once it is written.
+type PackFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContainerId   string                 `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	Files         []*PackedFile          `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackFilesRequest) Reset() {
+	*x = PackFilesRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackFilesRequest) ProtoMessage() {}
+
+func (x *PackFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackFilesRequest.ProtoReflect.Descriptor instead.
+func (*PackFilesRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PackFilesRequest) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+func (x *PackFilesRequest) GetFiles() []*PackedFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Recursive     bool                   `protobuf:"varint,2,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+type FileInfo struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Filename    string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	IsDir       bool                   `protobuf:"varint,2,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
+	Size        int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	NumChunks   int32                  `protobuf:"varint,4,opt,name=num_chunks,json=numChunks,proto3" json:"num_chunks,omitempty"`
+	Replication int32                  `protobuf:"varint,5,opt,name=replication,proto3" json:"replication,omitempty"`
+	Packed      bool                   `protobuf:"varint,6,opt,name=packed,proto3" json:"packed,omitempty"`
+	StoredSize  int64                  `protobuf:"varint,7,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"` // bytes per replica after compression
+	KeyId       string                 `protobuf:"bytes,8,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`                 // master key of an encrypted file
+	// Empty owner and zero mode when nobody owns the path and anyone may
+	// use it
+	Owner         string `protobuf:"bytes,9,opt,name=owner,proto3" json:"owner,omitempty"`
+	Group         string `protobuf:"bytes,10,opt,name=group,proto3" json:"group,omitempty"`
+	Mode          uint32 `protobuf:"varint,11,opt,name=mode,proto3" json:"mode,omitempty"` // permission bits, as in 0644
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FileInfo) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileInfo) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetNumChunks() int32 {
+	if x != nil {
+		return x.NumChunks
+	}
+	return 0
+}
+
+func (x *FileInfo) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *FileInfo) GetPacked() bool {
+	if x != nil {
+		return x.Packed
+	}
+	return false
+}
+
+func (x *FileInfo) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *FileInfo) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *FileInfo) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *FileInfo) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *FileInfo) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+type FileList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileList) Reset() {
+	*x = FileList{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileList) ProtoMessage() {}
+
+func (x *FileList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileList.ProtoReflect.Descriptor instead.
+func (*FileList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FileList) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type SetReplicationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Replication   int32                  `protobuf:"varint,2,opt,name=replication,proto3" json:"replication,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetReplicationRequest) Reset() {
+	*x = SetReplicationRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetReplicationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetReplicationRequest) ProtoMessage() {}
+
+func (x *SetReplicationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetReplicationRequest.ProtoReflect.Descriptor instead.
+func (*SetReplicationRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SetReplicationRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *SetReplicationRequest) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+type ChmodRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Mode          uint32                 `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChmodRequest) Reset() {
+	*x = ChmodRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChmodRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChmodRequest) ProtoMessage() {}
+
+func (x *ChmodRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChmodRequest.ProtoReflect.Descriptor instead.
+func (*ChmodRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ChmodRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ChmodRequest) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+type ChownRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"` // empty leaves the owner unchanged
+	Group         string                 `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"` // empty leaves the group unchanged
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChownRequest) Reset() {
+	*x = ChownRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChownRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChownRequest) ProtoMessage() {}
+
+func (x *ChownRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChownRequest.ProtoReflect.Descriptor instead.
+func (*ChownRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ChownRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ChownRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ChownRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// SetQuotaRequest limits the files of a user, or those below a directory.
+// Zero limits remove the quota.
+type SetQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Dir           string                 `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,3,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"` // counting every replica
+	MaxFiles      int64                  `protobuf:"varint,4,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetQuotaRequest) Reset() {
+	*x = SetQuotaRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetQuotaRequest) ProtoMessage() {}
+
+func (x *SetQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetQuotaRequest.ProtoReflect.Descriptor instead.
+func (*SetQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SetQuotaRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *SetQuotaRequest) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *SetQuotaRequest) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *SetQuotaRequest) GetMaxFiles() int64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+// QuotaRequest asks for the quota of a user, or the quotas on a path and
+// the directories above it. Leaving both empty asks for the caller's and
+// every directory's quota.
+type QuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Dir           string                 `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaRequest) Reset() {
+	*x = QuotaRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaRequest) ProtoMessage() {}
+
+func (x *QuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaRequest.ProtoReflect.Descriptor instead.
+func (*QuotaRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *QuotaRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *QuotaRequest) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+type Quota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Dir           string                 `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,3,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"` // 0 means unlimited
+	MaxFiles      int64                  `protobuf:"varint,4,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`
+	Bytes         int64                  `protobuf:"varint,5,opt,name=bytes,proto3" json:"bytes,omitempty"` // in use
+	Files         int64                  `protobuf:"varint,6,opt,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quota) Reset() {
+	*x = Quota{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quota) ProtoMessage() {}
+
+func (x *Quota) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quota.ProtoReflect.Descriptor instead.
+func (*Quota) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Quota) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Quota) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *Quota) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *Quota) GetMaxFiles() int64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+func (x *Quota) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Quota) GetFiles() int64 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+type QuotaList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quotas        []*Quota               `protobuf:"bytes,1,rep,name=quotas,proto3" json:"quotas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaList) Reset() {
+	*x = QuotaList{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaList) ProtoMessage() {}
+
+func (x *QuotaList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaList.ProtoReflect.Descriptor instead.
+func (*QuotaList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *QuotaList) GetQuotas() []*Quota {
+	if x != nil {
+		return x.Quotas
+	}
+	return nil
+}
+
+type ReplicateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"` // address of the DataNode to copy to
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`   // read access to the chunk
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateRequest) Reset() {
+	*x = ReplicateRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateRequest) ProtoMessage() {}
+
+func (x *ReplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ReplicateRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ReplicateRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *ReplicateRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type BalanceRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ThresholdPercent float64                `protobuf:"fixed64,1,opt,name=threshold_percent,json=thresholdPercent,proto3" json:"threshold_percent,omitempty"`
+	MaxBytes         int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	DryRun           bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BalanceRequest) Reset() {
+	*x = BalanceRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceRequest) ProtoMessage() {}
+
+func (x *BalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceRequest.ProtoReflect.Descriptor instead.
+func (*BalanceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BalanceRequest) GetThresholdPercent() float64 {
+	if x != nil {
+		return x.ThresholdPercent
+	}
+	return 0
+}
+
+func (x *BalanceRequest) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *BalanceRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type ReplicaMove struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicaMove) Reset() {
+	*x = ReplicaMove{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicaMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicaMove) ProtoMessage() {}
+
+func (x *ReplicaMove) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicaMove.ProtoReflect.Descriptor instead.
+func (*ReplicaMove) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ReplicaMove) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ReplicaMove) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ReplicaMove) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *ReplicaMove) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type BalanceReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Moves         []*ReplicaMove         `protobuf:"bytes,1,rep,name=moves,proto3" json:"moves,omitempty"`
+	Bytes         int64                  `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BalanceReport) Reset() {
+	*x = BalanceReport{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceReport) ProtoMessage() {}
+
+func (x *BalanceReport) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceReport.ProtoReflect.Descriptor instead.
+func (*BalanceReport) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *BalanceReport) GetMoves() []*ReplicaMove {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *BalanceReport) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+type NodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeRequest) Reset() {
+	*x = NodeRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeRequest) ProtoMessage() {}
+
+func (x *NodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeRequest.ProtoReflect.Descriptor instead.
+func (*NodeRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *NodeRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+type MaintenanceRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	NodeId          string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	DurationSeconds int64                  `protobuf:"varint,2,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"` // 0 ends maintenance
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MaintenanceRequest) Reset() {
+	*x = MaintenanceRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MaintenanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MaintenanceRequest) ProtoMessage() {}
+
+func (x *MaintenanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MaintenanceRequest.ProtoReflect.Descriptor instead.
+func (*MaintenanceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *MaintenanceRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *MaintenanceRequest) GetDurationSeconds() int64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+type ListNodesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNodesRequest) Reset() {
+	*x = ListNodesRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNodesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNodesRequest) ProtoMessage() {}
+
+func (x *ListNodesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNodesRequest.ProtoReflect.Descriptor instead.
+func (*ListNodesRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{31}
+}
+
+type NodeReport struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	NodeId           string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address          string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Rack             string                 `protobuf:"bytes,3,opt,name=rack,proto3" json:"rack,omitempty"`
+	Zone             string                 `protobuf:"bytes,4,opt,name=zone,proto3" json:"zone,omitempty"`
+	AdminState       string                 `protobuf:"bytes,5,opt,name=admin_state,json=adminState,proto3" json:"admin_state,omitempty"`
+	MaintenanceUntil int64                  `protobuf:"varint,6,opt,name=maintenance_until,json=maintenanceUntil,proto3" json:"maintenance_until,omitempty"` // unix seconds
+	CapacityBytes    int64                  `protobuf:"varint,7,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"`
+	UsedBytes        int64                  `protobuf:"varint,8,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	Replicas         int64                  `protobuf:"varint,9,opt,name=replicas,proto3" json:"replicas,omitempty"`
+	LastSeen         int64                  `protobuf:"varint,10,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // unix seconds
+	Liveness         string                 `protobuf:"bytes,11,opt,name=liveness,proto3" json:"liveness,omitempty"`                  // live, suspect, dead or rejoining
+	FailedVolumes    int32                  `protobuf:"varint,12,opt,name=failed_volumes,json=failedVolumes,proto3" json:"failed_volumes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *NodeReport) Reset() {
+	*x = NodeReport{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeReport) ProtoMessage() {}
+
+func (x *NodeReport) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeReport.ProtoReflect.Descriptor instead.
+func (*NodeReport) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *NodeReport) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *NodeReport) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *NodeReport) GetRack() string {
+	if x != nil {
+		return x.Rack
+	}
+	return ""
+}
+
+func (x *NodeReport) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *NodeReport) GetAdminState() string {
+	if x != nil {
+		return x.AdminState
+	}
+	return ""
+}
+
+func (x *NodeReport) GetMaintenanceUntil() int64 {
+	if x != nil {
+		return x.MaintenanceUntil
+	}
+	return 0
+}
+
+func (x *NodeReport) GetCapacityBytes() int64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
+}
+
+func (x *NodeReport) GetUsedBytes() int64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *NodeReport) GetReplicas() int64 {
+	if x != nil {
+		return x.Replicas
+	}
+	return 0
+}
+
+func (x *NodeReport) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *NodeReport) GetLiveness() string {
+	if x != nil {
+		return x.Liveness
+	}
+	return ""
+}
+
+func (x *NodeReport) GetFailedVolumes() int32 {
+	if x != nil {
+		return x.FailedVolumes
+	}
+	return 0
+}
+
+type NodeList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*NodeReport          `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeList) Reset() {
+	*x = NodeList{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeList) ProtoMessage() {}
+
+func (x *NodeList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeList.ProtoReflect.Descriptor instead.
+func (*NodeList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *NodeList) GetNodes() []*NodeReport {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type RenameRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Src           string                 `protobuf:"bytes,1,opt,name=src,proto3" json:"src,omitempty"`
+	Dst           string                 `protobuf:"bytes,2,opt,name=dst,proto3" json:"dst,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameRequest) Reset() {
+	*x = RenameRequest{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameRequest) ProtoMessage() {}
+
+func (x *RenameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameRequest.ProtoReflect.Descriptor instead.
+func (*RenameRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RenameRequest) GetSrc() string {
+	if x != nil {
+		return x.Src
+	}
+	return ""
+}
+
+func (x *RenameRequest) GetDst() string {
+	if x != nil {
+		return x.Dst
+	}
+	return ""
+}
+
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_internal_proto_dfs_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_dfs_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
 // Deprecated: Use Ack.ProtoReflect.Descriptor instead.
 func (*Ack) Descriptor() ([]byte, []int) {
-	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{8}
+	return file_internal_proto_dfs_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *Ack) GetOk() bool {
@@ -469,45 +2387,225 @@ var File_internal_proto_dfs_proto protoreflect.FileDescriptor
 
 const file_internal_proto_dfs_proto_rawDesc = "" +
 	"\n" +
-	"\x18internal/proto/dfs.proto\x12\x03dfs\"(\n" +
+	"\x18internal/proto/dfs.proto\x12\x03dfs\"\xe1\x01\n" +
 	"\rNodeHeartbeat\x12\x17\n" +
-	"\anode_id\x18\x01 \x01(\tR\x06nodeId\"=\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12%\n" +
+	"\x0ecapacity_bytes\x18\x02 \x01(\x03R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x03 \x01(\x03R\tusedBytes\x12\x1f\n" +
+	"\vchunk_count\x18\x04 \x01(\x03R\n" +
+	"chunkCount\x12)\n" +
+	"\x10active_transfers\x18\x05 \x01(\x05R\x0factiveTransfers\x12%\n" +
+	"\x0efailed_volumes\x18\x06 \x01(\x05R\rfailedVolumes\"\xe4\x01\n" +
+	"\vNodeCommand\x12)\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x15.dfs.NodeCommand.TypeR\x04type\x12\x1b\n" +
+	"\tchunk_ids\x18\x02 \x03(\tR\bchunkIds\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\tR\x06target\x12\x14\n" +
+	"\x05token\x18\x04 \x01(\tR\x05token\"_\n" +
+	"\x04Type\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\f\n" +
+	"\bREGISTER\x10\x01\x12\x10\n" +
+	"\fBLOCK_REPORT\x10\x02\x12\x11\n" +
+	"\rDELETE_CHUNKS\x10\x03\x12\x13\n" +
+	"\x0fREPLICATE_CHUNK\x10\x04\"Q\n" +
+	"\x11HeartbeatResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12,\n" +
+	"\bcommands\x18\x02 \x03(\v2\x10.dfs.NodeCommandR\bcommands\"J\n" +
+	"\x12BlockReportRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1b\n" +
+	"\tchunk_ids\x18\x02 \x03(\tR\bchunkIds\"e\n" +
 	"\bNodeInfo\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\")\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04rack\x18\x03 \x01(\tR\x04rack\x12\x12\n" +
+	"\x04zone\x18\x04 \x01(\tR\x04zone\"\x83\x01\n" +
 	"\vFileRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\"6\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12 \n" +
+	"\vreplication\x18\x02 \x01(\x05R\vreplication\x12\x1f\n" +
+	"\vwrapped_key\x18\x03 \x01(\fR\n" +
+	"wrappedKey\x12\x15\n" +
+	"\x06key_id\x18\x04 \x01(\tR\x05keyId\"L\n" +
 	"\x05Chunk\x12\x19\n" +
 	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\")\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\"o\n" +
 	"\fChunkRequest\x12\x19\n" +
-	"\bchunk_id\x18\x01 \x01(\tR\achunkId\"n\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x14\n" +
+	"\x05token\x18\x04 \x01(\tR\x05token\"(\n" +
+	"\tChunkList\x12\x1b\n" +
+	"\tchunk_ids\x18\x01 \x03(\tR\bchunkIds\"\xde\x01\n" +
 	"\x14AllocateChunkRequest\x12\x19\n" +
 	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x1a\n" +
 	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1f\n" +
 	"\vchunk_index\x18\x03 \x01(\x05R\n" +
-	"chunkIndex\"V\n" +
+	"chunkIndex\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05codec\x18\x05 \x01(\tR\x05codec\x12\x1f\n" +
+	"\vstored_size\x18\x06 \x01(\x03R\n" +
+	"storedSize\x12#\n" +
+	"\rexclude_nodes\x18\a \x03(\tR\fexcludeNodes\"\xb0\x01\n" +
 	"\fFileMetadata\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12*\n" +
-	"\x06chunks\x18\x02 \x03(\v2\x12.dfs.ChunkMetadataR\x06chunks\"@\n" +
+	"\x06chunks\x18\x02 \x03(\v2\x12.dfs.ChunkMetadataR\x06chunks\x12 \n" +
+	"\vreplication\x18\x03 \x01(\x05R\vreplication\x12\x1f\n" +
+	"\vwrapped_key\x18\x04 \x01(\fR\n" +
+	"wrappedKey\x12\x15\n" +
+	"\x06key_id\x18\x05 \x01(\tR\x05keyId\"\xd1\x01\n" +
 	"\rChunkMetadata\x12\x19\n" +
 	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x14\n" +
-	"\x05nodes\x18\x02 \x03(\tR\x05nodes\"\x15\n" +
+	"\x05nodes\x18\x02 \x03(\tR\x05nodes\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06packed\x18\x04 \x01(\bR\x06packed\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x03R\x06offset\x12\x14\n" +
+	"\x05codec\x18\x06 \x01(\tR\x05codec\x12\x1f\n" +
+	"\vstored_size\x18\a \x01(\x03R\n" +
+	"storedSize\x12\x14\n" +
+	"\x05token\x18\b \x01(\tR\x05token\"\x90\x01\n" +
+	"\x18AllocateContainerRequest\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12 \n" +
+	"\vreplication\x18\x03 \x01(\x05R\vreplication\x12#\n" +
+	"\rexclude_nodes\x18\x04 \x03(\tR\fexcludeNodes\"X\n" +
+	"\n" +
+	"PackedFile\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\"\\\n" +
+	"\x10PackFilesRequest\x12!\n" +
+	"\fcontainer_id\x18\x01 \x01(\tR\vcontainerId\x12%\n" +
+	"\x05files\x18\x02 \x03(\v2\x0f.dfs.PackedFileR\x05files\"C\n" +
+	"\vListRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1c\n" +
+	"\trecursive\x18\x02 \x01(\bR\trecursive\"\xa2\x02\n" +
+	"\bFileInfo\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x15\n" +
+	"\x06is_dir\x18\x02 \x01(\bR\x05isDir\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x1d\n" +
+	"\n" +
+	"num_chunks\x18\x04 \x01(\x05R\tnumChunks\x12 \n" +
+	"\vreplication\x18\x05 \x01(\x05R\vreplication\x12\x16\n" +
+	"\x06packed\x18\x06 \x01(\bR\x06packed\x12\x1f\n" +
+	"\vstored_size\x18\a \x01(\x03R\n" +
+	"storedSize\x12\x15\n" +
+	"\x06key_id\x18\b \x01(\tR\x05keyId\x12\x14\n" +
+	"\x05owner\x18\t \x01(\tR\x05owner\x12\x14\n" +
+	"\x05group\x18\n" +
+	" \x01(\tR\x05group\x12\x12\n" +
+	"\x04mode\x18\v \x01(\rR\x04mode\"/\n" +
+	"\bFileList\x12#\n" +
+	"\x05files\x18\x01 \x03(\v2\r.dfs.FileInfoR\x05files\"U\n" +
+	"\x15SetReplicationRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12 \n" +
+	"\vreplication\x18\x02 \x01(\x05R\vreplication\">\n" +
+	"\fChmodRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\rR\x04mode\"V\n" +
+	"\fChownRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x14\n" +
+	"\x05group\x18\x03 \x01(\tR\x05group\"q\n" +
+	"\x0fSetQuotaRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03dir\x18\x02 \x01(\tR\x03dir\x12\x1b\n" +
+	"\tmax_bytes\x18\x03 \x01(\x03R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x04 \x01(\x03R\bmaxFiles\"4\n" +
+	"\fQuotaRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03dir\x18\x02 \x01(\tR\x03dir\"\x93\x01\n" +
+	"\x05Quota\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03dir\x18\x02 \x01(\tR\x03dir\x12\x1b\n" +
+	"\tmax_bytes\x18\x03 \x01(\x03R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x04 \x01(\x03R\bmaxFiles\x12\x14\n" +
+	"\x05bytes\x18\x05 \x01(\x03R\x05bytes\x12\x14\n" +
+	"\x05files\x18\x06 \x01(\x03R\x05files\"/\n" +
+	"\tQuotaList\x12\"\n" +
+	"\x06quotas\x18\x01 \x03(\v2\n" +
+	".dfs.QuotaR\x06quotas\"[\n" +
+	"\x10ReplicateRequest\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\"s\n" +
+	"\x0eBalanceRequest\x12+\n" +
+	"\x11threshold_percent\x18\x01 \x01(\x01R\x10thresholdPercent\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\"`\n" +
+	"\vReplicaMove\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\"M\n" +
+	"\rBalanceReport\x12&\n" +
+	"\x05moves\x18\x01 \x03(\v2\x10.dfs.ReplicaMoveR\x05moves\x12\x14\n" +
+	"\x05bytes\x18\x02 \x01(\x03R\x05bytes\"&\n" +
+	"\vNodeRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\"X\n" +
+	"\x12MaintenanceRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12)\n" +
+	"\x10duration_seconds\x18\x02 \x01(\x03R\x0fdurationSeconds\"\x12\n" +
+	"\x10ListNodesRequest\"\xf7\x02\n" +
+	"\n" +
+	"NodeReport\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04rack\x18\x03 \x01(\tR\x04rack\x12\x12\n" +
+	"\x04zone\x18\x04 \x01(\tR\x04zone\x12\x1f\n" +
+	"\vadmin_state\x18\x05 \x01(\tR\n" +
+	"adminState\x12+\n" +
+	"\x11maintenance_until\x18\x06 \x01(\x03R\x10maintenanceUntil\x12%\n" +
+	"\x0ecapacity_bytes\x18\a \x01(\x03R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\b \x01(\x03R\tusedBytes\x12\x1a\n" +
+	"\breplicas\x18\t \x01(\x03R\breplicas\x12\x1b\n" +
+	"\tlast_seen\x18\n" +
+	" \x01(\x03R\blastSeen\x12\x1a\n" +
+	"\bliveness\x18\v \x01(\tR\bliveness\x12%\n" +
+	"\x0efailed_volumes\x18\f \x01(\x05R\rfailedVolumes\"1\n" +
+	"\bNodeList\x12%\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x0f.dfs.NodeReportR\x05nodes\"3\n" +
+	"\rRenameRequest\x12\x10\n" +
+	"\x03src\x18\x01 \x01(\tR\x03src\x12\x10\n" +
+	"\x03dst\x18\x02 \x01(\tR\x03dst\"\x15\n" +
 	"\x03Ack\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok2\x88\x02\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok2\xe9\b\n" +
 	"\x0fMetadataService\x12'\n" +
 	"\fRegisterNode\x12\r.dfs.NodeInfo\x1a\b.dfs.Ack\x121\n" +
 	"\n" +
 	"CreateFile\x12\x10.dfs.FileRequest\x1a\x11.dfs.FileMetadata\x12.\n" +
 	"\aGetFile\x12\x10.dfs.FileRequest\x1a\x11.dfs.FileMetadata\x12>\n" +
-	"\rAllocateChunk\x12\x19.dfs.AllocateChunkRequest\x1a\x12.dfs.ChunkMetadata\x12)\n" +
-	"\tHeartbeat\x12\x12.dfs.NodeHeartbeat\x1a\b.dfs.Ack2`\n" +
+	"\rAllocateChunk\x12\x19.dfs.AllocateChunkRequest\x1a\x12.dfs.ChunkMetadata\x12F\n" +
+	"\x11AllocateContainer\x12\x1d.dfs.AllocateContainerRequest\x1a\x12.dfs.ChunkMetadata\x12,\n" +
+	"\tPackFiles\x12\x15.dfs.PackFilesRequest\x1a\b.dfs.Ack\x127\n" +
+	"\tHeartbeat\x12\x12.dfs.NodeHeartbeat\x1a\x16.dfs.HeartbeatResponse\x120\n" +
+	"\vBlockReport\x12\x17.dfs.BlockReportRequest\x1a\b.dfs.Ack\x12,\n" +
+	"\tListFiles\x12\x10.dfs.ListRequest\x1a\r.dfs.FileList\x12+\n" +
+	"\bStatFile\x12\x10.dfs.FileRequest\x1a\r.dfs.FileInfo\x12(\n" +
+	"\n" +
+	"DeleteFile\x12\x10.dfs.FileRequest\x1a\b.dfs.Ack\x12*\n" +
+	"\n" +
+	"RenameFile\x12\x12.dfs.RenameRequest\x1a\b.dfs.Ack\x12#\n" +
+	"\x05Mkdir\x12\x10.dfs.FileRequest\x1a\b.dfs.Ack\x126\n" +
+	"\x0eSetReplication\x12\x1a.dfs.SetReplicationRequest\x1a\b.dfs.Ack\x12$\n" +
+	"\x05Chmod\x12\x11.dfs.ChmodRequest\x1a\b.dfs.Ack\x12$\n" +
+	"\x05Chown\x12\x11.dfs.ChownRequest\x1a\b.dfs.Ack\x12*\n" +
+	"\bSetQuota\x12\x14.dfs.SetQuotaRequest\x1a\b.dfs.Ack\x12-\n" +
+	"\bGetQuota\x12\x11.dfs.QuotaRequest\x1a\x0e.dfs.QuotaList\x122\n" +
+	"\aBalance\x12\x13.dfs.BalanceRequest\x1a\x12.dfs.BalanceReport\x12*\n" +
+	"\fDecommission\x12\x10.dfs.NodeRequest\x1a\b.dfs.Ack\x12*\n" +
+	"\fRecommission\x12\x10.dfs.NodeRequest\x1a\b.dfs.Ack\x125\n" +
+	"\x10EnterMaintenance\x12\x17.dfs.MaintenanceRequest\x1a\b.dfs.Ack\x121\n" +
+	"\tListNodes\x12\x15.dfs.ListNodesRequest\x1a\r.dfs.NodeList2\xee\x01\n" +
 	"\x0fDataNodeService\x12\"\n" +
 	"\n" +
 	"StoreChunk\x12\n" +
 	".dfs.Chunk\x1a\b.dfs.Ack\x12)\n" +
 	"\bGetChunk\x12\x11.dfs.ChunkRequest\x1a\n" +
-	".dfs.ChunkB\x1dZ\x1bDFS_GO/internal/proto;protob\x06proto3"
+	".dfs.Chunk\x12*\n" +
+	"\vDeleteChunk\x12\x11.dfs.ChunkRequest\x1a\b.dfs.Ack\x121\n" +
+	"\x0eReplicateChunk\x12\x15.dfs.ReplicateRequest\x1a\b.dfs.Ack\x12-\n" +
+	"\vCheckChunks\x12\x0e.dfs.ChunkList\x1a\x0e.dfs.ChunkListB\x1dZ\x1bDFS_GO/internal/proto;protob\x06proto3"
 
 var (
 	file_internal_proto_dfs_proto_rawDescOnce sync.Once
@@ -521,39 +2619,117 @@ func file_internal_proto_dfs_proto_rawDescGZIP() []byte {
 	return file_internal_proto_dfs_proto_rawDescData
 }
 
-var file_internal_proto_dfs_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_internal_proto_dfs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_internal_proto_dfs_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
 var file_internal_proto_dfs_proto_goTypes = []any{
-	(*NodeHeartbeat)(nil),        // 0: dfs.NodeHeartbeat
-	(*NodeInfo)(nil),             // 1: dfs.NodeInfo
-	(*FileRequest)(nil),          // 2: dfs.FileRequest
-	(*Chunk)(nil),                // 3: dfs.Chunk
-	(*ChunkRequest)(nil),         // 4: dfs.ChunkRequest
-	(*AllocateChunkRequest)(nil), // 5: dfs.AllocateChunkRequest
-	(*FileMetadata)(nil),         // 6: dfs.FileMetadata
-	(*ChunkMetadata)(nil),        // 7: dfs.ChunkMetadata
-	(*Ack)(nil),                  // 8: dfs.Ack
+	(NodeCommand_Type)(0),            // 0: dfs.NodeCommand.Type
+	(*NodeHeartbeat)(nil),            // 1: dfs.NodeHeartbeat
+	(*NodeCommand)(nil),              // 2: dfs.NodeCommand
+	(*HeartbeatResponse)(nil),        // 3: dfs.HeartbeatResponse
+	(*BlockReportRequest)(nil),       // 4: dfs.BlockReportRequest
+	(*NodeInfo)(nil),                 // 5: dfs.NodeInfo
+	(*FileRequest)(nil),              // 6: dfs.FileRequest
+	(*Chunk)(nil),                    // 7: dfs.Chunk
+	(*ChunkRequest)(nil),             // 8: dfs.ChunkRequest
+	(*ChunkList)(nil),                // 9: dfs.ChunkList
+	(*AllocateChunkRequest)(nil),     // 10: dfs.AllocateChunkRequest
+	(*FileMetadata)(nil),             // 11: dfs.FileMetadata
+	(*ChunkMetadata)(nil),            // 12: dfs.ChunkMetadata
+	(*AllocateContainerRequest)(nil), // 13: dfs.AllocateContainerRequest
+	(*PackedFile)(nil),               // 14: dfs.PackedFile
+	(*PackFilesRequest)(nil),         // 15: dfs.PackFilesRequest
+	(*ListRequest)(nil),              // 16: dfs.ListRequest
+	(*FileInfo)(nil),                 // 17: dfs.FileInfo
+	(*FileList)(nil),                 // 18: dfs.FileList
+	(*SetReplicationRequest)(nil),    // 19: dfs.SetReplicationRequest
+	(*ChmodRequest)(nil),             // 20: dfs.ChmodRequest
+	(*ChownRequest)(nil),             // 21: dfs.ChownRequest
+	(*SetQuotaRequest)(nil),          // 22: dfs.SetQuotaRequest
+	(*QuotaRequest)(nil),             // 23: dfs.QuotaRequest
+	(*Quota)(nil),                    // 24: dfs.Quota
+	(*QuotaList)(nil),                // 25: dfs.QuotaList
+	(*ReplicateRequest)(nil),         // 26: dfs.ReplicateRequest
+	(*BalanceRequest)(nil),           // 27: dfs.BalanceRequest
+	(*ReplicaMove)(nil),              // 28: dfs.ReplicaMove
+	(*BalanceReport)(nil),            // 29: dfs.BalanceReport
+	(*NodeRequest)(nil),              // 30: dfs.NodeRequest
+	(*MaintenanceRequest)(nil),       // 31: dfs.MaintenanceRequest
+	(*ListNodesRequest)(nil),         // 32: dfs.ListNodesRequest
+	(*NodeReport)(nil),               // 33: dfs.NodeReport
+	(*NodeList)(nil),                 // 34: dfs.NodeList
+	(*RenameRequest)(nil),            // 35: dfs.RenameRequest
+	(*Ack)(nil),                      // 36: dfs.Ack
 }
 var file_internal_proto_dfs_proto_depIdxs = []int32{
-	7, // 0: dfs.FileMetadata.chunks:type_name -> dfs.ChunkMetadata
-	1, // 1: dfs.MetadataService.RegisterNode:input_type -> dfs.NodeInfo
-	2, // 2: dfs.MetadataService.CreateFile:input_type -> dfs.FileRequest
-	2, // 3: dfs.MetadataService.GetFile:input_type -> dfs.FileRequest
-	5, // 4: dfs.MetadataService.AllocateChunk:input_type -> dfs.AllocateChunkRequest
-	0, // 5: dfs.MetadataService.Heartbeat:input_type -> dfs.NodeHeartbeat
-	3, // 6: dfs.DataNodeService.StoreChunk:input_type -> dfs.Chunk
-	4, // 7: dfs.DataNodeService.GetChunk:input_type -> dfs.ChunkRequest
-	8, // 8: dfs.MetadataService.RegisterNode:output_type -> dfs.Ack
-	6, // 9: dfs.MetadataService.CreateFile:output_type -> dfs.FileMetadata
-	6, // 10: dfs.MetadataService.GetFile:output_type -> dfs.FileMetadata
-	7, // 11: dfs.MetadataService.AllocateChunk:output_type -> dfs.ChunkMetadata
-	8, // 12: dfs.MetadataService.Heartbeat:output_type -> dfs.Ack
-	8, // 13: dfs.DataNodeService.StoreChunk:output_type -> dfs.Ack
-	3, // 14: dfs.DataNodeService.GetChunk:output_type -> dfs.Chunk
-	8, // [8:15] is the sub-list for method output_type
-	1, // [1:8] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: dfs.NodeCommand.type:type_name -> dfs.NodeCommand.Type
+	2,  // 1: dfs.HeartbeatResponse.commands:type_name -> dfs.NodeCommand
+	12, // 2: dfs.FileMetadata.chunks:type_name -> dfs.ChunkMetadata
+	14, // 3: dfs.PackFilesRequest.files:type_name -> dfs.PackedFile
+	17, // 4: dfs.FileList.files:type_name -> dfs.FileInfo
+	24, // 5: dfs.QuotaList.quotas:type_name -> dfs.Quota
+	28, // 6: dfs.BalanceReport.moves:type_name -> dfs.ReplicaMove
+	33, // 7: dfs.NodeList.nodes:type_name -> dfs.NodeReport
+	5,  // 8: dfs.MetadataService.RegisterNode:input_type -> dfs.NodeInfo
+	6,  // 9: dfs.MetadataService.CreateFile:input_type -> dfs.FileRequest
+	6,  // 10: dfs.MetadataService.GetFile:input_type -> dfs.FileRequest
+	10, // 11: dfs.MetadataService.AllocateChunk:input_type -> dfs.AllocateChunkRequest
+	13, // 12: dfs.MetadataService.AllocateContainer:input_type -> dfs.AllocateContainerRequest
+	15, // 13: dfs.MetadataService.PackFiles:input_type -> dfs.PackFilesRequest
+	1,  // 14: dfs.MetadataService.Heartbeat:input_type -> dfs.NodeHeartbeat
+	4,  // 15: dfs.MetadataService.BlockReport:input_type -> dfs.BlockReportRequest
+	16, // 16: dfs.MetadataService.ListFiles:input_type -> dfs.ListRequest
+	6,  // 17: dfs.MetadataService.StatFile:input_type -> dfs.FileRequest
+	6,  // 18: dfs.MetadataService.DeleteFile:input_type -> dfs.FileRequest
+	35, // 19: dfs.MetadataService.RenameFile:input_type -> dfs.RenameRequest
+	6,  // 20: dfs.MetadataService.Mkdir:input_type -> dfs.FileRequest
+	19, // 21: dfs.MetadataService.SetReplication:input_type -> dfs.SetReplicationRequest
+	20, // 22: dfs.MetadataService.Chmod:input_type -> dfs.ChmodRequest
+	21, // 23: dfs.MetadataService.Chown:input_type -> dfs.ChownRequest
+	22, // 24: dfs.MetadataService.SetQuota:input_type -> dfs.SetQuotaRequest
+	23, // 25: dfs.MetadataService.GetQuota:input_type -> dfs.QuotaRequest
+	27, // 26: dfs.MetadataService.Balance:input_type -> dfs.BalanceRequest
+	30, // 27: dfs.MetadataService.Decommission:input_type -> dfs.NodeRequest
+	30, // 28: dfs.MetadataService.Recommission:input_type -> dfs.NodeRequest
+	31, // 29: dfs.MetadataService.EnterMaintenance:input_type -> dfs.MaintenanceRequest
+	32, // 30: dfs.MetadataService.ListNodes:input_type -> dfs.ListNodesRequest
+	7,  // 31: dfs.DataNodeService.StoreChunk:input_type -> dfs.Chunk
+	8,  // 32: dfs.DataNodeService.GetChunk:input_type -> dfs.ChunkRequest
+	8,  // 33: dfs.DataNodeService.DeleteChunk:input_type -> dfs.ChunkRequest
+	26, // 34: dfs.DataNodeService.ReplicateChunk:input_type -> dfs.ReplicateRequest
+	9,  // 35: dfs.DataNodeService.CheckChunks:input_type -> dfs.ChunkList
+	36, // 36: dfs.MetadataService.RegisterNode:output_type -> dfs.Ack
+	11, // 37: dfs.MetadataService.CreateFile:output_type -> dfs.FileMetadata
+	11, // 38: dfs.MetadataService.GetFile:output_type -> dfs.FileMetadata
+	12, // 39: dfs.MetadataService.AllocateChunk:output_type -> dfs.ChunkMetadata
+	12, // 40: dfs.MetadataService.AllocateContainer:output_type -> dfs.ChunkMetadata
+	36, // 41: dfs.MetadataService.PackFiles:output_type -> dfs.Ack
+	3,  // 42: dfs.MetadataService.Heartbeat:output_type -> dfs.HeartbeatResponse
+	36, // 43: dfs.MetadataService.BlockReport:output_type -> dfs.Ack
+	18, // 44: dfs.MetadataService.ListFiles:output_type -> dfs.FileList
+	17, // 45: dfs.MetadataService.StatFile:output_type -> dfs.FileInfo
+	36, // 46: dfs.MetadataService.DeleteFile:output_type -> dfs.Ack
+	36, // 47: dfs.MetadataService.RenameFile:output_type -> dfs.Ack
+	36, // 48: dfs.MetadataService.Mkdir:output_type -> dfs.Ack
+	36, // 49: dfs.MetadataService.SetReplication:output_type -> dfs.Ack
+	36, // 50: dfs.MetadataService.Chmod:output_type -> dfs.Ack
+	36, // 51: dfs.MetadataService.Chown:output_type -> dfs.Ack
+	36, // 52: dfs.MetadataService.SetQuota:output_type -> dfs.Ack
+	25, // 53: dfs.MetadataService.GetQuota:output_type -> dfs.QuotaList
+	29, // 54: dfs.MetadataService.Balance:output_type -> dfs.BalanceReport
+	36, // 55: dfs.MetadataService.Decommission:output_type -> dfs.Ack
+	36, // 56: dfs.MetadataService.Recommission:output_type -> dfs.Ack
+	36, // 57: dfs.MetadataService.EnterMaintenance:output_type -> dfs.Ack
+	34, // 58: dfs.MetadataService.ListNodes:output_type -> dfs.NodeList
+	36, // 59: dfs.DataNodeService.StoreChunk:output_type -> dfs.Ack
+	7,  // 60: dfs.DataNodeService.GetChunk:output_type -> dfs.Chunk
+	36, // 61: dfs.DataNodeService.DeleteChunk:output_type -> dfs.Ack
+	36, // 62: dfs.DataNodeService.ReplicateChunk:output_type -> dfs.Ack
+	9,  // 63: dfs.DataNodeService.CheckChunks:output_type -> dfs.ChunkList
+	36, // [36:64] is the sub-list for method output_type
+	8,  // [8:36] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_dfs_proto_init() }
@@ -566,13 +2742,14 @@ func file_internal_proto_dfs_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_proto_dfs_proto_rawDesc), len(file_internal_proto_dfs_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      1,
+			NumMessages:   36,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_internal_proto_dfs_proto_goTypes,
 		DependencyIndexes: file_internal_proto_dfs_proto_depIdxs,
+		EnumInfos:         file_internal_proto_dfs_proto_enumTypes,
 		MessageInfos:      file_internal_proto_dfs_proto_msgTypes,
 	}.Build()
 	File_internal_proto_dfs_proto = out.File
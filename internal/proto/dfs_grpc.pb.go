@@ -19,11 +19,29 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MetadataService_RegisterNode_FullMethodName  = "/dfs.MetadataService/RegisterNode"
-	MetadataService_CreateFile_FullMethodName    = "/dfs.MetadataService/CreateFile"
-	MetadataService_GetFile_FullMethodName       = "/dfs.MetadataService/GetFile"
-	MetadataService_AllocateChunk_FullMethodName = "/dfs.MetadataService/AllocateChunk"
-	MetadataService_Heartbeat_FullMethodName     = "/dfs.MetadataService/Heartbeat"
+	MetadataService_RegisterNode_FullMethodName      = "/dfs.MetadataService/RegisterNode"
+	MetadataService_CreateFile_FullMethodName        = "/dfs.MetadataService/CreateFile"
+	MetadataService_GetFile_FullMethodName           = "/dfs.MetadataService/GetFile"
+	MetadataService_AllocateChunk_FullMethodName     = "/dfs.MetadataService/AllocateChunk"
+	MetadataService_AllocateContainer_FullMethodName = "/dfs.MetadataService/AllocateContainer"
+	MetadataService_PackFiles_FullMethodName         = "/dfs.MetadataService/PackFiles"
+	MetadataService_Heartbeat_FullMethodName         = "/dfs.MetadataService/Heartbeat"
+	MetadataService_BlockReport_FullMethodName       = "/dfs.MetadataService/BlockReport"
+	MetadataService_ListFiles_FullMethodName         = "/dfs.MetadataService/ListFiles"
+	MetadataService_StatFile_FullMethodName          = "/dfs.MetadataService/StatFile"
+	MetadataService_DeleteFile_FullMethodName        = "/dfs.MetadataService/DeleteFile"
+	MetadataService_RenameFile_FullMethodName        = "/dfs.MetadataService/RenameFile"
+	MetadataService_Mkdir_FullMethodName             = "/dfs.MetadataService/Mkdir"
+	MetadataService_SetReplication_FullMethodName    = "/dfs.MetadataService/SetReplication"
+	MetadataService_Chmod_FullMethodName             = "/dfs.MetadataService/Chmod"
+	MetadataService_Chown_FullMethodName             = "/dfs.MetadataService/Chown"
+	MetadataService_SetQuota_FullMethodName          = "/dfs.MetadataService/SetQuota"
+	MetadataService_GetQuota_FullMethodName          = "/dfs.MetadataService/GetQuota"
+	MetadataService_Balance_FullMethodName           = "/dfs.MetadataService/Balance"
+	MetadataService_Decommission_FullMethodName      = "/dfs.MetadataService/Decommission"
+	MetadataService_Recommission_FullMethodName      = "/dfs.MetadataService/Recommission"
+	MetadataService_EnterMaintenance_FullMethodName  = "/dfs.MetadataService/EnterMaintenance"
+	MetadataService_ListNodes_FullMethodName         = "/dfs.MetadataService/ListNodes"
 )
 
 // MetadataServiceClient is the client API for MetadataService service.
@@ -34,7 +52,25 @@ type MetadataServiceClient interface {
 	CreateFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileMetadata, error)
 	GetFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileMetadata, error)
 	AllocateChunk(ctx context.Context, in *AllocateChunkRequest, opts ...grpc.CallOption) (*ChunkMetadata, error)
-	Heartbeat(ctx context.Context, in *NodeHeartbeat, opts ...grpc.CallOption) (*Ack, error)
+	AllocateContainer(ctx context.Context, in *AllocateContainerRequest, opts ...grpc.CallOption) (*ChunkMetadata, error)
+	PackFiles(ctx context.Context, in *PackFilesRequest, opts ...grpc.CallOption) (*Ack, error)
+	Heartbeat(ctx context.Context, in *NodeHeartbeat, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	BlockReport(ctx context.Context, in *BlockReportRequest, opts ...grpc.CallOption) (*Ack, error)
+	ListFiles(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*FileList, error)
+	StatFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileInfo, error)
+	DeleteFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Ack, error)
+	RenameFile(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*Ack, error)
+	Mkdir(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Ack, error)
+	SetReplication(ctx context.Context, in *SetReplicationRequest, opts ...grpc.CallOption) (*Ack, error)
+	Chmod(ctx context.Context, in *ChmodRequest, opts ...grpc.CallOption) (*Ack, error)
+	Chown(ctx context.Context, in *ChownRequest, opts ...grpc.CallOption) (*Ack, error)
+	SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*Ack, error)
+	GetQuota(ctx context.Context, in *QuotaRequest, opts ...grpc.CallOption) (*QuotaList, error)
+	Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*BalanceReport, error)
+	Decommission(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*Ack, error)
+	Recommission(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*Ack, error)
+	EnterMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*Ack, error)
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*NodeList, error)
 }
 
 type metadataServiceClient struct {
@@ -85,9 +121,29 @@ func (c *metadataServiceClient) AllocateChunk(ctx context.Context, in *AllocateC
 	return out, nil
 }
 
-func (c *metadataServiceClient) Heartbeat(ctx context.Context, in *NodeHeartbeat, opts ...grpc.CallOption) (*Ack, error) {
+func (c *metadataServiceClient) AllocateContainer(ctx context.Context, in *AllocateContainerRequest, opts ...grpc.CallOption) (*ChunkMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChunkMetadata)
+	err := c.cc.Invoke(ctx, MetadataService_AllocateContainer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) PackFiles(ctx context.Context, in *PackFilesRequest, opts ...grpc.CallOption) (*Ack, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_PackFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Heartbeat(ctx context.Context, in *NodeHeartbeat, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
 	err := c.cc.Invoke(ctx, MetadataService_Heartbeat_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
@@ -95,6 +151,166 @@ func (c *metadataServiceClient) Heartbeat(ctx context.Context, in *NodeHeartbeat
 	return out, nil
 }
 
+func (c *metadataServiceClient) BlockReport(ctx context.Context, in *BlockReportRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_BlockReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ListFiles(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*FileList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileList)
+	err := c.cc.Invoke(ctx, MetadataService_ListFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) StatFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileInfo)
+	err := c.cc.Invoke(ctx, MetadataService_StatFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) DeleteFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_DeleteFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) RenameFile(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_RenameFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Mkdir(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_Mkdir_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) SetReplication(ctx context.Context, in *SetReplicationRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_SetReplication_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Chmod(ctx context.Context, in *ChmodRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_Chmod_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Chown(ctx context.Context, in *ChownRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_Chown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_SetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) GetQuota(ctx context.Context, in *QuotaRequest, opts ...grpc.CallOption) (*QuotaList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuotaList)
+	err := c.cc.Invoke(ctx, MetadataService_GetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*BalanceReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BalanceReport)
+	err := c.cc.Invoke(ctx, MetadataService_Balance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Decommission(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_Decommission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) Recommission(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_Recommission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) EnterMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, MetadataService_EnterMaintenance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*NodeList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeList)
+	err := c.cc.Invoke(ctx, MetadataService_ListNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetadataServiceServer is the server API for MetadataService service.
 // All implementations must embed UnimplementedMetadataServiceServer
 // for forward compatibility.
@@ -103,7 +319,25 @@ type MetadataServiceServer interface {
 	CreateFile(context.Context, *FileRequest) (*FileMetadata, error)
 	GetFile(context.Context, *FileRequest) (*FileMetadata, error)
 	AllocateChunk(context.Context, *AllocateChunkRequest) (*ChunkMetadata, error)
-	Heartbeat(context.Context, *NodeHeartbeat) (*Ack, error)
+	AllocateContainer(context.Context, *AllocateContainerRequest) (*ChunkMetadata, error)
+	PackFiles(context.Context, *PackFilesRequest) (*Ack, error)
+	Heartbeat(context.Context, *NodeHeartbeat) (*HeartbeatResponse, error)
+	BlockReport(context.Context, *BlockReportRequest) (*Ack, error)
+	ListFiles(context.Context, *ListRequest) (*FileList, error)
+	StatFile(context.Context, *FileRequest) (*FileInfo, error)
+	DeleteFile(context.Context, *FileRequest) (*Ack, error)
+	RenameFile(context.Context, *RenameRequest) (*Ack, error)
+	Mkdir(context.Context, *FileRequest) (*Ack, error)
+	SetReplication(context.Context, *SetReplicationRequest) (*Ack, error)
+	Chmod(context.Context, *ChmodRequest) (*Ack, error)
+	Chown(context.Context, *ChownRequest) (*Ack, error)
+	SetQuota(context.Context, *SetQuotaRequest) (*Ack, error)
+	GetQuota(context.Context, *QuotaRequest) (*QuotaList, error)
+	Balance(context.Context, *BalanceRequest) (*BalanceReport, error)
+	Decommission(context.Context, *NodeRequest) (*Ack, error)
+	Recommission(context.Context, *NodeRequest) (*Ack, error)
+	EnterMaintenance(context.Context, *MaintenanceRequest) (*Ack, error)
+	ListNodes(context.Context, *ListNodesRequest) (*NodeList, error)
 	mustEmbedUnimplementedMetadataServiceServer()
 }
 
@@ -126,9 +360,63 @@ func (UnimplementedMetadataServiceServer) GetFile(context.Context, *FileRequest)
 func (UnimplementedMetadataServiceServer) AllocateChunk(context.Context, *AllocateChunkRequest) (*ChunkMetadata, error) {
 	return nil, status.Error(codes.Unimplemented, "method AllocateChunk not implemented")
 }
-func (UnimplementedMetadataServiceServer) Heartbeat(context.Context, *NodeHeartbeat) (*Ack, error) {
+func (UnimplementedMetadataServiceServer) AllocateContainer(context.Context, *AllocateContainerRequest) (*ChunkMetadata, error) {
+	return nil, status.Error(codes.Unimplemented, "method AllocateContainer not implemented")
+}
+func (UnimplementedMetadataServiceServer) PackFiles(context.Context, *PackFilesRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method PackFiles not implemented")
+}
+func (UnimplementedMetadataServiceServer) Heartbeat(context.Context, *NodeHeartbeat) (*HeartbeatResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Heartbeat not implemented")
 }
+func (UnimplementedMetadataServiceServer) BlockReport(context.Context, *BlockReportRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method BlockReport not implemented")
+}
+func (UnimplementedMetadataServiceServer) ListFiles(context.Context, *ListRequest) (*FileList, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedMetadataServiceServer) StatFile(context.Context, *FileRequest) (*FileInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method StatFile not implemented")
+}
+func (UnimplementedMetadataServiceServer) DeleteFile(context.Context, *FileRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteFile not implemented")
+}
+func (UnimplementedMetadataServiceServer) RenameFile(context.Context, *RenameRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method RenameFile not implemented")
+}
+func (UnimplementedMetadataServiceServer) Mkdir(context.Context, *FileRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Mkdir not implemented")
+}
+func (UnimplementedMetadataServiceServer) SetReplication(context.Context, *SetReplicationRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetReplication not implemented")
+}
+func (UnimplementedMetadataServiceServer) Chmod(context.Context, *ChmodRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Chmod not implemented")
+}
+func (UnimplementedMetadataServiceServer) Chown(context.Context, *ChownRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Chown not implemented")
+}
+func (UnimplementedMetadataServiceServer) SetQuota(context.Context, *SetQuotaRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetQuota not implemented")
+}
+func (UnimplementedMetadataServiceServer) GetQuota(context.Context, *QuotaRequest) (*QuotaList, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetQuota not implemented")
+}
+func (UnimplementedMetadataServiceServer) Balance(context.Context, *BalanceRequest) (*BalanceReport, error) {
+	return nil, status.Error(codes.Unimplemented, "method Balance not implemented")
+}
+func (UnimplementedMetadataServiceServer) Decommission(context.Context, *NodeRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Decommission not implemented")
+}
+func (UnimplementedMetadataServiceServer) Recommission(context.Context, *NodeRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Recommission not implemented")
+}
+func (UnimplementedMetadataServiceServer) EnterMaintenance(context.Context, *MaintenanceRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method EnterMaintenance not implemented")
+}
+func (UnimplementedMetadataServiceServer) ListNodes(context.Context, *ListNodesRequest) (*NodeList, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListNodes not implemented")
+}
 func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
 func (UnimplementedMetadataServiceServer) testEmbeddedByValue()                         {}
 
@@ -222,6 +510,42 @@ func _MetadataService_AllocateChunk_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataService_AllocateContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).AllocateContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_AllocateContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).AllocateContainer(ctx, req.(*AllocateContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_PackFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PackFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).PackFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_PackFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).PackFiles(ctx, req.(*PackFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MetadataService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(NodeHeartbeat)
 	if err := dec(in); err != nil {
@@ -240,6 +564,294 @@ func _MetadataService_Heartbeat_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataService_BlockReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).BlockReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_BlockReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).BlockReport(ctx, req.(*BlockReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ListFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ListFiles(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_StatFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).StatFile(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_DeleteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).DeleteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_DeleteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).DeleteFile(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).RenameFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_RenameFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).RenameFile(ctx, req.(*RenameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Mkdir_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Mkdir(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Mkdir_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Mkdir(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_SetReplication_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetReplicationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).SetReplication(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_SetReplication_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).SetReplication(ctx, req.(*SetReplicationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Chmod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChmodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Chmod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Chmod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Chmod(ctx, req.(*ChmodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Chown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Chown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Chown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Chown(ctx, req.(*ChownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_SetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).SetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_SetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).SetQuota(ctx, req.(*SetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_GetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetQuota(ctx, req.(*QuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Balance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Balance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Balance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Balance(ctx, req.(*BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Decommission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Decommission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Decommission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Decommission(ctx, req.(*NodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_Recommission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).Recommission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_Recommission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).Recommission(ctx, req.(*NodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_EnterMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).EnterMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_EnterMaintenance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).EnterMaintenance(ctx, req.(*MaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ListNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -263,18 +875,93 @@ var MetadataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AllocateChunk",
 			Handler:    _MetadataService_AllocateChunk_Handler,
 		},
+		{
+			MethodName: "AllocateContainer",
+			Handler:    _MetadataService_AllocateContainer_Handler,
+		},
+		{
+			MethodName: "PackFiles",
+			Handler:    _MetadataService_PackFiles_Handler,
+		},
 		{
 			MethodName: "Heartbeat",
 			Handler:    _MetadataService_Heartbeat_Handler,
 		},
+		{
+			MethodName: "BlockReport",
+			Handler:    _MetadataService_BlockReport_Handler,
+		},
+		{
+			MethodName: "ListFiles",
+			Handler:    _MetadataService_ListFiles_Handler,
+		},
+		{
+			MethodName: "StatFile",
+			Handler:    _MetadataService_StatFile_Handler,
+		},
+		{
+			MethodName: "DeleteFile",
+			Handler:    _MetadataService_DeleteFile_Handler,
+		},
+		{
+			MethodName: "RenameFile",
+			Handler:    _MetadataService_RenameFile_Handler,
+		},
+		{
+			MethodName: "Mkdir",
+			Handler:    _MetadataService_Mkdir_Handler,
+		},
+		{
+			MethodName: "SetReplication",
+			Handler:    _MetadataService_SetReplication_Handler,
+		},
+		{
+			MethodName: "Chmod",
+			Handler:    _MetadataService_Chmod_Handler,
+		},
+		{
+			MethodName: "Chown",
+			Handler:    _MetadataService_Chown_Handler,
+		},
+		{
+			MethodName: "SetQuota",
+			Handler:    _MetadataService_SetQuota_Handler,
+		},
+		{
+			MethodName: "GetQuota",
+			Handler:    _MetadataService_GetQuota_Handler,
+		},
+		{
+			MethodName: "Balance",
+			Handler:    _MetadataService_Balance_Handler,
+		},
+		{
+			MethodName: "Decommission",
+			Handler:    _MetadataService_Decommission_Handler,
+		},
+		{
+			MethodName: "Recommission",
+			Handler:    _MetadataService_Recommission_Handler,
+		},
+		{
+			MethodName: "EnterMaintenance",
+			Handler:    _MetadataService_EnterMaintenance_Handler,
+		},
+		{
+			MethodName: "ListNodes",
+			Handler:    _MetadataService_ListNodes_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/proto/dfs.proto",
 }
 
 const (
-	DataNodeService_StoreChunk_FullMethodName = "/dfs.DataNodeService/StoreChunk"
-	DataNodeService_GetChunk_FullMethodName   = "/dfs.DataNodeService/GetChunk"
+	DataNodeService_StoreChunk_FullMethodName     = "/dfs.DataNodeService/StoreChunk"
+	DataNodeService_GetChunk_FullMethodName       = "/dfs.DataNodeService/GetChunk"
+	DataNodeService_DeleteChunk_FullMethodName    = "/dfs.DataNodeService/DeleteChunk"
+	DataNodeService_ReplicateChunk_FullMethodName = "/dfs.DataNodeService/ReplicateChunk"
+	DataNodeService_CheckChunks_FullMethodName    = "/dfs.DataNodeService/CheckChunks"
 )
 
 // DataNodeServiceClient is the client API for DataNodeService service.
@@ -283,6 +970,9 @@ const (
 type DataNodeServiceClient interface {
 	StoreChunk(ctx context.Context, in *Chunk, opts ...grpc.CallOption) (*Ack, error)
 	GetChunk(ctx context.Context, in *ChunkRequest, opts ...grpc.CallOption) (*Chunk, error)
+	DeleteChunk(ctx context.Context, in *ChunkRequest, opts ...grpc.CallOption) (*Ack, error)
+	ReplicateChunk(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*Ack, error)
+	CheckChunks(ctx context.Context, in *ChunkList, opts ...grpc.CallOption) (*ChunkList, error)
 }
 
 type dataNodeServiceClient struct {
@@ -313,12 +1003,45 @@ func (c *dataNodeServiceClient) GetChunk(ctx context.Context, in *ChunkRequest,
 	return out, nil
 }
 
+func (c *dataNodeServiceClient) DeleteChunk(ctx context.Context, in *ChunkRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, DataNodeService_DeleteChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataNodeServiceClient) ReplicateChunk(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, DataNodeService_ReplicateChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataNodeServiceClient) CheckChunks(ctx context.Context, in *ChunkList, opts ...grpc.CallOption) (*ChunkList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChunkList)
+	err := c.cc.Invoke(ctx, DataNodeService_CheckChunks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DataNodeServiceServer is the server API for DataNodeService service.
 // All implementations must embed UnimplementedDataNodeServiceServer
 // for forward compatibility.
 type DataNodeServiceServer interface {
 	StoreChunk(context.Context, *Chunk) (*Ack, error)
 	GetChunk(context.Context, *ChunkRequest) (*Chunk, error)
+	DeleteChunk(context.Context, *ChunkRequest) (*Ack, error)
+	ReplicateChunk(context.Context, *ReplicateRequest) (*Ack, error)
+	CheckChunks(context.Context, *ChunkList) (*ChunkList, error)
 	mustEmbedUnimplementedDataNodeServiceServer()
 }
 
@@ -335,6 +1058,15 @@ func (UnimplementedDataNodeServiceServer) StoreChunk(context.Context, *Chunk) (*
 func (UnimplementedDataNodeServiceServer) GetChunk(context.Context, *ChunkRequest) (*Chunk, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetChunk not implemented")
 }
+func (UnimplementedDataNodeServiceServer) DeleteChunk(context.Context, *ChunkRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteChunk not implemented")
+}
+func (UnimplementedDataNodeServiceServer) ReplicateChunk(context.Context, *ReplicateRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReplicateChunk not implemented")
+}
+func (UnimplementedDataNodeServiceServer) CheckChunks(context.Context, *ChunkList) (*ChunkList, error) {
+	return nil, status.Error(codes.Unimplemented, "method CheckChunks not implemented")
+}
 func (UnimplementedDataNodeServiceServer) mustEmbedUnimplementedDataNodeServiceServer() {}
 func (UnimplementedDataNodeServiceServer) testEmbeddedByValue()                         {}
 
@@ -392,6 +1124,60 @@ func _DataNodeService_GetChunk_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataNodeService_DeleteChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChunkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataNodeServiceServer).DeleteChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataNodeService_DeleteChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataNodeServiceServer).DeleteChunk(ctx, req.(*ChunkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataNodeService_ReplicateChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataNodeServiceServer).ReplicateChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataNodeService_ReplicateChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataNodeServiceServer).ReplicateChunk(ctx, req.(*ReplicateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataNodeService_CheckChunks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChunkList)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataNodeServiceServer).CheckChunks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataNodeService_CheckChunks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataNodeServiceServer).CheckChunks(ctx, req.(*ChunkList))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DataNodeService_ServiceDesc is the grpc.ServiceDesc for DataNodeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -407,6 +1193,18 @@ var DataNodeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetChunk",
 			Handler:    _DataNodeService_GetChunk_Handler,
 		},
+		{
+			MethodName: "DeleteChunk",
+			Handler:    _DataNodeService_DeleteChunk_Handler,
+		},
+		{
+			MethodName: "ReplicateChunk",
+			Handler:    _DataNodeService_ReplicateChunk_Handler,
+		},
+		{
+			MethodName: "CheckChunks",
+			Handler:    _DataNodeService_CheckChunks_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/proto/dfs.proto",
@@ -1 +1,60 @@
 package transport
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MaxMsgSize bounds the gRPC messages carrying chunks.
+const MaxMsgSize = 16 * 1024 * 1024
+
+// Dial connects to a metadata server or DataNode, over TLS unless t is
+// nil.
+func Dial(addr string, t *TLS, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return grpc.Dial(addr, append([]grpc.DialOption{
+		t.DialOption(),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallSendMsgSize(MaxMsgSize),
+			grpc.MaxCallRecvMsgSize(MaxMsgSize),
+		),
+	}, opts...)...)
+}
+
+// bearer sends a token with every call as an authorization header.
+type bearer string
+
+func (b bearer) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+// RequireTransportSecurity lets tokens go over plaintext, for test
+// clusters without TLS. Anyone on the network can read them there.
+func (b bearer) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithToken authenticates every call with a bearer token; an empty token
+// sends none.
+func WithToken(token string) grpc.DialOption {
+	if token == "" {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithPerRPCCredentials(bearer(token))
+}
+
+// BearerToken returns the token a caller sent with WithToken, or "".
+func BearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return token
+		}
+	}
+	return ""
+}
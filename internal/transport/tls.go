@@ -0,0 +1,204 @@
+package transport
+
+import (
+	"DFS_GO/internal/common"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+)
+
+// reloadCheck is how often the certificate files are checked for changes.
+const reloadCheck = time.Second
+
+// TLS holds the certificate and CA of one process. The files are watched
+// and reloaded when they change, so certificates can be renewed without a
+// restart; connections already open keep the certificate they started
+// with. A nil *TLS means plaintext.
+type TLS struct {
+	cfg common.TLSConfig
+
+	mu      sync.Mutex
+	cert    *tls.Certificate // nil for a client without a certificate
+	pool    *x509.CertPool
+	stamp   string // sizes and modification times of the loaded files
+	checked time.Time
+}
+
+// ServerTLS loads the TLS section of a server config. Servers need a
+// certificate; a config without a tls section gives nil.
+func ServerTLS(cfg common.TLSConfig) (*TLS, error) {
+	if cfg != (common.TLSConfig{}) && cfg.CertFile == "" {
+		return nil, errors.New("tls: cert_file and key_file are required")
+	}
+	return ClientTLS(cfg)
+}
+
+// ClientTLS loads the TLS section of a client config. The certificate is
+// optional and presented to servers that ask for one.
+func ClientTLS(cfg common.TLSConfig) (*TLS, error) {
+	if cfg == (common.TLSConfig{}) {
+		return nil, nil
+	}
+	if cfg.CAFile == "" {
+		return nil, errors.New("tls: ca_file is required")
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("tls: cert_file and key_file go together")
+	}
+
+	t := &TLS{cfg: cfg}
+	stamp, err := t.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.load(stamp); err != nil {
+		return nil, err
+	}
+	t.checked = time.Now()
+	return t, nil
+}
+
+func (t *TLS) fileStamp() (string, error) {
+	stamp := ""
+	for _, path := range []string{t.cfg.CertFile, t.cfg.KeyFile, t.cfg.CAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
+
+// load reads the files. Caller must hold t.mu, or own t exclusively.
+func (t *TLS) load(stamp string) error {
+	ca, err := os.ReadFile(t.cfg.CAFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("tls: no certificates in %s", t.cfg.CAFile)
+	}
+
+	var cert *tls.Certificate
+	if t.cfg.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(t.cfg.CertFile, t.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		cert = &c
+	}
+
+	t.cert, t.pool, t.stamp = cert, pool, stamp
+	return nil
+}
+
+// current returns the certificate and CA, reloading them first when the
+// files have changed. A broken update, such as a certificate replaced
+// before its key, keeps the previous ones until the files are consistent.
+func (t *TLS) current() (*tls.Certificate, *x509.CertPool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if time.Since(t.checked) >= reloadCheck {
+		t.checked = time.Now()
+		stamp, err := t.fileStamp()
+		if err == nil && stamp != t.stamp {
+			err = t.load(stamp)
+			if err == nil {
+				log.Printf("Reloaded TLS certificates")
+			}
+		}
+		if err != nil {
+			log.Printf("Keeping the current TLS certificates: %v", err)
+		}
+	}
+	return t.cert, t.pool
+}
+
+// ServerOption serves TLS. Clients presenting a certificate must have it
+// signed by the CA; see VerifiedPeer.
+func (t *TLS) ServerOption() grpc.ServerOption {
+	if t == nil {
+		return grpc.EmptyServerOption{}
+	}
+	return grpc.Creds(credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := t.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+			}, nil
+		},
+	}))
+}
+
+// DialOption connects over TLS, presenting the certificate if there is
+// one, or in plaintext when t is nil.
+func (t *TLS) DialOption() grpc.DialOption {
+	if t == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the server is verified by verifyServer against the current CA
+		// rather than the one loaded when the connection was set up
+		InsecureSkipVerify: true,
+		VerifyConnection:   t.verifyServer,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert, _ := t.current(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}))
+}
+
+func (t *TLS) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server sent no certificate")
+	}
+	_, pool := t.current()
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// VerifiedPeer returns the certificate a caller authenticated with, or nil
+// when it presented none or the connection is not TLS.
+func VerifiedPeer(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}